@@ -1,24 +1,120 @@
 // main.go
 package main
 
+import (
+	"context"
+	"course_scheduler/config"
+	"course_scheduler/internal/api/v1/routes"
+	"course_scheduler/internal/storage"
+	"course_scheduler/internal/utils"
+	"course_scheduler/internal/worker"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 服务配置
+// 优先使用命令行参数, 没有设置时使用环境变量, 都没有设置时使用默认值
+type Config struct {
+	Addr     string // 监听地址, 环境变量: COURSE_SCHEDULER_ADDR
+	DBDriver string // 数据库类型 mysql, sqlite, 环境变量: COURSE_SCHEDULER_DB_DRIVER
+	DBDSN    string // 数据库连接信息, sqlite时为数据库文件路径, 环境变量: COURSE_SCHEDULER_DB_DSN
+	Workers  int    // 后台同时执行的排课任务数量, 0表示不在后台执行, 环境变量: COURSE_SCHEDULER_WORKERS
+}
+
 func main() {
 
-	// // 初始化数据库
-	// dsn := "root:root@tcp(127.0.0.1:3306)/course_scheduler?charset=utf8mb4&parseTime=true&loc=Local"
-	// db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
-	// if err != nil {
-	// 	panic("failed to connect database")
-	// }
+	cfg := loadConfig(os.Args[1:])
+
+	// 创建日志文件, 所有排课任务共用
+	logFile := utils.SetUpLogFile()
+	defer logFile.Close()
+
+	// 初始化数据库
+	store, err := storage.Open(cfg.DBDriver, cfg.DBDSN)
+	if err != nil {
+		log.Fatalf("open storage failed. %s", err)
+	}
+	defer store.Close()
+
+	// 收到退出信号后, 停止接收请求, 执行中的排课任务在下一代遗传结束时改回等待执行
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 启动后台排课任务执行程序
+	var wg sync.WaitGroup
+	if cfg.Workers > 0 {
+		w := worker.NewWorker(store, cfg.Workers, config.TaskPollInterval, config.TaskStaleTimeout)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.Run(ctx)
+		}()
+	}
+
+	// 创建一个新的 Gin 引擎
+	r := gin.Default()
 
-	// // 创建一个新的 Gin 引擎
-	// r := gin.Default()
+	// 加载 v1 版本的路由
+	// 注册路由
+	routes.SetupRoutes(r, store)
 
-	// // 加载 v1 版本的路由
-	// // 注册路由
-	// routes.SetupRoutes(r, db)
+	// 启动 HTTP 服务器
+	srv := &http.Server{Addr: cfg.Addr, Handler: r}
+	go func() {
+		fmt.Printf("Server is running on %s, storage: %s, workers: %d\n", cfg.Addr, cfg.DBDriver, cfg.Workers)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("run server failed. %s", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("shutting down server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("shutdown server failed. %s", err)
+	}
+	wg.Wait()
+}
+
+// 加载服务配置
+func loadConfig(args []string) *Config {
+
+	cfg := &Config{}
+	fs := flag.NewFlagSet("api", flag.ExitOnError)
+	fs.StringVar(&cfg.Addr, "addr", getEnv("COURSE_SCHEDULER_ADDR", ":8081"), "listen address")
+	fs.StringVar(&cfg.DBDriver, "db-driver", getEnv("COURSE_SCHEDULER_DB_DRIVER", "sqlite"), "storage driver, mysql or sqlite")
+	fs.StringVar(&cfg.DBDSN, "db-dsn", getEnv("COURSE_SCHEDULER_DB_DSN", "course_scheduler.db"), "storage dsn, file path for sqlite")
+	fs.IntVar(&cfg.Workers, "workers", getEnvInt("COURSE_SCHEDULER_WORKERS", config.MaxRunningTasks), "max running tasks in background, 0 to disable")
+	fs.Parse(args)
+
+	return cfg
+}
+
+// 获取环境变量, 没有设置时使用默认值
+func getEnv(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
 
-	// // 启动 HTTP 服务器
-	// port := ":8081"
-	// fmt.Printf("Server is running on port %s\n", port)
-	// r.Run(port)
+// 获取整数类型的环境变量, 没有设置或者格式错误时使用默认值
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(getEnv(key, "")); err == nil {
+		return value
+	}
+	return defaultValue
 }
@@ -0,0 +1,141 @@
+// main.go
+// API密钥管理程序
+// 创建, 吊销, 列出学校(租户)的API密钥, 创建时可以同时设置租户的限制
+//
+//	apikey create -school-id 1 -name 教务处 [-school-name 某某中学] [-max-running-tasks 2] [-max-payload-bytes 1048576]
+//	apikey revoke -key-id 3
+//	apikey list [-school-id 1]
+//	apikey assign-orphans -school-id 1
+package main
+
+import (
+	"course_scheduler/internal/api/v1/middlewares"
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/storage"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+func main() {
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: apikey create|revoke|list|assign-orphans [flags]")
+		os.Exit(2)
+	}
+	action := os.Args[1]
+
+	fs := flag.NewFlagSet("apikey "+action, flag.ExitOnError)
+	dbDriver := fs.String("db-driver", getEnv("COURSE_SCHEDULER_DB_DRIVER", "sqlite"), "storage driver, mysql or sqlite")
+	dbDSN := fs.String("db-dsn", getEnv("COURSE_SCHEDULER_DB_DSN", "course_scheduler.db"), "storage dsn, file path for sqlite")
+	schoolID := fs.Uint64("school-id", 0, "school id of the tenant")
+	name := fs.String("name", "", "name of the api key")
+	schoolName := fs.String("school-name", "", "name of the school, updates the tenant when set")
+	maxRunningTasks := fs.Int("max-running-tasks", 0, "max running tasks of the school, 0 keeps the current setting")
+	maxPayloadBytes := fs.Int64("max-payload-bytes", 0, "max request body size of the school, 0 keeps the current setting")
+	keyID := fs.Uint64("key-id", 0, "id of the api key to revoke")
+	fs.Parse(os.Args[2:])
+
+	store, err := storage.Open(*dbDriver, *dbDSN)
+	if err != nil {
+		log.Fatalf("open storage failed. %s", err)
+	}
+	defer store.Close()
+
+	switch action {
+	case "create":
+		if *schoolID == 0 {
+			log.Fatal("school-id is required")
+		}
+		if err := saveTenant(store, *schoolID, *schoolName, *maxRunningTasks, *maxPayloadBytes); err != nil {
+			log.Fatalf("save tenant failed. %s", err)
+		}
+
+		key, err := middlewares.GenerateAPIKey()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		apiKey := &models.APIKey{
+			SchoolID:  *schoolID,
+			Name:      *name,
+			KeyPrefix: key[:10],
+			KeyHash:   middlewares.HashAPIKey(key),
+		}
+		if err := store.APIKeys().Create(apiKey); err != nil {
+			log.Fatalf("create api key failed. %s", err)
+		}
+
+		// 明文密钥只显示这一次
+		fmt.Printf("key_id: %d\nschool_id: %d\napi_key: %s\n", apiKey.KeyID, apiKey.SchoolID, key)
+
+	case "revoke":
+		if *keyID == 0 {
+			log.Fatal("key-id is required")
+		}
+		if err := store.APIKeys().Revoke(*keyID); err != nil {
+			log.Fatalf("revoke api key failed. %s", err)
+		}
+		fmt.Printf("api key %d revoked\n", *keyID)
+
+	case "list":
+		keys, err := store.APIKeys().List(*schoolID)
+		if err != nil {
+			log.Fatalf("list api keys failed. %s", err)
+		}
+		for _, key := range keys {
+			status := "active"
+			if key.RevokedAt != nil {
+				status = "revoked at " + key.RevokedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%d\tschool %d\t%s...\t%s\t%s\n", key.KeyID, key.SchoolID, key.KeyPrefix, key.Name, status)
+		}
+
+	// 租户隔离之前新增的任务学校id为0, 任何密钥都不能访问, 需要分配给学校
+	case "assign-orphans":
+		if *schoolID == 0 {
+			log.Fatal("school-id is required")
+		}
+		n, err := store.Tasks().AssignOrphans(*schoolID)
+		if err != nil {
+			log.Fatalf("assign orphaned tasks failed. %s", err)
+		}
+		fmt.Printf("%d tasks assigned to school %d\n", n, *schoolID)
+
+	default:
+		log.Fatalf("unknown action %s", action)
+	}
+}
+
+// 新增或者更新租户设置, 没有设置的字段保持不变
+func saveTenant(store storage.Storage, schoolID uint64, name string, maxRunningTasks int, maxPayloadBytes int64) error {
+
+	tenant, err := store.Tenants().Get(schoolID)
+	if errors.Is(err, storage.ErrNotFound) {
+		tenant = &models.Tenant{SchoolID: schoolID}
+	} else if err != nil {
+		return err
+	}
+
+	if name != "" {
+		tenant.Name = name
+	}
+	if maxRunningTasks > 0 {
+		tenant.MaxRunningTasks = maxRunningTasks
+	}
+	if maxPayloadBytes > 0 {
+		tenant.MaxPayloadBytes = maxPayloadBytes
+	}
+	return store.Tenants().Save(tenant)
+}
+
+// 获取环境变量, 没有设置时使用默认值
+func getEnv(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -50,6 +50,12 @@ func main() {
 	log.Printf("bestGen: %d, bestIndividual.Fitness: %d, uniqueId: %s\n", bestGen, bestIndividual.Fitness, bestIndividual.UniqueId)
 	bestIndividual.PrintSchedule(scheduleInput.Schedule, scheduleInput.Subjects)
 
+	// 打印学生课程冲突和课时过多的报告
+	bestIndividual.PrintStudentReport(scheduleInput.Schedule, scheduleInput.StudentEnrollments, scheduleInput.GetMaxStudentDailyLessons())
+
+	// 打印与已发布课表相比的变化
+	bestIndividual.PrintTimetableDiff(scheduleInput.Schedule, scheduleInput.BaseTimetable)
+
 	// 打印个体的约束状态信息
 	log.Println("打印个体的约束状态信息")
 	bestIndividual.PrintConstraints()
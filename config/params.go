@@ -24,6 +24,39 @@ const (
 	SubjectDayLimitThreshold    = 2 // 相同节次排课数量限制
 )
 
+const (
+	StudentClashPenalty    = 100 // 学生课程冲突的处罚分, 每个冲突从个体适应度中扣除
+	MaxStudentDailyLessons = 8   // 学生每天最多课时数, 超过时在排课质量报告中列出, 输入中没有设置时使用
+)
+
+const (
+	DisruptionWeight = 20 // 与基准课表不同的基因的处罚分, 每个基因从个体适应度中扣除, 输入中没有设置时使用
+)
+
+// 排课任务
+const (
+	MaxRunningTasks         = 2                            // 同时执行的排课任务数量
+	TaskPollInterval        = 5 * time.Second              // 扫描等待执行的任务的间隔
+	TaskStaleTimeout        = MaxDuration + 10*time.Minute // 执行中的任务超过这个时间没有更新, 视为程序已经崩溃, 改回等待执行
+	TaskStatusCheckInterval = time.Second                  // 执行中检查任务是否已经取消的间隔
+	TaskEventInterval       = time.Second                  // 排课进度事件流查询新的遗传代数据的间隔
+)
+
+// 租户(学校), 租户没有设置限制时使用
+const (
+	TenantMaxRunningTasks = 1        // 每个学校同时执行的排课任务数量
+	TenantMaxPayloadBytes = 10 << 20 // 每个请求体的最大字节数, 10MB
+)
+
+// 排课任务完成回调
+const (
+	CallbackTimeout        = 10 * time.Second // 回调请求的超时时间
+	CallbackInitialBackoff = 10 * time.Second // 回调失败后第一次重试的间隔, 之后每次翻倍
+	CallbackMaxBackoff     = 30 * time.Minute // 回调重试的最大间隔
+	CallbackMaxAttempts    = 8                // 最多回调次数, 都失败后不再重试
+	CallbackBatchSize      = 10               // 每次扫描最多发送的回调数量
+)
+
 const (
 	MaxPenaltyScore = 3 // 表示ClassMatrix中的元素可以具有的最大可能得分, 这个得分很重要,会直接影响适应度计算的结果, 一般和最高的奖励分是相同的
 
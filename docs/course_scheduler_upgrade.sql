@@ -0,0 +1,100 @@
+-- 从只有 task, schedule_error_log, schedule_result 三个表的旧版本升级
+-- 新部署直接使用 course_scheduler.sql 创建数据表, 不需要执行这个文件
+-- 程序启动时检查数据表和字段, 缺少时提示执行这个文件
+
+ALTER TABLE `task`
+  MODIFY `task_id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '任务ID',
+  MODIFY `task_data` JSON NOT NULL COMMENT '任务数据',
+  MODIFY `status` ENUM('pending', 'running', 'success', 'failed', 'cancelled') NOT NULL COMMENT '任务状态',
+  ADD COLUMN `school_id` bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT '学校(租户)ID' AFTER `task_id`,
+  ADD COLUMN `attempts` int(10) unsigned NOT NULL DEFAULT 0 COMMENT '执行次数' AFTER `progress`,
+  ADD COLUMN `started_at` TIMESTAMP NULL DEFAULT NULL COMMENT '最近一次开始执行时间' AFTER `attempts`,
+  ADD COLUMN `finished_at` TIMESTAMP NULL DEFAULT NULL COMMENT '最近一次执行结束时间' AFTER `started_at`,
+  ADD COLUMN `callback_url` varchar(1024) NOT NULL DEFAULT '' COMMENT '任务结束时的回调地址' AFTER `finished_at`,
+  ADD COLUMN `callback_secret` varchar(255) NOT NULL DEFAULT '' COMMENT '回调签名密钥' AFTER `callback_url`,
+  ADD COLUMN `callback_status` ENUM('', 'pending', 'delivered', 'failed') NOT NULL DEFAULT '' COMMENT '回调状态' AFTER `callback_secret`,
+  ADD COLUMN `callback_attempts` int(10) unsigned NOT NULL DEFAULT 0 COMMENT '回调次数' AFTER `callback_status`,
+  ADD COLUMN `callback_next_at` TIMESTAMP NULL DEFAULT NULL COMMENT '下次回调时间' AFTER `callback_attempts`,
+  ADD COLUMN `idempotency_key` varchar(255) NULL DEFAULT NULL COMMENT '幂等键' AFTER `callback_next_at`,
+  ADD COLUMN `input_hash` char(64) NOT NULL DEFAULT '' COMMENT '规范化后排课数据的SHA-256' AFTER `idempotency_key`,
+  ADD COLUMN `source_task_id` bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT '复用了这个任务的排课结果' AFTER `input_hash`,
+  ADD KEY `idx_status` (`status`),
+  ADD KEY `idx_school_status` (`school_id`, `status`),
+  ADD KEY `idx_created_at` (`created_at`),
+  ADD KEY `idx_callback` (`callback_status`, `callback_next_at`),
+  ADD UNIQUE KEY `uk_school_idempotency_key` (`school_id`, `idempotency_key`),
+  ADD KEY `idx_school_input_hash` (`school_id`, `input_hash`);
+
+-- 旧版本的任务 school_id 为 0, 任何密钥都不能访问, 需要分配给学校
+-- 也可以使用 go run ./cmd/apikey assign-orphans -school-id 1
+-- UPDATE `task` SET `school_id` = 1 WHERE `school_id` = 0;
+
+
+CREATE TABLE IF NOT EXISTS `schedule_report` (
+  `task_id` bigint(20) NOT NULL COMMENT '任务ID',
+  `fitness` int(11) NOT NULL COMMENT '适应度',
+  `num_hard_violations` int(11) NOT NULL DEFAULT 0 COMMENT '未满足的硬约束条件数量',
+  `num_soft_violations` int(11) NOT NULL DEFAULT 0 COMMENT '未满足的软约束条件数量',
+  `termination_reason` varchar(32) NOT NULL COMMENT '终止原因',
+  `report` mediumtext NOT NULL COMMENT '排课质量报告(JSON)',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`task_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='排课质量报告表';
+
+
+CREATE TABLE IF NOT EXISTS `task_generation` (
+  `task_id` bigint(20) NOT NULL COMMENT '任务ID',
+  `generation` int(11) NOT NULL COMMENT '遗传代数(从1开始)',
+  `best_fitness` int(11) NOT NULL COMMENT '最优适应度',
+  `avg_fitness` double NOT NULL COMMENT '平均适应度',
+  `worst_fitness` int(11) NOT NULL COMMENT '最差适应度',
+  `gen_without_improvement` int(11) NOT NULL COMMENT '连续没有改进的代数',
+  `progress` tinyint(3) NOT NULL COMMENT '任务进度(0-100)',
+  `elapsed_ms` bigint(20) NOT NULL COMMENT '已运行时间(毫秒)',
+  `eta_ms` bigint(20) NOT NULL COMMENT '预计剩余时间(毫秒)',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`task_id`, `generation`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='排课任务遗传代数据表';
+
+
+CREATE TABLE IF NOT EXISTS `callback_delivery` (
+  `delivery_id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '回调记录ID',
+  `task_id` bigint(20) NOT NULL COMMENT '任务ID',
+  `attempt` int(10) unsigned NOT NULL COMMENT '第几次回调',
+  `url` varchar(1024) NOT NULL COMMENT '回调地址',
+  `task_status` varchar(16) NOT NULL COMMENT '回调时的任务状态',
+  `status_code` int(11) NOT NULL DEFAULT 0 COMMENT '回调地址返回的HTTP状态码',
+  `success` tinyint(1) NOT NULL DEFAULT 0 COMMENT '是否成功',
+  `error_message` text NOT NULL COMMENT '错误信息',
+  `duration_ms` bigint(20) NOT NULL DEFAULT 0 COMMENT '请求耗时(毫秒)',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (`delivery_id`),
+  KEY `idx_task_id` (`task_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='排课任务回调记录表';
+
+
+CREATE TABLE IF NOT EXISTS `tenant` (
+  `school_id` bigint(20) unsigned NOT NULL COMMENT '学校ID',
+  `name` varchar(255) NOT NULL DEFAULT '' COMMENT '学校名称',
+  `max_running_tasks` int(10) unsigned NOT NULL DEFAULT 0 COMMENT '同时执行的排课任务数量, 0使用默认值',
+  `max_payload_bytes` bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT '请求体的最大字节数, 0使用默认值',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`school_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='租户表';
+
+
+CREATE TABLE IF NOT EXISTS `api_key` (
+  `key_id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT 'API密钥ID',
+  `school_id` bigint(20) unsigned NOT NULL COMMENT '学校ID',
+  `name` varchar(255) NOT NULL DEFAULT '' COMMENT '密钥名称',
+  `key_prefix` varchar(16) NOT NULL COMMENT '密钥前缀, 用于识别密钥',
+  `key_hash` char(64) NOT NULL COMMENT '密钥的SHA-256',
+  `revoked_at` TIMESTAMP NULL DEFAULT NULL COMMENT '吊销时间',
+  `created_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  `updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (`key_id`),
+  UNIQUE KEY `uk_key_hash` (`key_hash`),
+  KEY `idx_school_id` (`school_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='API密钥表';
@@ -1,15 +1,26 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	"course_scheduler/config"
 	"course_scheduler/internal/api/v1/middlewares"
+	"course_scheduler/internal/base"
+	"course_scheduler/internal/genetic_algorithm"
 	"course_scheduler/internal/models"
+	"course_scheduler/internal/storage"
+	"course_scheduler/internal/worker"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"github.com/samber/lo"
 )
 
 // Handler1 示例处理程序
@@ -24,119 +35,704 @@ func Handler1(c *gin.Context) {
 }
 
 // 创建排课任务
-func CreateTaskHandler(db *gorm.DB) gin.HandlerFunc {
+// 请求体需要是正确的排课输入, 检查不通过时返回 422 和错误列表, 不创建任务
+// 请求体中可以包含 callback_url 和 callback_secret, 任务结束(成功, 失败, 取消)时回调, 这两个字段不保存到任务数据中
+// 任务属于API密钥所在的学校
+// 参数:
+//
+//	Idempotency-Key 请求头, 幂等键, 可以为空. 同一个学校相同幂等键的请求只创建一个任务, 排课数据相同时返回 200 和已经创建的任务, 不同时返回 409
+//	reuse_result 是否复用排课结果, 默认为 false. 为 true 时如果有排课数据相同的成功任务, 直接复用它的排课结果, 任务状态为 success
+func CreateTaskHandler(store storage.Storage) gin.HandlerFunc {
 
 	return func(c *gin.Context) {
-		// 解析请求体中的 JSON 数据
-		var taskData map[string]interface{}
-		if err := c.ShouldBindJSON(&taskData); err != nil {
-			c.JSON(400, gin.H{"error": err.Error()})
+		// 解析并检查请求体中的 JSON 数据
+		payload, ok := validateTaskData(c)
+		if !ok {
 			return
 		}
 
-		// 将 taskData 转换为 JSON 格式的字符串
-		taskDataBytes, err := json.Marshal(taskData)
+		idempotencyKey := c.GetHeader(idempotencyKeyHeader)
+		if len(idempotencyKey) > 255 {
+			c.JSON(400, gin.H{"error": "invalid " + idempotencyKeyHeader})
+			return
+		}
+
+		reuseResult, err := strconv.ParseBool(c.DefaultQuery("reuse_result", "false"))
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid reuse_result"})
+			return
+		}
+
+		inputHash, err := payload.Input.Hash()
 		if err != nil {
 			c.JSON(500, gin.H{"error": err.Error()})
 			return
 		}
 
+		// 相同幂等键的任务已经存在时不再创建
+		if idempotencyKey != "" {
+			existing, err := store.Tasks().GetByIdempotencyKey(schoolID(c), idempotencyKey)
+			if err == nil {
+				replayTask(c, existing, inputHash)
+				return
+			}
+			if !errors.Is(err, storage.ErrNotFound) {
+				c.JSON(500, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
 		// 在排课任务队列中新增一条排课任务
 		task := &models.Task{
-			TaskData: string(taskDataBytes),
-			Status:   "pending",
+			SchoolID:       schoolID(c),
+			TaskData:       payload.Data,
+			Status:         models.TaskStatusPending,
+			CallbackURL:    payload.Callback.URL,
+			CallbackSecret: payload.Callback.Secret,
+			InputHash:      inputHash,
+		}
+		if idempotencyKey != "" {
+			task.IdempotencyKey = &idempotencyKey
+		}
+
+		err = createTask(store, task, reuseResult)
+		if errors.Is(err, storage.ErrDuplicateKey) {
+			// 并发的请求使用了相同的幂等键, 返回先创建的任务
+			if existing, getErr := store.Tasks().GetByIdempotencyKey(task.SchoolID, idempotencyKey); getErr == nil {
+				replayTask(c, existing, inputHash)
+				return
+			}
 		}
-		if err := db.Create(task).Error; err != nil {
+
+		if err != nil {
 			c.JSON(500, gin.H{"error": err.Error()})
 			return
 		}
 
 		// 给网站程序返回一个 task_id 作为接收数据的返回值
-		c.JSON(201, gin.H{"task_id": strconv.FormatUint(task.TaskID, 10)})
+		c.JSON(201, gin.H{"task_id": strconv.FormatUint(task.TaskID, 10), "status": task.Status, "source_task_id": sourceTaskID(task)})
+	}
+}
+
+// 幂等键的请求头
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// 新增排课任务
+// reuseResult 为 true 时复用排课数据相同的成功任务的排课结果, 不需要再执行排课
+// 遗传算法每次执行的结果不同, 复用时返回的是之前那次执行的结果
+func createTask(store storage.Storage, task *models.Task, reuseResult bool) error {
+
+	if reuseResult {
+		source, err := store.Tasks().FindReusable(task.SchoolID, task.InputHash)
+		if err == nil {
+			err = store.Tasks().CreateFromSource(task, source.TaskID)
+			// 查询之后源任务被删除时, 新增等待执行的任务
+			if !errors.Is(err, storage.ErrNotFound) && !errors.Is(err, storage.ErrTaskStatus) {
+				return err
+			}
+		} else if !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+	return store.Tasks().Create(task)
+}
+
+// 返回幂等键已经创建的任务, 排课数据不同时返回 409
+func replayTask(c *gin.Context, task *models.Task, inputHash string) {
+
+	taskID := strconv.FormatUint(task.TaskID, 10)
+	if task.InputHash != inputHash {
+		c.JSON(409, gin.H{"error": "idempotency key already used with different task data", "task_id": taskID})
+		return
+	}
+
+	c.Header("Idempotent-Replayed", "true")
+	c.JSON(200, gin.H{"task_id": taskID, "status": task.Status, "source_task_id": sourceTaskID(task)})
+}
+
+// 复用的排课结果的任务id, 没有复用时为空
+func sourceTaskID(task *models.Task) string {
+	if task.SourceTaskID == 0 {
+		return ""
 	}
+	return strconv.FormatUint(task.SourceTaskID, 10)
 }
 
-// 执行排课任务
-func ExecuteTaskHandler(db *gorm.DB) gin.HandlerFunc {
+// 检查排课数据, 不创建任务
+// 检查通过时返回 200, 不通过时返回 422 和错误列表
+func ValidateTaskHandler(store storage.Storage) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 从 URL 中获取 task_id
-		taskID := c.Param("task_id")
+		if _, ok := validateTaskData(c); ok {
+			c.JSON(200, gin.H{"valid": true})
+		}
+	}
+}
+
+// 任务结束时的回调设置
+type taskCallback struct {
+	URL    string // 回调地址
+	Secret string // 签名密钥, 可以为空
+}
+
+// 检查通过的请求体
+type taskPayload struct {
+	Data     string              // 压缩后的排课数据 JSON, 不包含回调字段
+	Input    *base.ScheduleInput // 解析后的排课数据
+	Callback *taskCallback       // 回调设置
+}
+
+// 读取并检查请求体中的排课数据
+// 检查失败时直接返回错误响应, 第二个返回值为 false
+func validateTaskData(c *gin.Context) (*taskPayload, bool) {
+
+	// 请求体的大小由认证中间件按照租户的设置限制
+	body, err := c.GetRawData()
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		c.JSON(413, gin.H{"error": "request body too large", "limit": maxBytesErr.Limit})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, body); err != nil {
+		c.JSON(400, gin.H{"error": "invalid json. " + err.Error()})
+		return nil, false
+	}
+
+	data, callback, fieldErr := extractCallback(buf.Bytes())
+	if fieldErr != nil {
+		c.JSON(422, gin.H{"valid": false, "errors": base.ValidationErrors{fieldErr}})
+		return nil, false
+	}
+
+	input, errs := base.ValidateScheduleInputJSON(data)
+	if len(errs) > 0 {
+		c.JSON(422, gin.H{"valid": false, "errors": errs})
+		return nil, false
+	}
+	return &taskPayload{Data: string(data), Input: input, Callback: callback}, true
+}
+
+// 从请求体中取出回调字段, 返回去掉回调字段的排课数据
+// 没有回调字段时排课数据不变, 回调地址是内网或者保留的IP地址时返回错误
+func extractCallback(data []byte) ([]byte, *taskCallback, *base.FieldError) {
+
+	var fields struct {
+		CallbackURL    *string `json:"callback_url"`
+		CallbackSecret *string `json:"callback_secret"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && strings.HasPrefix(typeErr.Field, "callback_") {
+			return nil, nil, &base.FieldError{Field: typeErr.Field, Message: "must be string"}
+		}
+		// 其他格式错误由排课数据的检查返回
+		return data, &taskCallback{}, nil
+	}
 
-		// 从排课任务队列中获取到该任务
-		var task models.Task
-		if err := db.Where("id = ? AND status = ?", taskID, "pending").First(&task).Error; err != nil {
-			c.JSON(404, gin.H{"error": "task not found"})
+	if fields.CallbackURL == nil && fields.CallbackSecret == nil {
+		return data, &taskCallback{}, nil
+	}
+
+	callback := &taskCallback{URL: lo.FromPtr(fields.CallbackURL), Secret: lo.FromPtr(fields.CallbackSecret)}
+	if callback.URL == "" && callback.Secret != "" {
+		return nil, nil, &base.FieldError{Field: "callback_url", Message: "is required when callback_secret is set"}
+	}
+
+	if len(callback.URL) > 1024 {
+		return nil, nil, &base.FieldError{Field: "callback_url", Message: "must be at most 1024 characters"}
+	}
+	if len(callback.Secret) > 255 {
+		return nil, nil, &base.FieldError{Field: "callback_secret", Message: "must be at most 255 characters"}
+	}
+
+	if callback.URL != "" {
+		u, err := url.Parse(callback.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, nil, &base.FieldError{Field: "callback_url", Message: "must be an absolute http or https url"}
+		}
+		if err := worker.CheckCallbackURL(callback.URL); err != nil {
+			return nil, nil, &base.FieldError{Field: "callback_url", Message: err.Error()}
+		}
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return data, callback, nil
+	}
+	delete(object, "callback_url")
+	delete(object, "callback_secret")
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, nil, &base.FieldError{Message: err.Error()}
+	}
+	return data, callback, nil
+}
+
+// 查询排课结果
+// 参数:
+//
+//	grade_id, class_id, teacher_id, venue_id, subject_id, weekday(从0开始) 查询条件, 可以为空
+//	view 返回格式 list: 排课结果列表(默认), grid: 按照班级, 教师或者教室的课表网格
+//	by 课表网格的类型 class: 班级(默认), teacher: 教师, venue: 教室
+//
+// 任务状态不是 success 时, 返回任务状态, 失败时返回错误信息
+func GetTaskResultHandler(store storage.Storage) gin.HandlerFunc {
+
+	return func(c *gin.Context) {
+		// 查询排课任务
+		task, ok := getTask(c, store)
+		if !ok {
+			return
+		}
+
+		filter, err := parseResultFilter(c, task.TaskID)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
 			return
 		}
 
-		// 更新任务状态为 running
-		if err := db.Model(&task).Update("status", "running").Error; err != nil {
+		view := c.DefaultQuery("view", "list")
+		if view != "list" && view != "grid" {
+			c.JSON(400, gin.H{"error": "invalid view, must be list or grid"})
+			return
+		}
+
+		gridType := c.DefaultQuery("by", "class")
+		if !lo.Contains(base.TimetableGridTypes, gridType) {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("invalid by, must be one of %v", base.TimetableGridTypes)})
+			return
+		}
+
+		// 如果任务状态是 pending、running、failed 或 cancelled，则返回任务状态
+		resp, err := taskStatus(store, task)
+		if err != nil {
 			c.JSON(500, gin.H{"error": err.Error()})
 			return
 		}
+		if task.Status != models.TaskStatusSuccess {
+			c.JSON(200, resp)
+			return
+		}
 
-		// 执行排课
-		scheduleResults, _, err := middlewares.ExecuteTask(task.TaskID, task.TaskData)
+		// 如果任务状态是 success，则返回排课结果
+		results, err := store.Results().Query(filter)
 		if err != nil {
-			// 执行排课失败，更新任务状态为 failed
-			if err := db.Model(&task).Update("status", "failed").Error; err != nil {
-				c.JSON(500, gin.H{"error": err.Error()})
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+
+		if view == "list" {
+			if results == nil {
+				results = []*models.ScheduleResult{}
+			}
+			resp["results"] = results
+			c.JSON(200, resp)
+			return
+		}
+
+		// 课表网格中的名称从任务数据中获取
+		input, err := base.ParseScheduleInputFromJSON(task.TaskData)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+
+		grids, err := base.BuildTimetableGrids(input, results, gridType)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		resp["grids"] = grids
+		c.JSON(200, resp)
+	}
+}
+
+// 任务状态, 进度, 失败时包含最近一次的错误信息
+func taskStatus(store storage.Storage, task *models.Task) (gin.H, error) {
+
+	resp := gin.H{"task_id": strconv.FormatUint(task.TaskID, 10), "status": task.Status, "progress": task.Progress}
+	if task.Status == models.TaskStatusFailed {
+		errorLogs, err := store.ErrorLogs().ListByTask(task.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		if len(errorLogs) > 0 {
+			resp["error_message"] = errorLogs[len(errorLogs)-1].ErrorMsg
+		}
+	}
+
+	// 排课成功但是有学生课程冲突时提示, 冲突的详细信息在排课质量报告中
+	// 部分重排时返回与已发布课表相比的变化汇总
+	if task.Status == models.TaskStatusSuccess {
+		summary, err := reportSummary(store, task.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		if summary.NumStudentClashes > 0 {
+			resp["num_student_clashes"] = summary.NumStudentClashes
+		}
+		if summary.TimetableDiff != nil {
+			resp["timetable_diff"] = summary.TimetableDiff
+		}
+	}
+	return resp, nil
+}
+
+// 排课质量报告中和排课结果一起返回的内容
+type scheduleReportSummary struct {
+	NumStudentClashes int                                     `json:"num_student_clashes"`
+	TimetableDiff     *genetic_algorithm.TimetableDiffSummary `json:"timetable_diff"`
+}
+
+// 从排课质量报告中获取学生课程冲突数量和课表变化汇总, 没有报告时返回空的汇总
+func reportSummary(store storage.Storage, taskID uint64) (*scheduleReportSummary, error) {
+
+	summary := &scheduleReportSummary{}
+	report, err := store.Reports().GetByTask(taskID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return summary, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(report.Report), summary); err != nil {
+		return nil, fmt.Errorf("unmarshal schedule report failed. %s", err)
+	}
+	return summary, nil
+}
+
+// 推送排课进度(Server-Sent Events)
+// 1. generation 事件: 每一代遗传的最优, 平均, 最差适应度, 连续没有改进的代数, 进度和预计剩余时间, 连接时先推送已完成的代
+// 2. status 事件: 任务状态和进度, 连接时和状态变化时推送, 任务结束(success, failed, cancelled)后关闭连接
+// 3. error 事件: 查询数据失败, 推送后关闭连接
+func GetTaskEventsHandler(store storage.Storage) gin.HandlerFunc {
+
+	return func(c *gin.Context) {
+		task, ok := getTask(c, store)
+		if !ok {
+			return
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		// 关闭 nginx 的缓冲, 事件才能及时推送
+		c.Header("X-Accel-Buffering", "no")
+
+		ticker := time.NewTicker(config.TaskEventInterval)
+		defer ticker.Stop()
+
+		lastGeneration := 0
+		lastStatus := ""
+		attempts := task.Attempts
+		for {
+			// 任务重新执行时, 遗传代数据从第一代重新开始
+			if task.Attempts != attempts {
+				attempts = task.Attempts
+				lastGeneration = 0
+			}
+
+			// 先查询任务再查询遗传代数据, 任务结束时已经包含所有的代
+			generations, err := store.Generations().ListByTask(task.TaskID, lastGeneration)
+			if err != nil {
+				c.SSEvent("error", gin.H{"error": err.Error()})
 				return
 			}
+			for _, generation := range generations {
+				c.SSEvent("generation", generation)
+				lastGeneration = generation.Generation
+			}
 
-			// 记录排课错误日志
-			errorLog := &models.ScheduleErrorLog{
-				TaskID:   task.TaskID,
-				ErrorMsg: err.Error(),
+			if task.Status != lastStatus {
+				lastStatus = task.Status
+				resp, err := taskStatus(store, task)
+				if err != nil {
+					c.SSEvent("error", gin.H{"error": err.Error()})
+					return
+				}
+				c.SSEvent("status", resp)
 			}
-			if err := db.Create(errorLog).Error; err != nil {
-				c.JSON(500, gin.H{"error": err.Error()})
+			c.Writer.Flush()
+
+			if lo.Contains(finishedTaskStatuses, task.Status) {
 				return
 			}
-		} else {
-			// 执行排课成功，更新任务状态为 success
-			if err := db.Model(&task).Update("status", "success").Error; err != nil {
-				c.JSON(500, gin.H{"error": err.Error()})
+
+			select {
+			case <-c.Request.Context().Done():
 				return
+			case <-ticker.C:
 			}
 
-			// 将排课结果写入排课结果数据表
-			err := db.CreateInBatches(scheduleResults, len(scheduleResults)).Error
+			task, err = store.Tasks().Get(task.TaskID)
 			if err != nil {
-				c.JSON(500, gin.H{"error": err.Error()})
+				c.SSEvent("error", gin.H{"error": err.Error()})
 				return
 			}
 		}
+	}
+}
+
+// 查询排课质量报告
+// 报告包含适应度, 每个基因未满足的硬约束和软约束条件, 分散度, 遗传代数, 终止原因和运行时间
+// 任务状态不是 success 时, 返回任务状态
+func GetTaskReportHandler(store storage.Storage) gin.HandlerFunc {
+
+	return func(c *gin.Context) {
+		task, ok := getTask(c, store)
+		if !ok {
+			return
+		}
 
-		c.JSON(200, gin.H{"status": task.Status})
+		resp := gin.H{"task_id": strconv.FormatUint(task.TaskID, 10), "status": task.Status, "progress": task.Progress}
+		if task.Status != models.TaskStatusSuccess {
+			c.JSON(200, resp)
+			return
+		}
+
+		report, err := store.Reports().GetByTask(task.TaskID)
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(404, gin.H{"error": "report not found"})
+			return
+		}
+
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+
+		// 报告已经是 JSON 格式, 直接返回
+		resp["report"] = json.RawMessage(report.Report)
+		c.JSON(200, resp)
 	}
 }
 
-// 查询排课结果
-func GetTaskResultHandler(db *gorm.DB) gin.HandlerFunc {
+// 解析排课结果的查询条件
+func parseResultFilter(c *gin.Context, taskID uint64) (*storage.ScheduleResultFilter, error) {
+
+	filter := &storage.ScheduleResultFilter{TaskID: taskID}
+	params := []struct {
+		key   string
+		value *uint64
+	}{
+		{"grade_id", &filter.GradeID},
+		{"class_id", &filter.ClassID},
+		{"teacher_id", &filter.TeacherID},
+		{"venue_id", &filter.VenueID},
+		{"subject_id", &filter.SubjectID},
+	}
+
+	for _, param := range params {
+		value := c.Query(param.key)
+		if value == "" {
+			continue
+		}
+
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s", param.key)
+		}
+		*param.value = id
+	}
+
+	if value := c.Query("weekday"); value != "" {
+		weekday, err := strconv.ParseInt(value, 10, 8)
+		if err != nil || weekday < 0 {
+			return nil, errors.New("invalid weekday")
+		}
+		filter.Weekday = lo.ToPtr(int8(weekday))
+	}
+	return filter, nil
+}
+
+// 根据 URL 中的 task_id 获取排课任务
+// 只能获取当前学校的任务, 其他学校的任务返回 404
+// 获取失败时直接返回错误响应, 第二个返回值为 false
+func getTask(c *gin.Context, store storage.Storage) (*models.Task, bool) {
+
+	taskID, err := strconv.ParseUint(c.Param("task_id"), 10, 64)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid task_id"})
+		return nil, false
+	}
+
+	task, err := store.Tasks().Get(taskID)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && task.SchoolID != schoolID(c)) {
+		c.JSON(404, gin.H{"error": "task not found"})
+		return nil, false
+	}
+
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return task, true
+}
+
+// 当前请求的学校id, 没有经过认证时为0
+func schoolID(c *gin.Context) uint64 {
+	if tenant := middlewares.GetTenant(c); tenant != nil {
+		return tenant.SchoolID
+	}
+	return 0
+}
+
+// 任务列表的分页数量
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// 查询排课任务列表
+// 参数: status 任务状态, created_after, created_before 创建时间范围(RFC3339 或者 2006-01-02), page 页码(从1开始), page_size 每页数量
+// 只返回当前学校的任务
+func ListTasksHandler(store storage.Storage) gin.HandlerFunc {
 
 	return func(c *gin.Context) {
-		// 从 URL 中获取 task_id
-		taskID := c.Param("task_id")
 
-		// 查询排课任务
-		var task models.Task
-		if err := db.Where("id = ?", taskID).First(&task).Error; err != nil {
-			c.JSON(404, gin.H{"error": "task not found"})
+		filter := &storage.TaskFilter{SchoolID: schoolID(c), Status: c.Query("status")}
+		if filter.Status != "" && !lo.Contains(taskStatuses, filter.Status) {
+			c.JSON(400, gin.H{"error": "invalid status"})
 			return
 		}
 
-		// 如果任务状态是 success，则返回排课结果
-		if task.Status == "success" {
-			var result []models.ScheduleResult
-			if err := db.Where("task_id = ?", taskID).Find(&result).Error; err != nil {
-				c.JSON(500, gin.H{"error": err.Error()})
-				return
-			}
-			c.JSON(200, result)
-		} else {
-			// 如果任务状态是 pending、running 或 failed，则排课结果为空
-			c.JSON(200, []models.ScheduleResult{})
+		var err error
+		if filter.CreatedAfter, err = parseTimeQuery(c, "created_after"); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if filter.CreatedBefore, err = parseTimeQuery(c, "created_before"); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+		if err != nil || page < 1 {
+			c.JSON(400, gin.H{"error": "invalid page"})
+			return
+		}
+
+		pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+		if err != nil || pageSize < 1 || pageSize > maxPageSize {
+			c.JSON(400, gin.H{"error": "invalid page_size"})
+			return
+		}
+
+		filter.Offset = (page - 1) * pageSize
+		filter.Limit = pageSize
+		tasks, total, err := store.Tasks().List(filter)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+
+		if tasks == nil {
+			tasks = []*models.Task{}
+		}
+		c.JSON(200, gin.H{"total": total, "page": page, "page_size": pageSize, "tasks": tasks})
+	}
+}
+
+// 取消排课任务
+// 等待执行的任务直接取消, 执行中的任务在下一代遗传结束时停止
+func CancelTaskHandler(store storage.Storage) gin.HandlerFunc {
+	return changeTaskHandler(store, store.Tasks().Cancel)
+}
+
+// 重新执行失败或者已经取消的排课任务
+func RetryTaskHandler(store storage.Storage) gin.HandlerFunc {
+	return changeTaskHandler(store, store.Tasks().Retry)
+}
+
+// 删除排课任务, 以及任务的排课结果, 错误日志, 排课质量报告, 遗传代数据和回调记录
+// 执行中的任务不能删除, 需要先取消
+func DeleteTaskHandler(store storage.Storage) gin.HandlerFunc {
+
+	return func(c *gin.Context) {
+		task, ok := getTask(c, store)
+		if !ok {
+			return
+		}
+
+		err := store.Tasks().Delete(task.TaskID)
+		if errors.Is(err, storage.ErrTaskStatus) {
+			c.JSON(409, gin.H{"error": "running task cannot be deleted", "status": task.Status})
+			return
+		}
+
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(204)
+	}
+}
+
+// 排课任务的状态
+var taskStatuses = []string{
+	models.TaskStatusPending,
+	models.TaskStatusRunning,
+	models.TaskStatusSuccess,
+	models.TaskStatusFailed,
+	models.TaskStatusCancelled,
+}
+
+// 已经结束的排课任务状态
+var finishedTaskStatuses = []string{
+	models.TaskStatusSuccess,
+	models.TaskStatusFailed,
+	models.TaskStatusCancelled,
+}
+
+// 修改排课任务状态, 成功时返回修改后的任务
+// 当前状态不允许修改时返回 409
+func changeTaskHandler(store storage.Storage, change func(taskID uint64) error) gin.HandlerFunc {
+
+	return func(c *gin.Context) {
+		task, ok := getTask(c, store)
+		if !ok {
+			return
+		}
+
+		err := change(task.TaskID)
+		if errors.Is(err, storage.ErrTaskStatus) {
+			c.JSON(409, gin.H{"error": err.Error(), "status": task.Status})
+			return
+		}
+
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+
+		task, ok = getTask(c, store)
+		if !ok {
+			return
+		}
+		task.TaskData = ""
+		c.JSON(200, task)
+	}
+}
+
+// 解析时间查询参数, 支持 RFC3339 和 2006-01-02 两种格式, 参数为空时返回nil
+func parseTimeQuery(c *gin.Context, key string) (*time.Time, error) {
+
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return &t, nil
 		}
 	}
+	return nil, fmt.Errorf("invalid %s, must be RFC3339 or 2006-01-02", key)
 }
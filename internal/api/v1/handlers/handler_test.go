@@ -0,0 +1,892 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"course_scheduler/internal/api/v1/middlewares"
+	"course_scheduler/internal/api/v1/routes"
+	"course_scheduler/internal/base"
+	"course_scheduler/internal/genetic_algorithm"
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/storage"
+	"course_scheduler/internal/worker"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 测试用的排课数据: 1个班级, 2个科目, 2个教师, 每个科目每周5节课
+const taskData = `{
+	"schedule": {"name": "test", "num_workdays": 5, "num_days_off": 2, "num_forenoon_classes": 4},
+	"subjects": [{"subject_id": 1, "name": "语文", "subject_group_ids": [1], "priority": 1}, {"subject_id": 2, "name": "数学", "subject_group_ids": [1], "priority": 2}],
+	"teachers": [
+		{"teacher_id": 1, "name": "语文1", "teacher_group_ids": [], "class_subjects": [{"grade_id": 1, "class_id": 1, "subject_id": [1]}]},
+		{"teacher_id": 2, "name": "数学1", "teacher_group_ids": [], "class_subjects": [{"grade_id": 1, "class_id": 1, "subject_id": [2]}]}
+	],
+	"teaching_tasks": [
+		{"id": 1, "grade_id": 1, "class_id": 1, "subject_id": 1, "teacher_id": 1, "num_classes_per_week": 5},
+		{"id": 2, "grade_id": 1, "class_id": 1, "subject_id": 2, "teacher_id": 2, "num_classes_per_week": 5}
+	],
+	"grades": [{"school_id": 1, "grade_id": 1, "name": "一年级", "classes": [{"school_id": 1, "class_id": 1, "name": "1班"}]}],
+	"subject_venue_map": {"1_1_1": [101], "2_1_1": [101]}
+}`
+
+// 排课时会在上级目录创建日志文件, 切换到临时目录, 避免在代码目录中写入日志
+func TestMain(m *testing.M) {
+
+	dir, err := os.MkdirTemp("", "handlers_test")
+	if err != nil {
+		panic(err)
+	}
+
+	workDir := filepath.Join(dir, "work")
+	if err := os.MkdirAll(workDir, os.ModePerm); err != nil {
+		panic(err)
+	}
+
+	if err := os.Chdir(workDir); err != nil {
+		panic(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func newTestServer(t *testing.T) (*gin.Engine, storage.Storage) {
+
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open storage failed. %s", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	addAPIKey(t, store, &models.Tenant{SchoolID: 1}, testAPIKey)
+
+	r := gin.New()
+	routes.SetupRoutes(r, store)
+	return r, store
+}
+
+// 领取并执行排课任务, 和后台的排课任务执行程序相同
+func runTask(t *testing.T, store storage.Storage, taskID string) {
+
+	id, _ := strconv.ParseUint(taskID, 10, 64)
+	if err := store.Tasks().Claim(id); err != nil {
+		t.Fatalf("claim failed. %s", err)
+	}
+	task, err := store.Tasks().Get(id)
+	if err != nil {
+		t.Fatalf("get task failed. %s", err)
+	}
+	if err := worker.RunTask(context.Background(), store, task); err != nil {
+		t.Fatalf("run task failed. %s", err)
+	}
+}
+
+// 测试服务器中学校1的API密钥, doRequest 默认使用这个密钥
+const testAPIKey = "test-key"
+
+// 新增租户和API密钥
+func addAPIKey(t *testing.T, store storage.Storage, tenant *models.Tenant, key string) {
+
+	if err := store.Tenants().Save(tenant); err != nil {
+		t.Fatalf("save tenant failed. %s", err)
+	}
+
+	apiKey := &models.APIKey{SchoolID: tenant.SchoolID, KeyPrefix: key[:4], KeyHash: middlewares.HashAPIKey(key)}
+	if err := store.APIKeys().Create(apiKey); err != nil {
+		t.Fatalf("create api key failed. %s", err)
+	}
+}
+
+func doRequest(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	return doRequestWithKey(r, testAPIKey, method, path, body)
+}
+
+// 使用指定的API密钥发送请求, 密钥为空时不设置
+func doRequestWithKey(r *gin.Engine, key, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(middlewares.APIKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func createTask(t *testing.T, r *gin.Engine, body string) string {
+
+	w := doRequest(r, http.MethodPost, "/api/v1/tasks", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create task: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		TaskID string `json:"task_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("create task: %s", err)
+	}
+	return resp.TaskID
+}
+
+// 排课结果查询的返回值
+type resultResponse struct {
+	Status       string                   `json:"status"`
+	ErrorMessage string                   `json:"error_message"`
+	Results      []*models.ScheduleResult `json:"results"`
+	Grids        []*base.TimetableGrid    `json:"grids"`
+}
+
+func getResult(t *testing.T, r *gin.Engine, taskID, query string, resp *resultResponse) *httptest.ResponseRecorder {
+
+	w := doRequest(r, http.MethodGet, fmt.Sprintf("/api/v1/tasks/%s/result?%s", taskID, query), "")
+	if resp != nil && w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+			t.Fatalf("result: %s, body %s", err, w.Body.String())
+		}
+	}
+	return w
+}
+
+// 排课进度事件
+type event struct {
+	Name string
+	Data string
+}
+
+// 获取排课进度事件, 任务需要已经结束
+func getEvents(t *testing.T, r *gin.Engine, taskID string) []*event {
+
+	w := doRequest(r, http.MethodGet, fmt.Sprintf("/api/v1/tasks/%s/events", taskID), "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("events: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	var events []*event
+	for _, block := range strings.Split(strings.TrimSpace(w.Body.String()), "\n\n") {
+		e := &event{}
+		for _, line := range strings.Split(block, "\n") {
+			if name, ok := strings.CutPrefix(line, "event:"); ok {
+				e.Name = name
+			}
+			if data, ok := strings.CutPrefix(line, "data:"); ok {
+				e.Data = data
+			}
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestTaskLifecycle(t *testing.T) {
+
+	r, store := newTestServer(t)
+	taskID := createTask(t, r, taskData)
+
+	// 未执行的任务, 返回任务状态, 没有排课结果
+	var resp resultResponse
+	w := getResult(t, r, taskID, "", &resp)
+	if w.Code != http.StatusOK || resp.Status != models.TaskStatusPending || resp.Results != nil {
+		t.Fatalf("pending result: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	// 未执行的任务没有排课质量报告
+	w = doRequest(r, http.MethodGet, fmt.Sprintf("/api/v1/tasks/%s/report", taskID), "")
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), `"report"`) {
+		t.Fatalf("pending report: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	// 执行排课
+	runTask(t, store, taskID)
+
+	// 查询排课结果
+	tests := []struct {
+		query string
+		count int
+	}{
+		{"", 10},
+		{"subject_id=1", 5},
+		{"teacher_id=2&subject_id=1", 0},
+		{"grade_id=1&class_id=1&venue_id=101", 10},
+	}
+	for _, tt := range tests {
+		resp = resultResponse{}
+		w = getResult(t, r, taskID, tt.query, &resp)
+		if w.Code != http.StatusOK || len(resp.Results) != tt.count {
+			t.Errorf("result %s: status %d, got %d results, want %d", tt.query, w.Code, len(resp.Results), tt.count)
+		}
+	}
+
+	resp = resultResponse{}
+	getResult(t, r, taskID, "weekday=0", &resp)
+	for _, result := range resp.Results {
+		if result.Weekday != 0 {
+			t.Errorf("result weekday=0: got weekday %d", result.Weekday)
+		}
+	}
+
+	// 按照班级和教师的课表网格
+	resp = resultResponse{}
+	getResult(t, r, taskID, "view=grid", &resp)
+	if len(resp.Grids) != 1 || resp.Grids[0].Name != "一年级1班" || len(resp.Grids[0].Cells) != 5 || len(resp.Grids[0].Cells[0]) != 4 {
+		t.Fatalf("class grid: %+v", resp.Grids)
+	}
+
+	lessons := 0
+	for _, day := range resp.Grids[0].Cells {
+		for _, cell := range day {
+			for _, lesson := range cell {
+				lessons++
+				if lesson.SubjectName == "" || lesson.TeacherName == "" {
+					t.Errorf("class grid: lesson names not resolved %+v", lesson)
+				}
+			}
+		}
+	}
+	if lessons != 10 {
+		t.Errorf("class grid: got %d lessons, want 10", lessons)
+	}
+
+	resp = resultResponse{}
+	getResult(t, r, taskID, "view=grid&by=teacher", &resp)
+	if len(resp.Grids) != 2 || resp.Grids[0].Key != "1" || resp.Grids[0].Name != "语文1" {
+		t.Errorf("teacher grid: %+v", resp.Grids)
+	}
+
+	for _, query := range []string{"view=table", "view=grid&by=room", "teacher_id=abc", "weekday=-1"} {
+		w = getResult(t, r, taskID, query, nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("result %s: status %d, want %d", query, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	// 排课质量报告
+	w = doRequest(r, http.MethodGet, fmt.Sprintf("/api/v1/tasks/%s/report", taskID), "")
+	var reportResp struct {
+		Status string                            `json:"status"`
+		Report *genetic_algorithm.ScheduleReport `json:"report"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &reportResp); err != nil || w.Code != http.StatusOK || reportResp.Report == nil {
+		t.Fatalf("report: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	report := reportResp.Report
+	if report.NumGenerations <= 0 || report.BestGen < 0 || report.BestGen > report.NumGenerations || report.TerminationReason == "" {
+		t.Errorf("report: generations %d, best gen %d, termination reason %q", report.NumGenerations, report.BestGen, report.TerminationReason)
+	}
+
+	hard, soft := 0, 0
+	for _, violation := range report.Violations {
+		hard += len(violation.Hard)
+		soft += len(violation.Soft)
+	}
+	if hard != report.NumHardViolations || soft != report.NumSoftViolations {
+		t.Errorf("report: got %d hard, %d soft violations, want %d, %d", hard, soft, report.NumHardViolations, report.NumSoftViolations)
+	}
+
+	// 已结束的任务, 推送所有的遗传代数据和最终状态后关闭
+	events := getEvents(t, r, taskID)
+	if len(events) < 2 || events[len(events)-1].Name != "status" {
+		t.Fatalf("events: got %d events", len(events))
+	}
+
+	var generation models.TaskGeneration
+	for i, event := range events[:len(events)-1] {
+		if event.Name != "generation" {
+			t.Fatalf("events: got %s event, want generation", event.Name)
+		}
+		if err := json.Unmarshal([]byte(event.Data), &generation); err != nil || generation.Generation != i+1 {
+			t.Fatalf("events: generation %d, err %v, data %s", generation.Generation, err, event.Data)
+		}
+	}
+	// 最后一代也会推送, 包括停滞提前终止时
+	if generation.Generation != report.NumGenerations || generation.BestFitness != report.Fitness {
+		t.Errorf("events: last generation %+v, report %+v", generation, report)
+	}
+
+	var status struct {
+		Status   string `json:"status"`
+		Progress int8   `json:"progress"`
+	}
+	if err := json.Unmarshal([]byte(events[len(events)-1].Data), &status); err != nil || status.Status != models.TaskStatusSuccess || status.Progress != 100 {
+		t.Errorf("events: final status %s", events[len(events)-1].Data)
+	}
+
+	// 已经移除同步执行排课的接口
+	w = doRequest(r, http.MethodGet, fmt.Sprintf("/api/v1/tasks/%s/execute", taskID), "")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("execute: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestCreateInvalidTask(t *testing.T) {
+
+	r, store := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		fields []string
+	}{
+		{
+			name:   "required",
+			body:   `{"schedule": {"name": "test", "num_workdays": 5, "num_days_off": 2, "num_forenoon_classes": 4}}`,
+			fields: []string{"teaching_tasks", "teachers", "subjects", "grades"},
+		},
+		{
+			name:   "unknown field",
+			body:   `{"schedule": {"name": "test"}, "unknown": 1}`,
+			fields: []string{"unknown"},
+		},
+		{
+			name:   "type",
+			body:   `{"teaching_tasks": [{"id": "1"}]}`,
+			fields: []string{"teaching_tasks[0].id"},
+		},
+		{
+			name:   "reference",
+			body:   strings.Replace(taskData, `"teacher_id": 2, "num_classes_per_week"`, `"teacher_id": 3, "num_classes_per_week"`, 1),
+			fields: []string{"teaching_tasks[1].teacher_id"},
+		},
+		{
+			// 返回所有的错误, 下标和请求中的教学任务一致
+			name: "check",
+			body: strings.Replace(strings.Replace(taskData, `"teacher_id": 2, "num_classes_per_week": 5`, `"teacher_id": 2, "num_classes_per_week": 6, "num_connected_classes_per_week": 4`, 1),
+				`"subject_venue_map"`, `"subject_period_consistency_constraints": [{"subject_id": 9}], "subject_venue_map"`, 1),
+			fields: []string{"subject_period_consistency_constraints[0].subject_id", "teaching_tasks[1].num_connected_classes_per_week"},
+		},
+		{
+			name:   "max student daily lessons",
+			body:   strings.Replace(taskData, `{`, `{"max_student_daily_lessons": -1, `, 1),
+			fields: []string{"max_student_daily_lessons"},
+		},
+		{
+			name:   "callback url",
+			body:   strings.Replace(taskData, `{`, `{"callback_url": "ftp://example.com", `, 1),
+			fields: []string{"callback_url"},
+		},
+		{
+			name:   "callback loopback url",
+			body:   strings.Replace(taskData, `{`, `{"callback_url": "http://127.0.0.1:8080/callback", `, 1),
+			fields: []string{"callback_url"},
+		},
+		{
+			name:   "callback metadata url",
+			body:   strings.Replace(taskData, `{`, `{"callback_url": "http://169.254.169.254/latest/meta-data", `, 1),
+			fields: []string{"callback_url"},
+		},
+		{
+			name:   "callback private url",
+			body:   strings.Replace(taskData, `{`, `{"callback_url": "https://[fd00::1]/callback", `, 1),
+			fields: []string{"callback_url"},
+		},
+		{
+			name:   "callback secret without url",
+			body:   strings.Replace(taskData, `{`, `{"callback_secret": "secret", `, 1),
+			fields: []string{"callback_url"},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, path := range []string{"/api/v1/tasks", "/api/v1/tasks/validate"} {
+			w := doRequest(r, http.MethodPost, path, tt.body)
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Errorf("%s %s: status %d, want %d", tt.name, path, w.Code, http.StatusUnprocessableEntity)
+				continue
+			}
+
+			var resp struct {
+				Errors []struct {
+					Field string `json:"field"`
+				} `json:"errors"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("%s %s: %s", tt.name, path, err)
+			}
+
+			var fields []string
+			for _, e := range resp.Errors {
+				fields = append(fields, e.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("%s %s: fields %v, want %v", tt.name, path, fields, tt.fields)
+			}
+		}
+	}
+
+	// 检查不通过的任务不会保存
+	w := doRequest(r, http.MethodGet, "/api/v1/tasks", "")
+	if !strings.Contains(w.Body.String(), `"total":0`) {
+		t.Errorf("list: body %s", w.Body.String())
+	}
+
+	// 检查通过
+	w = doRequest(r, http.MethodPost, "/api/v1/tasks/validate", taskData)
+	if w.Code != http.StatusOK {
+		t.Errorf("validate: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	// 回调字段不保存到任务数据中, 密钥不返回
+	w = doRequest(r, http.MethodPost, "/api/v1/tasks/validate", strings.Replace(taskData, `{`, `{"callback_url": "https://93.184.215.14/callback", "callback_secret": "secret", `, 1))
+	if w.Code != http.StatusOK {
+		t.Errorf("validate with callback: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	taskID := createTask(t, r, strings.Replace(taskData, `{`, `{"callback_url": "https://93.184.215.14/callback", "callback_secret": "secret", `, 1))
+	id, _ := strconv.ParseUint(taskID, 10, 64)
+	task, err := store.Tasks().Get(id)
+	if err != nil || task.CallbackURL != "https://93.184.215.14/callback" || task.CallbackSecret != "secret" || strings.Contains(task.TaskData, "callback") {
+		t.Fatalf("create with callback: task %+v, err %v", task, err)
+	}
+
+	w = doRequest(r, http.MethodPost, fmt.Sprintf("/api/v1/tasks/%s/cancel", taskID), "")
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "secret") || !strings.Contains(w.Body.String(), `"callback_status":"pending"`) {
+		t.Errorf("cancel with callback: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	// 相似的路径不匹配
+	for _, path := range []string{"/api/v1/tasksvalidate", "/api/v1/tasks:validate", "/api/v1/tasks/validatefoo"} {
+		w = doRequest(r, http.MethodPost, path, taskData)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: status %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+
+	// 其他请求方法不匹配
+	w = doRequest(r, http.MethodGet, "/api/v1/tasks/validate", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("get validate: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestTaskNotFound(t *testing.T) {
+
+	r, _ := newTestServer(t)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		code   int
+	}{
+		{http.MethodGet, "/api/v1/tasks/999/result", "", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/tasks/abc/result", "", http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/tasks", "not json", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		w := doRequest(r, tt.method, tt.path, tt.body)
+		if w.Code != tt.code {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, w.Code, tt.code)
+		}
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+
+	r, store := newTestServer(t)
+	addAPIKey(t, store, &models.Tenant{SchoolID: 2, MaxPayloadBytes: 100}, "other-key")
+	taskID := createTask(t, r, taskData)
+
+	// 没有密钥或者密钥不存在时不能访问
+	for _, key := range []string{"", "unknown-key"} {
+		w := doRequestWithKey(r, key, http.MethodGet, "/api/v1/tasks", "")
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("key %q: status %d, want %d", key, w.Code, http.StatusUnauthorized)
+		}
+	}
+
+	// 使用 Authorization 头也可以访问
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks/"+taskID+"/result", nil)
+	req.Header.Set("Authorization", "Bearer "+testAPIKey)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("bearer: status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	// 其他学校的任务不存在, 也不出现在任务列表中
+	for _, path := range []string{"/result", "/report", "/events"} {
+		w := doRequestWithKey(r, "other-key", http.MethodGet, "/api/v1/tasks/"+taskID+path, "")
+		if w.Code != http.StatusNotFound {
+			t.Errorf("other school %s: status %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+
+	w = doRequestWithKey(r, "other-key", http.MethodDelete, "/api/v1/tasks/"+taskID, "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("other school delete: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	w = doRequestWithKey(r, "other-key", http.MethodGet, "/api/v1/tasks", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"total":0`) {
+		t.Errorf("other school list: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	id, _ := strconv.ParseUint(taskID, 10, 64)
+	task, err := store.Tasks().Get(id)
+	if err != nil || task.SchoolID != 1 {
+		t.Fatalf("task school: %v, %v", task, err)
+	}
+
+	// 请求体超过学校的限制
+	w = doRequestWithKey(r, "other-key", http.MethodPost, "/api/v1/tasks", taskData)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("payload limit: status %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	// 吊销后不能再使用
+	keys, err := store.APIKeys().List(2)
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("list api keys: %v, %v", keys, err)
+	}
+	if err := store.APIKeys().Revoke(keys[0].KeyID); err != nil {
+		t.Fatalf("revoke api key failed. %s", err)
+	}
+	w = doRequestWithKey(r, "other-key", http.MethodGet, "/api/v1/tasks", "")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key: status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+// 租户隔离之前新增的任务分配给学校后才能访问
+func TestAssignOrphans(t *testing.T) {
+
+	r, store := newTestServer(t)
+	addAPIKey(t, store, &models.Tenant{SchoolID: 2}, "other-key")
+
+	task := &models.Task{TaskData: taskData, Status: models.TaskStatusPending}
+	if err := store.Tasks().Create(task); err != nil {
+		t.Fatalf("create task failed. %s", err)
+	}
+	path := fmt.Sprintf("/api/v1/tasks/%d/result", task.TaskID)
+
+	if w := doRequest(r, http.MethodGet, path, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("orphan: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	n, err := store.Tasks().AssignOrphans(1)
+	if err != nil || n != 1 {
+		t.Fatalf("assign orphans: %d, %v", n, err)
+	}
+	if w := doRequest(r, http.MethodGet, path, ""); w.Code != http.StatusOK {
+		t.Errorf("assigned: status %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := doRequestWithKey(r, "other-key", http.MethodGet, path, ""); w.Code != http.StatusNotFound {
+		t.Errorf("other school: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	// 已经分配的任务不再分配
+	if n, err := store.Tasks().AssignOrphans(2); err != nil || n != 0 {
+		t.Errorf("assign again: %d, %v", n, err)
+	}
+}
+
+func TestIdempotentCreate(t *testing.T) {
+
+	r, store := newTestServer(t)
+	addAPIKey(t, store, &models.Tenant{SchoolID: 2}, "other-key")
+
+	create := func(key, idempotencyKey, path, body string) (*httptest.ResponseRecorder, map[string]string) {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set(middlewares.APIKeyHeader, key)
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		resp := map[string]string{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	w, first := create(testAPIKey, "k1", "/api/v1/tasks", taskData)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	// 相同的排课数据, 空白和字段顺序不同也视为相同
+	var object map[string]json.RawMessage
+	json.Unmarshal([]byte(taskData), &object)
+	compact, _ := json.Marshal(object)
+	w, replay := create(testAPIKey, "k1", "/api/v1/tasks", string(compact))
+	if w.Code != http.StatusOK || replay["task_id"] != first["task_id"] || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("replay: status %d, body %s, want task %s", w.Code, w.Body.String(), first["task_id"])
+	}
+
+	// 数组中元素的顺序不同也视为相同
+	reordered := strings.Replace(taskData, `"subject_venue_map"`, `"class_constraints": [{"grade_id": 1, "class_id": 1, "time_slots": [1, 0], "limit": "avoid"}], "subject_venue_map"`, 1)
+	w, withConstraint := create(testAPIKey, "k2", "/api/v1/tasks", reordered)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create with constraint: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	reordered = strings.Replace(reordered, `"time_slots": [1, 0]`, `"time_slots": [0, 1]`, 1)
+	reordered = strings.Replace(reordered, `{"subject_id": 1, "name": "语文", "subject_group_ids": [1], "priority": 1}, {"subject_id": 2, "name": "数学", "subject_group_ids": [1], "priority": 2}`,
+		`{"subject_id": 2, "name": "数学", "subject_group_ids": [1], "priority": 2}, {"subject_id": 1, "name": "语文", "subject_group_ids": [1], "priority": 1}`, 1)
+	w, replay = create(testAPIKey, "k2", "/api/v1/tasks", reordered)
+	if w.Code != http.StatusOK || replay["task_id"] != withConstraint["task_id"] {
+		t.Errorf("reordered replay: status %d, body %s, want task %s", w.Code, w.Body.String(), withConstraint["task_id"])
+	}
+
+	w, _ = create(testAPIKey, "k1", "/api/v1/tasks", strings.Replace(taskData, `"name": "test"`, `"name": "other"`, 1))
+	if w.Code != http.StatusConflict {
+		t.Errorf("different data: status %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	// 其他学校的幂等键不冲突
+	w, other := create("other-key", "k1", "/api/v1/tasks", taskData)
+	if w.Code != http.StatusCreated || other["task_id"] == first["task_id"] {
+		t.Errorf("other school: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	// 排课数据相同的成功任务的排课结果可以复用
+	// 没有成功任务时新增等待执行的任务
+	w, source := create(testAPIKey, "", "/api/v1/tasks?reuse_result=true", taskData)
+	if w.Code != http.StatusCreated || source["status"] != models.TaskStatusPending {
+		t.Fatalf("source: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	runTask(t, store, source["task_id"])
+
+	w, reused := create(testAPIKey, "", "/api/v1/tasks?reuse_result=true", taskData)
+	if w.Code != http.StatusCreated || reused["status"] != models.TaskStatusSuccess || reused["source_task_id"] != source["task_id"] {
+		t.Fatalf("reused: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	var want, got resultResponse
+	getResult(t, r, source["task_id"], "", &want)
+	getResult(t, r, reused["task_id"], "", &got)
+	if got.Status != models.TaskStatusSuccess || len(got.Results) == 0 || len(got.Results) != len(want.Results) {
+		t.Errorf("reused results: %d, want %d", len(got.Results), len(want.Results))
+	}
+
+	w = doRequest(r, http.MethodGet, fmt.Sprintf("/api/v1/tasks/%s/report", reused["task_id"]), "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"report"`) {
+		t.Errorf("reused report: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	// 不复用时总是重新排课, 其他学校的任务不复用
+	w, resp := create("other-key", "", "/api/v1/tasks?reuse_result=true", taskData)
+	if w.Code != http.StatusCreated || resp["status"] != models.TaskStatusPending {
+		t.Errorf("other school: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	for _, path := range []string{"/api/v1/tasks?reuse_result=false", "/api/v1/tasks"} {
+		if w, resp := create(testAPIKey, "", path, taskData); w.Code != http.StatusCreated || resp["status"] != models.TaskStatusPending {
+			t.Errorf("%s: status %d, body %s", path, w.Code, w.Body.String())
+		}
+	}
+
+	if w, _ := create(testAPIKey, "", "/api/v1/tasks?reuse_result=abc", taskData); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid reuse_result: status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestTaskManagement(t *testing.T) {
+
+	r, store := newTestServer(t)
+	taskID := createTask(t, r, taskData)
+	otherID := createTask(t, r, taskData)
+
+	// 取消等待执行的任务, 已经取消的任务不能再次取消
+	w := doRequest(r, http.MethodPost, fmt.Sprintf("/api/v1/tasks/%s/cancel", taskID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("cancel: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	w = doRequest(r, http.MethodPost, fmt.Sprintf("/api/v1/tasks/%s/cancel", taskID), "")
+	if w.Code != http.StatusConflict {
+		t.Fatalf("cancel again: status %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	// 按照状态查询任务列表
+	var list struct {
+		Total int64          `json:"total"`
+		Tasks []*models.Task `json:"tasks"`
+	}
+	w = doRequest(r, http.MethodGet, "/api/v1/tasks?status=cancelled&created_after=2000-01-01", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("list: %s, body %s", err, w.Body.String())
+	}
+	if list.Total != 1 || len(list.Tasks) != 1 || fmt.Sprint(list.Tasks[0].TaskID) != taskID || list.Tasks[0].TaskData != "" {
+		t.Fatalf("list cancelled: body %s", w.Body.String())
+	}
+
+	w = doRequest(r, http.MethodGet, "/api/v1/tasks?page=2&page_size=1", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("list: %s, body %s", err, w.Body.String())
+	}
+	if list.Total != 2 || len(list.Tasks) != 1 || fmt.Sprint(list.Tasks[0].TaskID) != taskID {
+		t.Fatalf("list page 2: body %s", w.Body.String())
+	}
+
+	// 重新执行已经取消的任务
+	w = doRequest(r, http.MethodPost, fmt.Sprintf("/api/v1/tasks/%s/retry", taskID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("retry: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	runTask(t, store, taskID)
+
+	// 执行成功的任务不能重新执行
+	w = doRequest(r, http.MethodPost, fmt.Sprintf("/api/v1/tasks/%s/retry", taskID), "")
+	if w.Code != http.StatusConflict {
+		t.Fatalf("retry success: status %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	// 删除任务以及排课结果
+	w = doRequest(r, http.MethodDelete, fmt.Sprintf("/api/v1/tasks/%s", taskID), "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("delete: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	w = doRequest(r, http.MethodGet, fmt.Sprintf("/api/v1/tasks/%s/result", taskID), "")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("result after delete: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	// 查询参数错误
+	for _, query := range []string{"status=unknown", "created_before=yesterday", "page=0", "page_size=1000"} {
+		w = doRequest(r, http.MethodGet, "/api/v1/tasks?"+query, "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("list %s: status %d, want %d", query, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	w = doRequest(r, http.MethodDelete, fmt.Sprintf("/api/v1/tasks/%s", otherID), "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("delete pending: status %d, body %s", w.Code, w.Body.String())
+	}
+}
+
+func TestFailedTaskResult(t *testing.T) {
+
+	r, store := newTestServer(t)
+	taskID := createTask(t, r, taskData)
+
+	id, _ := strconv.ParseUint(taskID, 10, 64)
+	if err := store.Tasks().Claim(id); err != nil {
+		t.Fatalf("claim failed. %s", err)
+	}
+	if err := store.Tasks().Fail(id, "genetic execute failed"); err != nil {
+		t.Fatalf("fail failed. %s", err)
+	}
+
+	// 失败的任务, 推送包含错误信息的最终状态后关闭
+	events := getEvents(t, r, taskID)
+	if len(events) != 1 || events[0].Name != "status" || !strings.Contains(events[0].Data, "genetic execute failed") {
+		t.Errorf("failed events: %+v", events)
+	}
+
+	// 失败的任务返回错误信息
+	var resp resultResponse
+	w := getResult(t, r, taskID, "", &resp)
+	if w.Code != http.StatusOK || resp.Status != models.TaskStatusFailed || resp.ErrorMessage != "genetic execute failed" {
+		t.Fatalf("failed result: status %d, body %s", w.Code, w.Body.String())
+	}
+}
+
+// 排课成功但是有学生课程冲突时, 排课结果中提示冲突数量
+func TestStudentClashesResult(t *testing.T) {
+
+	r, store := newTestServer(t)
+	taskID := createTask(t, r, taskData)
+
+	id, _ := strconv.ParseUint(taskID, 10, 64)
+	if err := store.Tasks().Claim(id); err != nil {
+		t.Fatalf("claim failed. %s", err)
+	}
+	report := &models.ScheduleReport{
+		TerminationReason: "max_generations",
+		Report:            `{"num_student_clashes":2,"student_clashes":[{"student_id":1001,"time_slot":4,"class_sns":["7_11_101","8_11_102"]}]}`,
+	}
+	if err := store.Tasks().Complete(id, nil, report); err != nil {
+		t.Fatalf("complete failed. %s", err)
+	}
+
+	w := getResult(t, r, taskID, "", nil)
+	var resp struct {
+		Status            string `json:"status"`
+		NumStudentClashes int    `json:"num_student_clashes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Status != models.TaskStatusSuccess || resp.NumStudentClashes != 2 {
+		t.Fatalf("result: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	w = doRequest(r, http.MethodGet, fmt.Sprintf("/api/v1/tasks/%s/report", taskID), "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"student_id":1001`) {
+		t.Errorf("report: status %d, body %s", w.Code, w.Body.String())
+	}
+}
+
+// 部分重排时, 查询排课结果和质量报告都返回与已发布课表相比的变化汇总
+func TestTimetableDiffResult(t *testing.T) {
+
+	r, store := newTestServer(t)
+	taskID := createTask(t, r, taskData)
+
+	id, _ := strconv.ParseUint(taskID, 10, 64)
+	if err := store.Tasks().Claim(id); err != nil {
+		t.Fatalf("claim failed. %s", err)
+	}
+	report := &models.ScheduleReport{
+		TerminationReason: "max_generations",
+		Report:            `{"num_student_clashes":0,"timetable_diff":{"moved":3,"added":1,"removed":0,"class_moved":{"9_1":3},"teacher_moved":{"8":3}},"timetable_changes":[{"type":"moved","before":{"subject_id":2,"grade_id":9,"class_id":1,"teacher_id":8,"venue_id":901,"weekday":0,"period":0,"locked":false},"after":{"subject_id":2,"grade_id":9,"class_id":1,"teacher_id":8,"venue_id":901,"weekday":1,"period":1,"locked":false}}]}`,
+	}
+	if err := store.Tasks().Complete(id, nil, report); err != nil {
+		t.Fatalf("complete failed. %s", err)
+	}
+
+	w := getResult(t, r, taskID, "", nil)
+	var resp struct {
+		Status        string                                  `json:"status"`
+		TimetableDiff *genetic_algorithm.TimetableDiffSummary `json:"timetable_diff"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.TimetableDiff == nil {
+		t.Fatalf("result: status %d, body %s", w.Code, w.Body.String())
+	}
+	if resp.TimetableDiff.Moved != 3 || resp.TimetableDiff.Added != 1 || resp.TimetableDiff.ClassMoved["9_1"] != 3 {
+		t.Errorf("unexpected timetable diff: %+v", resp.TimetableDiff)
+	}
+
+	// 质量报告中包含每节课的变化
+	w = doRequest(r, http.MethodGet, fmt.Sprintf("/api/v1/tasks/%s/report", taskID), "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"timetable_diff":{"moved":3`) {
+		t.Errorf("report: status %d, body %s", w.Code, w.Body.String())
+	}
+	var reportResp struct {
+		Report struct {
+			TimetableChanges []*genetic_algorithm.TimetableChange `json:"timetable_changes"`
+		} `json:"report"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &reportResp); err != nil || len(reportResp.Report.TimetableChanges) != 1 {
+		t.Fatalf("report changes: body %s, err %v", w.Body.String(), err)
+	}
+	if change := reportResp.Report.TimetableChanges[0]; change.Type != "moved" || change.Before.Weekday != 0 || change.After.Weekday != 1 {
+		t.Errorf("unexpected timetable change: %s", change)
+	}
+
+	// 不是部分重排时不返回
+	taskID = createTask(t, r, taskData)
+	id, _ = strconv.ParseUint(taskID, 10, 64)
+	store.Tasks().Claim(id)
+	store.Tasks().Complete(id, nil, &models.ScheduleReport{Report: `{"num_student_clashes":0}`})
+	w = getResult(t, r, taskID, "", nil)
+	if strings.Contains(w.Body.String(), "timetable_diff") {
+		t.Errorf("result without base timetable: body %s", w.Body.String())
+	}
+}
@@ -0,0 +1,99 @@
+// auth.go
+package middlewares
+
+import (
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/storage"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 请求头中的API密钥, 也可以使用 Authorization: Bearer <密钥>
+const APIKeyHeader = "X-API-Key"
+
+// 上下文中保存租户的键
+const tenantKey = "tenant"
+
+// 生成一个新的API密钥明文, 格式为 cs_<64位十六进制>
+func GenerateAPIKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate api key failed. %s", err)
+	}
+	return "cs_" + hex.EncodeToString(buf), nil
+}
+
+// 计算API密钥的SHA-256, 数据库中只保存这个值
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// API密钥认证
+// 根据请求中的API密钥找到所属的学校(租户), 保存到上下文中, 并按照租户的设置限制请求体的大小
+// 没有密钥, 密钥不存在或者已经吊销时返回 401
+func Auth(store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		key := requestAPIKey(c)
+		if key == "" {
+			c.AbortWithStatusJSON(401, gin.H{"error": "missing api key"})
+			return
+		}
+
+		apiKey, err := store.APIKeys().GetByHash(HashAPIKey(key))
+		if errors.Is(err, storage.ErrNotFound) {
+			c.AbortWithStatusJSON(401, gin.H{"error": "invalid api key"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(500, gin.H{"error": err.Error()})
+			return
+		}
+
+		// 没有租户设置时使用默认的限制
+		tenant, err := store.Tenants().Get(apiKey.SchoolID)
+		if errors.Is(err, storage.ErrNotFound) {
+			tenant = &models.Tenant{SchoolID: apiKey.SchoolID}
+		} else if err != nil {
+			c.AbortWithStatusJSON(500, gin.H{"error": err.Error()})
+			return
+		}
+
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, tenant.PayloadLimit())
+		}
+		c.Set(tenantKey, tenant)
+		c.Next()
+	}
+}
+
+// 获取当前请求的租户, 没有经过认证时返回空
+func GetTenant(c *gin.Context) *models.Tenant {
+	if value, ok := c.Get(tenantKey); ok {
+		if tenant, ok := value.(*models.Tenant); ok {
+			return tenant
+		}
+	}
+	return nil
+}
+
+// 读取请求中的API密钥
+func requestAPIKey(c *gin.Context) string {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		return key
+	}
+
+	auth := c.GetHeader("Authorization")
+	if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
@@ -1,11 +1,11 @@
 package middlewares
 
 import (
+	"course_scheduler/config"
 	"course_scheduler/internal/base"
 	"course_scheduler/internal/genetic_algorithm"
 	"course_scheduler/internal/models"
 	"course_scheduler/internal/types"
-	"course_scheduler/internal/utils"
 	"fmt"
 	"log"
 	"time"
@@ -16,59 +16,113 @@ import (
 // 1. 分离关注点：中间件是处理请求和响应之间的中间逻辑的组件，将排课的逻辑写在中间件中可以将业务逻辑与 HTTP 处理程序分离开来，使得代码更加模块化、易于维护和扩展
 // 2. 重用性：中间件可以在多个处理程序中重用，如果将排课的逻辑写在中间件中，那么可以在不同的处理程序中重用该逻辑，提高代码的重用性
 // 3. 可测试性：将排课的逻辑写在中间件中可以提高代码的可测试性，因为中间件可以独立于 HTTP 处理程序进行测试，这使得测试排课的逻辑更加方便和高效
-func ExecuteTask(taskID uint64, taskData string) ([]*models.ScheduleResult, int, error) {
-	// 创建日志文件
-	logFile := utils.SetUpLogFile()
-	defer logFile.Close()
+// 参数:
+//
+//	taskID 任务id, 排课过程中的日志以任务id开头, 日志文件由程序启动时设置
+//	onGeneration 每一代遗传结束时调用, 参数为这一代的监控数据和排课进度(0-99), 可以为空, 排课完成后由调用方设置进度为100
+//	             返回错误时停止排课, 并返回这个错误
+//
+// 返回值:
+//
+//	返回 排课结果、排课质量报告(包括与已发布课表相比的变化汇总)、错误信息
+func ExecuteTask(taskID uint64, taskData string, onGeneration func(generation *models.TaskGeneration) error) ([]*models.ScheduleResult, *genetic_algorithm.ScheduleReport, error) {
+	// 同时执行多个任务时, 区分每个任务的日志
+	logger := log.New(log.Writer(), fmt.Sprintf("task %d: ", taskID), log.Flags()|log.Lmsgprefix)
 
 	// 开始时间
 	startTime := time.Now()
 
 	// 监控器
 	monitor := base.NewMonitor()
+	if onGeneration != nil {
+		monitor.OnGeneration = func(gen int) error {
+			// 刚完成的一代在监控数据中的下标为 gen-1
+			elapsed := time.Since(startTime)
+			return onGeneration(&models.TaskGeneration{
+				TaskID:                taskID,
+				Generation:            gen,
+				BestFitness:           monitor.BestFitnessPerGen[gen-1],
+				AvgFitness:            monitor.AvgFitnessPerGen[gen-1],
+				WorstFitness:          monitor.WorstFitnessPerGen[gen-1],
+				GenWithoutImprovement: monitor.GenWithoutImprovementPerGen[gen-1],
+				// 按照最大遗传代数估算进度, 提前结束时直接完成
+				Progress:  int8(min(gen*100/config.MaxGen, 99)),
+				ElapsedMs: elapsed.Milliseconds(),
+				EtaMs:     estimateRemaining(gen, elapsed).Milliseconds(),
+			})
+		}
+	}
 
 	// 加载测试数据
 	scheduleInput, err := base.ParseScheduleInputFromJSON(taskData)
 	if err != nil {
-		return nil, 0, fmt.Errorf("load test data failed. %s", err)
+		return nil, nil, fmt.Errorf("load test data failed. %s", err)
 	}
 
 	// 检查输入数据
 	err = scheduleInput.Check()
 	if err != nil {
-		return nil, 0, fmt.Errorf("check teach task allocation failed. %s", err)
+		return nil, nil, fmt.Errorf("check teach task allocation failed. %s", err)
 	}
 
 	// 遗传算法排课
 	bestIndividual, bestGen, err := genetic_algorithm.Execute(scheduleInput, monitor, startTime)
 	if err != nil {
-		return nil, 0, fmt.Errorf("genetic execute failed. %s", err)
+		return nil, nil, fmt.Errorf("genetic execute failed. %w", err)
 	}
 
 	// 结束时间
 	monitor.TotalTime = time.Since(startTime)
 
 	// 输出最终排课结果
-	log.Println("🍻 Best solution done!")
+	logger.Println("🍻 Best solution done!")
 
 	// 打印最好的个体
-	log.Printf("bestGen: %d, bestIndividual.Fitness: %d, uniqueId: %s\n", bestGen, bestIndividual.Fitness, bestIndividual.UniqueId)
+	logger.Printf("bestGen: %d, bestIndividual.Fitness: %d, uniqueId: %s\n", bestGen, bestIndividual.Fitness, bestIndividual.UniqueId)
 	bestIndividual.PrintSchedule(scheduleInput.Schedule, scheduleInput.Subjects)
 
 	// 打印个体的约束状态信息
-	log.Println("打印个体的约束状态信息")
+	logger.Println("打印个体的约束状态信息")
 	bestIndividual.PrintConstraints()
 
 	// 打印监控数据
 	// monitor.Dump()
 
+	// 排课质量报告
+	report, err := bestIndividual.Report(scheduleInput.Schedule, scheduleInput.StudentEnrollments, scheduleInput.GetMaxStudentDailyLessons(), scheduleInput.SubjectPeriodConsistencyConstraints, monitor)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate schedule report failed. %s", err)
+	}
+
 	// 将 bestIndividual 转换为 []*models.ScheduleResult
 	scheduleResults, err := convertIndividualToScheduleResults(taskID, bestIndividual, scheduleInput)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, err
+	}
+
+	// 与已发布课表相比的变化和变化汇总, 和排课质量报告一起保存
+	if len(scheduleInput.BaseTimetable) > 0 {
+		changes, err := bestIndividual.DiffTimetable(scheduleInput.Schedule, scheduleInput.BaseTimetable)
+		if err != nil {
+			return nil, nil, err
+		}
+		report.TimetableDiff = genetic_algorithm.SummarizeTimetableChanges(changes)
+		report.TimetableChanges = changes
 	}
 
-	return scheduleResults, bestGen, nil
+	return scheduleResults, report, nil
+}
+
+// 估算排课的剩余时间
+// 按照已完成代的平均耗时和剩余的最大代数估算, 不超过最长运行时间, 找到满意的解或者停滞时会提前结束
+func estimateRemaining(gen int, elapsed time.Duration) time.Duration {
+
+	if gen <= 0 {
+		return config.MaxDuration - elapsed
+	}
+
+	remaining := elapsed / time.Duration(gen) * time.Duration(max(config.MaxGen-gen, 0))
+	return max(min(remaining, config.MaxDuration-elapsed), 0)
 }
 
 // 将遗传个体类型转换为排课结果类型
@@ -86,28 +140,31 @@ func convertIndividualToScheduleResults(taskID uint64, individual *genetic_algor
 				return nil, err
 			}
 
-			totalClassesPerDay := input.Schedule.GetTotalClassesPerDay()
-
 			for _, timeSlot := range gene.TimeSlots {
 
-				weekday := int8(timeSlot / totalClassesPerDay)
-				period := int8(timeSlot % totalClassesPerDay)
-
-				result := &models.ScheduleResult{
-					TaskID:    taskID,
-					SubjectID: uint64(SN.SubjectID),
-					TeacherID: uint64(gene.TeacherID),
-					GradeID:   uint64(SN.GradeID),
-					ClassID:   uint64(SN.ClassID),
-					VenueID:   uint64(gene.VenueID),
-					Weekday:   weekday,
-					Period:    period,
-					// TODO: 待完成
-					// StartTime: item.StartTime,
-					// EndTime:   item.EndTime,
+				ts := input.Schedule.GetTimeSlot(timeSlot)
+				weekday := int8(ts.Day)
+				period := int8(ts.Period)
+
+				// 根据作息时间表获取上下课时间
+				startTime, endTime := input.Schedule.GetPeriodTime(int(weekday), int(period))
+
+				// 协同上课的教师, 每个教师一条排课结果
+				for _, teacherID := range gene.GetTeacherIDs() {
+					result := &models.ScheduleResult{
+						TaskID:    taskID,
+						SubjectID: uint64(SN.SubjectID),
+						TeacherID: uint64(teacherID),
+						GradeID:   uint64(SN.GradeID),
+						ClassID:   uint64(SN.ClassID),
+						VenueID:   uint64(gene.VenueID),
+						Weekday:   weekday,
+						Period:    period,
+						StartTime: startTime,
+						EndTime:   endTime,
+					}
+					scheduleResults = append(scheduleResults, result)
 				}
-				scheduleResults = append(scheduleResults, result)
-
 			}
 		}
 	}
@@ -2,23 +2,43 @@ package routes
 
 import (
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 
 	"course_scheduler/internal/api/v1/handlers"
+	"course_scheduler/internal/api/v1/middlewares"
+	"course_scheduler/internal/storage"
 )
 
 // SetupRoutes 设置路由
-func SetupRoutes(r *gin.Engine, db *gorm.DB) {
+func SetupRoutes(r *gin.Engine, store storage.Storage) {
 
 	// 创建一个新的路由组
-	v1 := r.Group("/api/v1")
+	// 所有接口都需要API密钥, 排课任务按照学校(租户)隔离
+	v1 := r.Group("/api/v1", middlewares.Auth(store))
 
 	// 注册接收数据路由
-	v1.POST("/tasks", handlers.CreateTaskHandler(db))
+	v1.POST("/tasks", handlers.CreateTaskHandler(store))
 
-	// 注册执行排课路由
-	v1.GET("/tasks/:task_id/execute", handlers.ExecuteTaskHandler(db))
+	// 注册检查排课数据路由
+	v1.POST("/tasks/validate", handlers.ValidateTaskHandler(store))
 
 	// 注册查询排课结果路由
-	v1.GET("/tasks/:task_id/result", handlers.GetTaskResultHandler(db))
+	v1.GET("/tasks/:task_id/result", handlers.GetTaskResultHandler(store))
+
+	// 注册查询排课质量报告路由
+	v1.GET("/tasks/:task_id/report", handlers.GetTaskReportHandler(store))
+
+	// 注册推送排课进度路由(Server-Sent Events)
+	v1.GET("/tasks/:task_id/events", handlers.GetTaskEventsHandler(store))
+
+	// 注册查询排课任务列表路由
+	v1.GET("/tasks", handlers.ListTasksHandler(store))
+
+	// 注册取消排课任务路由
+	v1.POST("/tasks/:task_id/cancel", handlers.CancelTaskHandler(store))
+
+	// 注册重新执行排课任务路由
+	v1.POST("/tasks/:task_id/retry", handlers.RetryTaskHandler(store))
+
+	// 注册删除排课任务路由
+	v1.DELETE("/tasks/:task_id", handlers.DeleteTaskHandler(store))
 }
@@ -18,6 +18,9 @@ type Monitor struct {
 	// 记录每一代最坏适应度值
 	WorstFitnessPerGen map[int]int
 
+	// 记录每一代结束时连续没有改进的代数
+	GenWithoutImprovementPerGen map[int]int
+
 	// 准备执行交叉操作次数
 	NumPreparedCrossover map[int]int
 	// 实际执行交叉操作次数
@@ -30,18 +33,33 @@ type Monitor struct {
 
 	// 总计算时间
 	TotalTime time.Duration
+
+	// 执行的代数
+	NumGenerations int
+
+	// 最佳个体所在的代数
+	BestGen int
+
+	// 终止原因 如: max_generations, stagnation
+	TerminationReason string
+
+	// 每一代结束时调用, 参数为已完成的代数, 可以为空
+	// 这一代的监控数据在各个map中的下标为 gen-1
+	// 用于更新排课任务的进度, 返回错误时停止排课(如: 任务已经取消)
+	OnGeneration func(gen int) error
 }
 
 // 构造函数
 func NewMonitor() *Monitor {
 	return &Monitor{
-		BestFitnessPerGen:    make(map[int]int),
-		AvgFitnessPerGen:     make(map[int]float64),
-		WorstFitnessPerGen:   make(map[int]int),
-		NumPreparedCrossover: make(map[int]int),
-		NumExecutedCrossover: make(map[int]int),
-		NumPreparedMutation:  make(map[int]int),
-		NumExecutedMutation:  make(map[int]int),
+		BestFitnessPerGen:           make(map[int]int),
+		AvgFitnessPerGen:            make(map[int]float64),
+		WorstFitnessPerGen:          make(map[int]int),
+		GenWithoutImprovementPerGen: make(map[int]int),
+		NumPreparedCrossover:        make(map[int]int),
+		NumExecutedCrossover:        make(map[int]int),
+		NumPreparedMutation:         make(map[int]int),
+		NumExecutedMutation:         make(map[int]int),
 	}
 }
 
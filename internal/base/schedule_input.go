@@ -2,81 +2,225 @@
 package base
 
 import (
+	"bytes"
+	"course_scheduler/config"
 	"course_scheduler/internal/constraints"
 	"course_scheduler/internal/models"
+	"course_scheduler/internal/utils"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"sort"
 
+	"github.com/samber/lo"
 	"github.com/spf13/viper"
 )
 
 // 排课输入信息
 type ScheduleInput struct {
-	Schedule      *models.Schedule       `json:"schedule" mapstructure:"schedule"`             // 排课方案
-	TeachingTasks []*models.TeachingTask `json:"teaching_tasks" mapstructure:"teaching_tasks"` // 教学任务
-	Teachers      []*models.Teacher      `json:"teachers" mapstructure:"teachers"`             // 教师信息
-	Subjects      []*models.Subject      `json:"subjects" mapstructure:"subjects"`             // 科目信息
-	// Venues                        []*models.Venue                  `json:"venues" mapstructure:"venues"`                                                             // 教学场地
-	SubjectVenueMap                map[string][]int                   `json:"subject_venue_map" mapstructure:"subject_venue_map"`                                 // 教学场地 key: sn(科目id_年级id_班级id) value: 教室id
-	Grades                         []*models.Grade                    `json:"grades"`                                                                             // 年级信息
-	ClassConstraints               []*constraints.Class               `json:"class_constraints" mapstructure:"class_constraints"`                                 // 班级固排禁排约束条件
-	SubjectMutexConstraints        []*constraints.SubjectMutex        `json:"subject_mutex_constraints" mapstructure:"subject_mutex_constraints"`                 // 科目互斥限制约束条件
-	SubjectOrderConstraints        []*constraints.SubjectOrder        `json:"subject_order_constraints" mapstructure:"subject_order_constraints"`                 // 科目顺序限制约束条件
-	SubjectDayLimitConstraints     []*constraints.SubjectDayLimit     `json:"subject_day_limit_constraints" mapstructure:"subject_day_limit_constraints"`         // 科目顺序限制约束条件
-	SubjectConstraints             []*constraints.Subject             `json:"subject_constraints" mapstructure:"subject_constraints"`                             // 科目优先排禁排约束条件
-	SubjectConnectedDayConstraints []*constraints.SubjectConnectedDay `json:"subject_connected_day_constraints" mapstructure:"subject_connected_day_constraints"` // 连堂课各天约束条件
-	TeacherMutexConstraints        []*constraints.TeacherMutex        `json:"teacher_mutex_constraints" mapstructure:"teacher_mutex_constraints"`                 // 教师互斥限制约束条件
-	TeacherNoonBreakConstraints    []*constraints.TeacherNoonBreak    `json:"teacher_noon_break_constraints" mapstructure:"teacher_noon_break_constraints"`       // 教师不跨中午约束条件
-	TeacherPeriodLimitConstraints  []*constraints.TeacherPeriodLimit  `json:"teacher_period_limit_constraints" mapstructure:"teacher_period_limit_constraints"`   // 教师节数限制条件
-	TeacherRangeLimitConstraints   []*constraints.TeacherRangeLimit   `json:"teacher_range_limit_constraints" mapstructure:"teacher_range_limit_constraints"`     // 教师时间段限制条件
-	TeacherConstraints             []*constraints.Teacher             `json:"teacher_constraints" mapstructure:"teacher_constraints"`                             // 教师固排禁排约束条件
+	Schedule                            *models.Schedule                        `json:"schedule" mapstructure:"schedule"`                                                             // 排课方案
+	TeachingTasks                       []*models.TeachingTask                  `json:"teaching_tasks" mapstructure:"teaching_tasks"`                                                 // 教学任务
+	Teachers                            []*models.Teacher                       `json:"teachers" mapstructure:"teachers"`                                                             // 教师信息
+	TeacherGroups                       []*models.TeacherGroup                  `json:"teacher_groups" mapstructure:"teacher_groups"`                                                 // 教师分组信息
+	Subjects                            []*models.Subject                       `json:"subjects" mapstructure:"subjects"`                                                             // 科目信息
+	TeachingGroups                      []*models.TeachingGroup                 `json:"teaching_groups" mapstructure:"teaching_groups"`                                               // 教学班(走班)信息
+	ElectiveBlocks                      []*models.ElectiveBlock                 `json:"elective_blocks" mapstructure:"elective_blocks"`                                               // 走班时段信息
+	StudentEnrollments                  []*models.StudentEnrollment             `json:"student_enrollments" mapstructure:"student_enrollments"`                                       // 学生选课信息, 可以为空
+	MaxStudentDailyLessons              int                                     `json:"max_student_daily_lessons" mapstructure:"max_student_daily_lessons"`                           // 学生每天最多课时数, 超过时在排课质量报告中列出, 为空时使用默认值
+	Venues                              []*models.Venue                         `json:"venues" mapstructure:"venues"`                                                                 // 教学场地, 可以为空
+	TravelTimes                         []*models.TravelTime                    `json:"travel_times" mapstructure:"travel_times"`                                                     // 教学楼之间的通行时间, 可以为空
+	SubjectVenueMap                     map[string][]int                        `json:"subject_venue_map" mapstructure:"subject_venue_map"`                                           // 教学场地 key: sn(科目id_年级id_班级id) value: 教室id
+	BaseTimetable                       []*models.TimetableEntry                `json:"base_timetable" mapstructure:"base_timetable"`                                                 // 已发布的课表, 部分重排时使用, 可以为空
+	RescheduleScope                     *models.RescheduleScope                 `json:"reschedule_scope" mapstructure:"reschedule_scope"`                                             // 部分重排的范围, 范围外的课全部锁定, 可以为空
+	DisruptionWeight                    int                                     `json:"disruption_weight" mapstructure:"disruption_weight"`                                           // 与已发布课表不同的基因的处罚分, 为空时使用默认值
+	Grades                              []*models.Grade                         `json:"grades"`                                                                                       // 年级信息
+	ClassConstraints                    []*constraints.Class                    `json:"class_constraints" mapstructure:"class_constraints"`                                           // 班级固排禁排约束条件
+	SubjectMutexConstraints             []*constraints.SubjectMutex             `json:"subject_mutex_constraints" mapstructure:"subject_mutex_constraints"`                           // 科目互斥限制约束条件
+	SubjectOrderConstraints             []*constraints.SubjectOrder             `json:"subject_order_constraints" mapstructure:"subject_order_constraints"`                           // 科目顺序限制约束条件
+	SubjectDayLimitConstraints          []*constraints.SubjectDayLimit          `json:"subject_day_limit_constraints" mapstructure:"subject_day_limit_constraints"`                   // 科目顺序限制约束条件
+	SubjectConstraints                  []*constraints.Subject                  `json:"subject_constraints" mapstructure:"subject_constraints"`                                       // 科目优先排禁排约束条件
+	SubjectConnectedDayConstraints      []*constraints.SubjectConnectedDay      `json:"subject_connected_day_constraints" mapstructure:"subject_connected_day_constraints"`           // 连堂课各天约束条件
+	TeacherMutexConstraints             []*constraints.TeacherMutex             `json:"teacher_mutex_constraints" mapstructure:"teacher_mutex_constraints"`                           // 教师互斥限制约束条件
+	TeacherNoonBreakConstraints         []*constraints.TeacherNoonBreak         `json:"teacher_noon_break_constraints" mapstructure:"teacher_noon_break_constraints"`                 // 教师不跨中午约束条件
+	TeacherPeriodLimitConstraints       []*constraints.TeacherPeriodLimit       `json:"teacher_period_limit_constraints" mapstructure:"teacher_period_limit_constraints"`             // 教师节数限制条件
+	TeacherRangeLimitConstraints        []*constraints.TeacherRangeLimit        `json:"teacher_range_limit_constraints" mapstructure:"teacher_range_limit_constraints"`               // 教师时间段限制条件
+	TeacherWorkloadConstraints          []*constraints.TeacherWorkload          `json:"teacher_workload_constraints" mapstructure:"teacher_workload_constraints"`                     // 教师工作量限制条件
+	TeacherConstraints                  []*constraints.Teacher                  `json:"teacher_constraints" mapstructure:"teacher_constraints"`                                       // 教师固排禁排约束条件
+	SegmentEligibilityConstraints       []*constraints.SegmentEligibility       `json:"segment_eligibility_constraints" mapstructure:"segment_eligibility_constraints"`               // 时间区间排课限制约束条件
+	SubjectAdjacencyConstraints         []*constraints.SubjectAdjacency         `json:"subject_adjacency_constraints" mapstructure:"subject_adjacency_constraints"`                   // 科目相邻限制约束条件
+	SubjectSpreadConstraints            []*constraints.SubjectSpread            `json:"subject_spread_constraints" mapstructure:"subject_spread_constraints"`                         // 科目分布限制约束条件
+	SubjectPeriodConsistencyConstraints []*constraints.SubjectPeriodConsistency `json:"subject_period_consistency_constraints" mapstructure:"subject_period_consistency_constraints"` // 科目固定节次约束条件
+	TeacherTravelConstraints            []*constraints.TeacherTravel            `json:"teacher_travel_constraints" mapstructure:"teacher_travel_constraints"`                         // 教师跨教学楼上课限制条件
 }
 
 // 输入检查
-// 检查教学计划是否正确
+// 检查教学计划是否正确, 返回所有的检查错误(ValidationErrors), 错误中包含字段的路径
 func (s *ScheduleInput) Check() error {
+	if errs := s.check(); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
 
-	err := s.Schedule.Check()
-	if err != nil {
-		return err
+// 检查教学计划, 返回所有的检查错误
+// 课表设置不正确时无法继续检查, 直接返回
+func (s *ScheduleInput) check() ValidationErrors {
+
+	var errs ValidationErrors
+	addErr := func(field, format string, args ...interface{}) {
+		errs = append(errs, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Schedule == nil {
+		addErr("schedule", "is required")
+		return errs
+	}
+
+	if err := s.Schedule.Check(); err != nil {
+		addErr("schedule", "%s", err)
+		return errs
 	}
 
 	if len(s.TeachingTasks) == 0 {
-		return errors.New("teaching tasks cannot be empty")
+		addErr("teaching_tasks", "cannot be empty")
 	}
 
 	if len(s.Teachers) == 0 {
-		return errors.New("teachers cannot be empty")
+		addErr("teachers", "cannot be empty")
 	}
 
 	if len(s.Subjects) == 0 {
-		return errors.New("subjects cannot be empty")
+		addErr("subjects", "cannot be empty")
 	}
 
 	if len(s.Grades) == 0 {
-		return errors.New("grades cannot be empty")
+		addErr("grades", "cannot be empty")
+	}
+
+	// 检查约束条件中的教师分组是否存在
+	errs = append(errs, s.checkTeacherGroups()...)
+
+	// 检查教学班和走班时段
+	errs = append(errs, s.checkTeachingGroups()...)
+
+	// 检查学生选课信息
+	errs = append(errs, s.checkStudentEnrollments()...)
+
+	// 检查已发布的课表
+	errs = append(errs, s.checkBaseTimetable()...)
+
+	// 检查时间区间排课限制中的时间区间
+	for i, c := range s.SegmentEligibilityConstraints {
+		if !lo.Contains(models.Segments, c.Segment) {
+			addErr(fmt.Sprintf("segment_eligibility_constraints[%d].segment", i), "invalid segment %q, must be one of %v", c.Segment, models.Segments)
+		}
+	}
+
+	// 检查科目相邻限制
+	for i, c := range s.SubjectAdjacencyConstraints {
+		field := fmt.Sprintf("subject_adjacency_constraints[%d]", i)
+
+		if !lo.Contains(constraints.SubjectAdjacencyModes, c.Mode) {
+			addErr(field+".mode", "invalid mode %q, must be one of %v", c.Mode, constraints.SubjectAdjacencyModes)
+		}
+
+		if c.SubjectAID == 0 && c.SubjectAGroupID == 0 {
+			addErr(field+".subject_a_id", "subject a cannot be empty")
+		}
+
+		if c.Mode != "max_consecutive" && c.SubjectBID == 0 && c.SubjectBGroupID == 0 {
+			addErr(field+".subject_b_id", "subject b cannot be empty")
+		}
+	}
+
+	// 检查科目分布限制
+	for i, c := range s.SubjectSpreadConstraints {
+		field := fmt.Sprintf("subject_spread_constraints[%d]", i)
+
+		if _, err := models.FindSubjectByID(c.SubjectID, s.Subjects); err != nil {
+			addErr(field+".subject_id", "subject %d not found", c.SubjectID)
+		}
+
+		for j, pattern := range c.ForbiddenDayPatterns {
+			for k, weekday := range pattern {
+				if weekday < 1 || weekday > s.Schedule.NumWorkdays {
+					addErr(fmt.Sprintf("%s.forbidden_day_patterns[%d][%d]", field, j, k), "invalid weekday %d, must be in range [1, %d]", weekday, s.Schedule.NumWorkdays)
+				}
+			}
+		}
+	}
+
+	// 检查科目固定节次
+	for i, c := range s.SubjectPeriodConsistencyConstraints {
+		field := fmt.Sprintf("subject_period_consistency_constraints[%d]", i)
+
+		if _, err := models.FindSubjectByID(c.SubjectID, s.Subjects); err != nil {
+			addErr(field+".subject_id", "subject %d not found", c.SubjectID)
+		}
+
+		if c.MaxDistinctPeriods < 0 {
+			addErr(field+".max_distinct_periods", "cannot be negative")
+		}
+	}
+
+	// 检查教学楼之间的通行时间和教师跨教学楼上课限制
+	for i, t := range s.TravelTimes {
+		if t.Minutes < 0 {
+			addErr(fmt.Sprintf("travel_times[%d].minutes", i), "invalid travel time %d minutes between %s and %s", t.Minutes, t.FromBuilding, t.ToBuilding)
+		}
+	}
+
+	for i, c := range s.TeacherTravelConstraints {
+		if c.Limit != "not" && c.Limit != "avoid" {
+			addErr(fmt.Sprintf("teacher_travel_constraints[%d].limit", i), "invalid limit %q, must be not or avoid", c.Limit)
+		}
 	}
 
 	// 1. 检查每周总课时数是否超过总课时数
-	totalClassesPerWeek := s.Schedule.GetTotalClassesPerDay() * s.Schedule.NumWorkdays
+	// 不存在的时间段不计算在内
+	totalClassesPerWeek := s.Schedule.TotalClassesPerWeek()
 
 	// 按照年级和班级统计课程数量
 	classCount := make(map[string]int)
 
 	// 按照年级、班级和科目统计上课次数
 	subjectCount := make(map[string]int)
-	for _, task := range s.TeachingTasks {
+	for i, task := range s.TeachingTasks {
+		field := fmt.Sprintf("teaching_tasks[%d]", i)
 		classKey := fmt.Sprintf("%d_%d", task.GradeID, task.ClassID)
 		classSubjectKey := fmt.Sprintf("%d_%d_%d", task.GradeID, task.ClassID, task.SubjectID)
-		classCount[classKey] += task.NumClassesPerWeek - task.NumConnectedClassesPerWeek
-		subjectCount[classSubjectKey] += task.NumClassesPerWeek - task.NumConnectedClassesPerWeek
+
+		// 协同上课的教师需要存在
+		for j, coTeacherID := range task.CoTeacherIDs {
+			if _, err := models.FindTeacherByID(coTeacherID, s.Teachers); err != nil {
+				addErr(fmt.Sprintf("%s.co_teacher_ids[%d]", field, j), "teacher %d not found", coTeacherID)
+			}
+		}
+
+		// 连堂课的节数不能超过周课时, 且需要有足够长的连续时间段
+		if task.NumConnectedClassesPerWeek > 0 {
+			connectedLength := task.GetConnectedLength()
+			if task.NumConnectedClassesPerWeek*connectedLength > task.NumClassesPerWeek {
+				addErr(field+".num_connected_classes_per_week", "connected classes %d * %d exceed weekly classes %d", task.NumConnectedClassesPerWeek, connectedLength, task.NumClassesPerWeek)
+			}
+
+			if len(utils.GetAllConnectedTimeSlotsWithLength(s.Schedule, connectedLength)) == 0 {
+				addErr(field+".connected_length", "no available time slots for %d connected classes", connectedLength)
+			}
+		}
+
+		// 按照节数统计, 一次连堂课占用connected_length节
+		classCount[classKey] += task.NumClassesPerWeek
+		subjectCount[classSubjectKey] += task.NumClassesPerWeek - task.NumConnectedClassesPerWeek*(task.GetConnectedLength()-1)
 	}
 
-	for key, count := range classCount {
-		if count > totalClassesPerWeek {
-			return fmt.Errorf("%s total course Classes %d exceed maximum weekly Classes %d", key, count, totalClassesPerWeek)
+	// 按照班级排序, 错误的顺序固定
+	classKeys := lo.Keys(classCount)
+	sort.Strings(classKeys)
+	for _, key := range classKeys {
+		if count := classCount[key]; count > totalClassesPerWeek {
+			addErr("teaching_tasks", "%s total course Classes %d exceed maximum weekly Classes %d", key, count, totalClassesPerWeek)
 		}
 	}
 
@@ -88,24 +232,257 @@ func (s *ScheduleInput) Check() error {
 	// 		return false, fmt.Errorf("subject %s has invalid weekly Classes count", key)
 	// 	}
 	// }
-	return nil
+	return errs
+}
+
+// 检查约束条件中引用的教师分组
+func (s *ScheduleInput) checkTeacherGroups() ValidationErrors {
+
+	var errs ValidationErrors
+	checkGroup := func(field string, teacherGroupID int) {
+		if teacherGroupID == 0 {
+			return
+		}
+
+		// 分组没有在教师分组信息中定义时, 只要有教师属于该分组也是可以的
+		_, err := models.FindTeacherGroupByID(teacherGroupID, s.TeacherGroups)
+		if err != nil && len(models.GroupTeacherIDs(teacherGroupID, s.Teachers)) == 0 {
+			errs = append(errs, &FieldError{Field: field, Message: fmt.Sprintf("teacher group %d not found", teacherGroupID)})
+		}
+	}
+
+	for i, c := range s.TeacherConstraints {
+		checkGroup(fmt.Sprintf("teacher_constraints[%d].teacher_group_id", i), c.TeacherGroupID)
+	}
+
+	for i, c := range s.TeacherMutexConstraints {
+		checkGroup(fmt.Sprintf("teacher_mutex_constraints[%d].teacher_a_group_id", i), c.TeacherAGroupID)
+		checkGroup(fmt.Sprintf("teacher_mutex_constraints[%d].teacher_b_group_id", i), c.TeacherBGroupID)
+	}
+
+	for i, c := range s.TeacherRangeLimitConstraints {
+		checkGroup(fmt.Sprintf("teacher_range_limit_constraints[%d].teacher_group_id", i), c.TeacherGroupID)
+	}
+
+	for i, c := range s.TeacherWorkloadConstraints {
+		checkGroup(fmt.Sprintf("teacher_workload_constraints[%d].teacher_group_id", i), c.TeacherGroupID)
+	}
+
+	for i, c := range s.SegmentEligibilityConstraints {
+		checkGroup(fmt.Sprintf("segment_eligibility_constraints[%d].teacher_group_id", i), c.TeacherGroupID)
+	}
+
+	for i, c := range s.TeacherTravelConstraints {
+		checkGroup(fmt.Sprintf("teacher_travel_constraints[%d].teacher_group_id", i), c.TeacherGroupID)
+	}
+	return errs
+}
+
+// 检查教学班和走班时段
+func (s *ScheduleInput) checkTeachingGroups() ValidationErrors {
+
+	var errs ValidationErrors
+	addErr := func(field, format string, args ...interface{}) {
+		errs = append(errs, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for i, group := range s.TeachingGroups {
+		field := fmt.Sprintf("teaching_groups[%d]", i)
+
+		grade, err := models.FindGradeByID(group.GradeID, s.Grades)
+		if err != nil {
+			addErr(field+".grade_id", "grade %d not found", group.GradeID)
+			continue
+		}
+
+		// 教学班id作为班级id使用, 不能和行政班id重复
+		classIDs := lo.Map(grade.Classes, func(class models.Class, _ int) int {
+			return class.ClassID
+		})
+		if lo.Contains(classIDs, group.TeachingGroupID) {
+			addErr(field+".teaching_group_id", "teaching group %d conflicts with class id in grade %d", group.TeachingGroupID, group.GradeID)
+		}
+
+		if len(group.ClassIDs) == 0 {
+			addErr(field+".class_ids", "cannot be empty")
+		}
+
+		for j, classID := range group.ClassIDs {
+			if !lo.Contains(classIDs, classID) {
+				addErr(fmt.Sprintf("%s.class_ids[%d]", field, j), "class %d not found in grade %d", classID, group.GradeID)
+			}
+		}
+
+		if group.ElectiveBlockID > 0 {
+			block, err := models.FindElectiveBlockByID(group.ElectiveBlockID, s.ElectiveBlocks)
+			if err != nil || block.GradeID != group.GradeID {
+				addErr(field+".elective_block_id", "elective block %d not found in grade %d", group.ElectiveBlockID, group.GradeID)
+			}
+		}
+	}
+
+	// 走班时段的时间段需要是课表中存在的时间段
+	weekTimeSlots := s.Schedule.GenWeekTimeSlots()
+	for i, block := range s.ElectiveBlocks {
+		field := fmt.Sprintf("elective_blocks[%d].time_slots", i)
+		if len(block.TimeSlots) == 0 {
+			addErr(field, "cannot be empty")
+		}
+
+		for j, timeSlot := range block.TimeSlots {
+			if !lo.Contains(weekTimeSlots, timeSlot) {
+				addErr(fmt.Sprintf("%s[%d]", field, j), "time slot %d not available", timeSlot)
+			}
+		}
+	}
+	return errs
+}
+
+// 检查学生选课信息中的行政班和教学班
+func (s *ScheduleInput) checkStudentEnrollments() ValidationErrors {
+
+	var errs ValidationErrors
+	addErr := func(field, format string, args ...interface{}) {
+		errs = append(errs, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.MaxStudentDailyLessons < 0 {
+		addErr("max_student_daily_lessons", "cannot be negative")
+	}
+
+	for i, enrollment := range s.StudentEnrollments {
+		field := fmt.Sprintf("student_enrollments[%d]", i)
+
+		grade, err := models.FindGradeByID(enrollment.GradeID, s.Grades)
+		if err != nil {
+			addErr(field+".grade_id", "grade %d not found", enrollment.GradeID)
+			continue
+		}
+
+		if enrollment.ClassID > 0 && !lo.ContainsBy(grade.Classes, func(class models.Class) bool { return class.ClassID == enrollment.ClassID }) {
+			addErr(field+".class_id", "class %d not found in grade %d", enrollment.ClassID, enrollment.GradeID)
+		}
+
+		for j, teachingGroupID := range enrollment.TeachingGroupIDs {
+			if _, err := models.FindTeachingGroupByID(enrollment.GradeID, teachingGroupID, s.TeachingGroups); err != nil {
+				addErr(fmt.Sprintf("%s.teaching_group_ids[%d]", field, j), "teaching group %d not found in grade %d", teachingGroupID, enrollment.GradeID)
+			}
+		}
+	}
+	return errs
+}
+
+// 检查已发布课表中的科目和时间段
+func (s *ScheduleInput) checkBaseTimetable() ValidationErrors {
+
+	var errs ValidationErrors
+	addErr := func(field, format string, args ...interface{}) {
+		errs = append(errs, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.DisruptionWeight < 0 {
+		addErr("disruption_weight", "cannot be negative")
+	}
+
+	for i, entry := range s.BaseTimetable {
+		field := fmt.Sprintf("base_timetable[%d]", i)
+
+		if _, err := models.FindSubjectByID(entry.SubjectID, s.Subjects); err != nil {
+			addErr(field+".subject_id", "subject %d not found", entry.SubjectID)
+		}
+
+		timeSlot := s.Schedule.GetTimeSlotIndex(entry.Weekday, entry.Period)
+		if entry.Period >= s.Schedule.GetTotalClassesPerDay() || !s.Schedule.IsTimeSlotAvailable(timeSlot) {
+			addErr(field+".period", "time slot not available. weekday: %d, period: %d", entry.Weekday, entry.Period)
+		}
+	}
+	return errs
+}
+
+// 获取与已发布课表不同的基因的处罚分
+func (s *ScheduleInput) GetDisruptionWeight() int {
+	if s.DisruptionWeight > 0 {
+		return s.DisruptionWeight
+	}
+	return config.DisruptionWeight
+}
+
+// 获取学生每天最多课时数
+func (s *ScheduleInput) GetMaxStudentDailyLessons() int {
+	if s.MaxStudentDailyLessons > 0 {
+		return s.MaxStudentDailyLessons
+	}
+	return config.MaxStudentDailyLessons
+}
+
+// 走班时段生成的班级禁排约束条件
+// 学生来源的行政班, 在走班时段不能排其他课
+// 走班时段内的教学班, 只能排在走班时段
+func (s *ScheduleInput) electiveBlockClassConstraints() []*constraints.Class {
+
+	var classConstraints []*constraints.Class
+	for _, block := range s.ElectiveBlocks {
+
+		desc := fmt.Sprintf("走班时段: %s", block.Name)
+		for _, classID := range block.ClassIDs(s.TeachingGroups) {
+			classConstraints = append(classConstraints, &constraints.Class{
+				GradeID:   block.GradeID,
+				ClassID:   classID,
+				TimeSlots: block.TimeSlots,
+				Limit:     "not",
+				Desc:      desc,
+			})
+		}
+
+		otherTimeSlots, _ := lo.Difference(s.Schedule.GenWeekTimeSlots(), block.TimeSlots)
+		for _, group := range s.TeachingGroups {
+			if group.GradeID == block.GradeID && group.ElectiveBlockID == block.ElectiveBlockID {
+				classConstraints = append(classConstraints, &constraints.Class{
+					GradeID:   block.GradeID,
+					ClassID:   group.TeachingGroupID,
+					TimeSlots: otherTimeSlots,
+					Limit:     "not",
+					Desc:      desc,
+				})
+			}
+		}
+	}
+	return classConstraints
 }
 
 // 当前的约束条件
 func (s *ScheduleInput) Constraints() map[string]interface{} {
 
+	// 班级固排禁排, 包括走班时段生成的约束条件
+	classConstraints := append([]*constraints.Class{}, s.ClassConstraints...)
+	classConstraints = append(classConstraints, s.electiveBlockClassConstraints()...)
+
 	constraints := make(map[string]interface{})
-	constraints["Class"] = s.ClassConstraints
+	constraints["Class"] = classConstraints
 	constraints["Subject"] = s.SubjectConstraints
 	constraints["Teacher"] = s.TeacherConstraints
 	constraints["SubjectMutex"] = s.SubjectMutexConstraints
 	constraints["SubjectOrder"] = s.SubjectOrderConstraints
 	constraints["SubjectDayLimit"] = s.SubjectDayLimitConstraints
+	constraints["SubjectAdjacency"] = s.SubjectAdjacencyConstraints
+	constraints["SubjectSpread"] = s.SubjectSpreadConstraints
+	constraints["SubjectPeriodConsistency"] = s.SubjectPeriodConsistencyConstraints
 	constraints["SubjectConnectedDay"] = s.SubjectConnectedDayConstraints
 	constraints["TeacherMutex"] = s.TeacherMutexConstraints
 	constraints["TeacherNoonBreak"] = s.TeacherNoonBreakConstraints
 	constraints["TeacherPeriodLimit"] = s.TeacherPeriodLimitConstraints
 	constraints["TeacherRangeLimit"] = s.TeacherRangeLimitConstraints
+	constraints["TeacherWorkload"] = s.TeacherWorkloadConstraints
+	constraints["SegmentEligibility"] = s.SegmentEligibilityConstraints
+
+	constraints["TeacherTravel"] = s.TeacherTravelConstraints
+
+	// 教学场地和通行时间不生成规则, 用于教师跨教学楼上课限制
+	constraints["Venue"] = s.Venues
+	constraints["TravelTime"] = s.TravelTimes
+
+	// 学生选课信息不生成规则, 用于计算个体适应度时检查学生课程冲突
+	constraints["StudentEnrollment"] = s.StudentEnrollments
 
 	return constraints
 }
@@ -126,6 +503,14 @@ func LoadTestData(configFilePath string) (*ScheduleInput, error) {
 		return nil, fmt.Errorf("fatal error unmarshaling testdata: %s", err)
 	}
 
+	// 合并教师分组成员信息
+	if err := models.ApplyTeacherGroups(config.Teachers, config.TeacherGroups); err != nil {
+		return nil, fmt.Errorf("fatal error applying teacher groups: %s", err)
+	}
+
+	// 合并教学班信息
+	models.ApplyTeachingGroups(config.TeachingTasks, config.TeachingGroups)
+
 	// 对 Courses 属性的值按照 NumClassesPerWeek 排序
 	sort.Slice(config.TeachingTasks, func(i, j int) bool {
 		return config.TeachingTasks[i].NumClassesPerWeek > config.TeachingTasks[j].NumClassesPerWeek
@@ -144,10 +529,95 @@ func ParseScheduleInputFromJSON(jsonStr string) (*ScheduleInput, error) {
 		return nil, fmt.Errorf("fatal error unmarshaling json: %s", err)
 	}
 
-	// 对 Courses 属性的值按照 NumClassesPerWeek 排序
-	sort.Slice(input.TeachingTasks, func(i, j int) bool {
-		return input.TeachingTasks[i].NumClassesPerWeek > input.TeachingTasks[j].NumClassesPerWeek
-	})
-
+	if err := input.prepare(); err != nil {
+		return nil, err
+	}
 	return &input, nil
 }
+
+// 排课数据的规范化哈希(SHA-256)
+// 解析后重新序列化, 与空白, 字段顺序, 省略的字段和null无关
+// 数组中元素的顺序不影响排课结果(如: 教师列表, 约束条件), 序列化前按照元素的JSON排序, 也与数组的顺序无关
+// 用于判断两次提交的排课数据是否相同
+func (s *ScheduleInput) Hash() (string, error) {
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("marshal schedule input failed. %s", err)
+	}
+
+	// 转换为通用的JSON值后排序数组, 对象的字段在序列化时按照字段名排序
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return "", fmt.Errorf("decode schedule input failed. %s", err)
+	}
+
+	data, err = json.Marshal(sortJSONArrays(value))
+	if err != nil {
+		return "", fmt.Errorf("marshal schedule input failed. %s", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// 递归排序JSON值中的数组, 元素按照序列化后的JSON排序
+func sortJSONArrays(value interface{}) interface{} {
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = sortJSONArrays(item)
+		}
+
+	case []interface{}:
+		keys := make([]string, len(v))
+		for i, item := range v {
+			v[i] = sortJSONArrays(item)
+			data, _ := json.Marshal(v[i])
+			keys[i] = string(data)
+		}
+
+		indexes := lo.Range(len(v))
+		sort.SliceStable(indexes, func(i, j int) bool {
+			return keys[indexes[i]] < keys[indexes[j]]
+		})
+		return lo.Map(indexes, func(index int, _ int) interface{} {
+			return v[index]
+		})
+	}
+	return value
+}
+
+// 解析后的预处理
+// 合并教师分组和教学班信息, 教学任务按照每周课时数倒序
+func (s *ScheduleInput) prepare() error {
+
+	if err := s.applyGroups(); err != nil {
+		return err
+	}
+	s.sortTeachingTasks()
+	return nil
+}
+
+// 合并教师分组和教学班信息
+func (s *ScheduleInput) applyGroups() error {
+
+	// 合并教师分组成员信息
+	if err := models.ApplyTeacherGroups(s.Teachers, s.TeacherGroups); err != nil {
+		return fmt.Errorf("fatal error applying teacher groups: %s", err)
+	}
+
+	// 合并教学班信息
+	models.ApplyTeachingGroups(s.TeachingTasks, s.TeachingGroups)
+	return nil
+}
+
+// 对 Courses 属性的值按照 NumClassesPerWeek 排序
+func (s *ScheduleInput) sortTeachingTasks() {
+	sort.Slice(s.TeachingTasks, func(i, j int) bool {
+		return s.TeachingTasks[i].NumClassesPerWeek > s.TeachingTasks[j].NumClassesPerWeek
+	})
+}
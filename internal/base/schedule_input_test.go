@@ -0,0 +1,83 @@
+package base
+
+import (
+	"course_scheduler/internal/models"
+	"reflect"
+	"testing"
+)
+
+// 测试用的走班输入: 高二(11)有1-4班, 走班时段1在星期一第1, 2节
+func newTestTeachingGroupInput() *ScheduleInput {
+	return &ScheduleInput{
+		Schedule: &models.Schedule{Name: "test", NumWorkdays: 1, NumForenoonClasses: 4},
+		Grades: []*models.Grade{
+			{GradeID: 11, Classes: []models.Class{{ClassID: 1}, {ClassID: 2}, {ClassID: 3}, {ClassID: 4}}},
+		},
+		TeachingGroups: []*models.TeachingGroup{
+			{TeachingGroupID: 101, GradeID: 11, Name: "物理A班", ClassIDs: []int{1, 2}, ElectiveBlockID: 1},
+			{TeachingGroupID: 102, GradeID: 11, Name: "历史A班", ClassIDs: []int{2, 3}, ElectiveBlockID: 1},
+			{TeachingGroupID: 103, GradeID: 11, Name: "化学A班", ClassIDs: []int{3, 4}},
+		},
+		ElectiveBlocks: []*models.ElectiveBlock{
+			{ElectiveBlockID: 1, GradeID: 11, Name: "选考1", TimeSlots: []int{0, 1}},
+		},
+	}
+}
+
+func TestCheckTeachingGroups(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		modify func(s *ScheduleInput)
+		fields []string // 错误字段的路径, 为空时检查通过
+	}{
+		{"valid", func(s *ScheduleInput) {}, nil},
+		{"grade not found", func(s *ScheduleInput) { s.TeachingGroups[0].GradeID = 12 }, []string{"teaching_groups[0].grade_id"}},
+		{"conflicts with class id", func(s *ScheduleInput) { s.TeachingGroups[0].TeachingGroupID = 1 }, []string{"teaching_groups[0].teaching_group_id"}},
+		{"empty class ids", func(s *ScheduleInput) { s.TeachingGroups[0].ClassIDs = nil }, []string{"teaching_groups[0].class_ids"}},
+		{"class not found", func(s *ScheduleInput) { s.TeachingGroups[1].ClassIDs = []int{2, 5} }, []string{"teaching_groups[1].class_ids[1]"}},
+		{"elective block not found", func(s *ScheduleInput) { s.TeachingGroups[0].ElectiveBlockID = 2 }, []string{"teaching_groups[0].elective_block_id"}},
+		{"elective block other grade", func(s *ScheduleInput) { s.ElectiveBlocks[0].GradeID = 12 }, []string{"teaching_groups[0].elective_block_id", "teaching_groups[1].elective_block_id"}},
+		{"empty elective block time slots", func(s *ScheduleInput) { s.ElectiveBlocks[0].TimeSlots = nil }, []string{"elective_blocks[0].time_slots"}},
+		{"elective block time slot out of range", func(s *ScheduleInput) { s.ElectiveBlocks[0].TimeSlots = []int{1, 4, -1} }, []string{"elective_blocks[0].time_slots[1]", "elective_blocks[0].time_slots[2]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := newTestTeachingGroupInput()
+			tt.modify(input)
+
+			var fields []string
+			for _, err := range input.checkTeachingGroups() {
+				fields = append(fields, err.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("got fields %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}
+
+// 行政班在走班时段禁排, 走班时段内的教学班只能排在走班时段
+func TestElectiveBlockClassConstraints(t *testing.T) {
+
+	input := newTestTeachingGroupInput()
+	got := make(map[int][]int)
+	for _, c := range input.electiveBlockClassConstraints() {
+		if c.GradeID != 11 || c.Limit != "not" {
+			t.Errorf("unexpected constraint: %+v", c)
+		}
+		got[c.ClassID] = c.TimeSlots
+	}
+
+	want := map[int][]int{
+		1:   {0, 1},
+		2:   {0, 1},
+		3:   {0, 1},
+		101: {2, 3},
+		102: {2, 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
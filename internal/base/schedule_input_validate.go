@@ -0,0 +1,206 @@
+// schedule_input_validate.go
+package base
+
+import (
+	"bytes"
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// 输入检查错误
+// Field 为出错字段的路径, 如: teaching_tasks[0].teacher_id, 无法确定字段时为空
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// 输入检查错误列表
+type ValidationErrors []*FieldError
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+func (e ValidationErrors) Error() string {
+	return strings.Join(lo.Map(e, func(err *FieldError, _ int) string { return err.Error() }), "; ")
+}
+
+// 严格解析并检查 JSON 格式的排课输入
+// 1. 不允许未知的字段, 字段类型需要正确
+// 2. 必填的字段不能为空, 引用的年级, 班级, 科目, 教师需要存在
+// 3. 前面的检查都通过后, 使用 Check 检查教学计划, 返回所有的错误
+// 返回值:
+//
+//	返回 排课输入、检查错误列表(检查通过时为空)
+func ValidateScheduleInputJSON(data []byte) (*ScheduleInput, ValidationErrors) {
+
+	var input ScheduleInput
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&input); err != nil {
+		return nil, ValidationErrors{decodeFieldError(err)}
+	}
+
+	if decoder.More() {
+		return nil, ValidationErrors{{Message: "unexpected data after the json object"}}
+	}
+
+	errs := input.validateReferences()
+	if len(errs) > 0 {
+		return &input, errs
+	}
+
+	if err := input.applyGroups(); err != nil {
+		return &input, ValidationErrors{{Field: "teacher_groups", Message: err.Error()}}
+	}
+
+	// 预处理会调整教学任务的顺序, 排序前检查, 错误中的下标和请求一致
+	if errs := input.check(); len(errs) > 0 {
+		return &input, errs
+	}
+	input.sortTeachingTasks()
+	return &input, nil
+}
+
+// 将 JSON 解析错误转换为字段错误
+func decodeFieldError(err error) *FieldError {
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &FieldError{Field: fieldPath(typeErr.Field), Message: fmt.Sprintf("must be %s, got %s", typeErr.Type, typeErr.Value)}
+	}
+
+	// 未知字段的错误格式: json: unknown field "name"
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return &FieldError{Field: strings.Trim(field, `"`), Message: "unknown field"}
+	}
+	return &FieldError{Message: err.Error()}
+}
+
+// 将 JSON 解析错误中的字段路径转换为下标格式, 如: teaching_tasks.0.id 转换为 teaching_tasks[0].id
+func fieldPath(field string) string {
+
+	var path strings.Builder
+	for i, part := range strings.Split(field, ".") {
+		if _, err := strconv.Atoi(part); err == nil {
+			path.WriteString("[" + part + "]")
+			continue
+		}
+
+		if i > 0 {
+			path.WriteString(".")
+		}
+		path.WriteString(part)
+	}
+	return path.String()
+}
+
+// 检查必填字段和引用的年级, 班级, 科目, 教师
+func (s *ScheduleInput) validateReferences() ValidationErrors {
+
+	var errs ValidationErrors
+	addErr := func(field, format string, args ...interface{}) {
+		errs = append(errs, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.Schedule == nil {
+		addErr("schedule", "is required")
+	}
+	if len(s.TeachingTasks) == 0 {
+		addErr("teaching_tasks", "cannot be empty")
+	}
+	if len(s.Teachers) == 0 {
+		addErr("teachers", "cannot be empty")
+	}
+	if len(s.Subjects) == 0 {
+		addErr("subjects", "cannot be empty")
+	}
+	if len(s.Grades) == 0 {
+		addErr("grades", "cannot be empty")
+	}
+
+	for i, task := range s.TeachingTasks {
+		field := fmt.Sprintf("teaching_tasks[%d]", i)
+
+		if !s.hasClass(task.GradeID, task.ClassID) {
+			addErr(field+".class_id", "class %d not found in grade %d", task.ClassID, task.GradeID)
+		}
+		if _, err := models.FindSubjectByID(task.SubjectID, s.Subjects); err != nil {
+			addErr(field+".subject_id", "subject %d not found", task.SubjectID)
+		}
+		if _, err := models.FindTeacherByID(task.TeacherID, s.Teachers); err != nil {
+			addErr(field+".teacher_id", "teacher %d not found", task.TeacherID)
+		}
+		for j, coTeacherID := range task.CoTeacherIDs {
+			if _, err := models.FindTeacherByID(coTeacherID, s.Teachers); err != nil {
+				addErr(fmt.Sprintf("%s.co_teacher_ids[%d]", field, j), "teacher %d not found", coTeacherID)
+			}
+		}
+		if task.NumClassesPerWeek <= 0 {
+			addErr(field+".num_classes_per_week", "must be greater than 0")
+		}
+	}
+
+	for i, group := range s.TeacherGroups {
+		for j, teacherID := range group.TeacherIDs {
+			if _, err := models.FindTeacherByID(teacherID, s.Teachers); err != nil {
+				addErr(fmt.Sprintf("teacher_groups[%d].teacher_ids[%d]", i, j), "teacher %d not found", teacherID)
+			}
+		}
+	}
+
+	for sn := range s.SubjectVenueMap {
+		field := fmt.Sprintf("subject_venue_map.%s", sn)
+
+		SN, err := types.ParseSN(sn)
+		if err != nil {
+			addErr(field, "invalid key, must be subject_grade_class")
+			continue
+		}
+		if _, err := models.FindSubjectByID(SN.SubjectID, s.Subjects); err != nil {
+			addErr(field, "subject %d not found", SN.SubjectID)
+		}
+		if !s.hasClass(SN.GradeID, SN.ClassID) {
+			addErr(field, "class %d not found in grade %d", SN.ClassID, SN.GradeID)
+		}
+	}
+
+	for i, entry := range s.BaseTimetable {
+		field := fmt.Sprintf("base_timetable[%d]", i)
+
+		if _, err := models.FindTeacherByID(entry.TeacherID, s.Teachers); err != nil {
+			addErr(field+".teacher_id", "teacher %d not found", entry.TeacherID)
+		}
+		if !s.hasClass(entry.GradeID, entry.ClassID) {
+			addErr(field+".class_id", "class %d not found in grade %d", entry.ClassID, entry.GradeID)
+		}
+	}
+
+	return errs
+}
+
+// 判断年级中是否有这个班级, 教学班的id也作为班级id使用
+func (s *ScheduleInput) hasClass(gradeID, classID int) bool {
+
+	grade, err := models.FindGradeByID(gradeID, s.Grades)
+	if err != nil {
+		return false
+	}
+
+	if lo.ContainsBy(grade.Classes, func(class models.Class) bool { return class.ClassID == classID }) {
+		return true
+	}
+
+	_, err = models.FindTeachingGroupByID(gradeID, classID, s.TeachingGroups)
+	return err == nil
+}
@@ -0,0 +1,155 @@
+// timetable_grid.go
+package base
+
+import (
+	"course_scheduler/internal/models"
+	"fmt"
+	"sort"
+)
+
+// 课表网格的类型
+var TimetableGridTypes = []string{"class", "teacher", "venue"}
+
+// 课表网格中的一节课
+// 名称从排课输入中获取, 找不到时为空
+type GridLesson struct {
+	SubjectID   uint64 `json:"subject_id"`
+	SubjectName string `json:"subject_name"`
+	TeacherID   uint64 `json:"teacher_id"`
+	TeacherName string `json:"teacher_name"`
+	GradeID     uint64 `json:"grade_id"`
+	ClassID     uint64 `json:"class_id"`
+	ClassName   string `json:"class_name"`
+	VenueID     uint64 `json:"venue_id"`
+	VenueName   string `json:"venue_name"`
+	StartTime   string `json:"start_time"`
+	EndTime     string `json:"end_time"`
+}
+
+// 一个班级, 教师或者教室的课表
+//
+// | 节次 | 星期一 | 星期二 | 星期三 | 星期四 | 星期五 |
+// | ---- | ------ | ------ | ------ | ------ | ------ |
+// | 1    | 语文   | 数学   | 英语   | 语文   | 数学   |
+type TimetableGrid struct {
+	Type  string            `json:"type"`  // 类型 class: 班级, teacher: 教师, venue: 教室
+	Key   string            `json:"key"`   // 班级: 年级id_班级id, 教师: 教师id, 教室: 教室id
+	Name  string            `json:"name"`  // 班级, 教师或者教室名称
+	Cells [][][]*GridLesson `json:"cells"` // [天][节次]的课, 天和节次从0开始, 没有课时为空, 协同上课时有多节
+}
+
+// 将排课结果按照班级, 教师或者教室转换为课表网格
+// 只包含排课结果中出现的班级, 教师或者教室, 按照key排序
+func BuildTimetableGrids(input *ScheduleInput, results []*models.ScheduleResult, gridType string) ([]*TimetableGrid, error) {
+
+	var keyFn func(result *models.ScheduleResult) string
+	var nameFn func(result *models.ScheduleResult) string
+	switch gridType {
+	case "class":
+		keyFn = func(result *models.ScheduleResult) string {
+			return fmt.Sprintf("%d_%d", result.GradeID, result.ClassID)
+		}
+		nameFn = func(result *models.ScheduleResult) string {
+			return input.className(int(result.GradeID), int(result.ClassID))
+		}
+	case "teacher":
+		keyFn = func(result *models.ScheduleResult) string { return fmt.Sprintf("%d", result.TeacherID) }
+		nameFn = func(result *models.ScheduleResult) string { return input.teacherName(int(result.TeacherID)) }
+	case "venue":
+		keyFn = func(result *models.ScheduleResult) string { return fmt.Sprintf("%d", result.VenueID) }
+		nameFn = func(result *models.ScheduleResult) string { return input.venueName(int(result.VenueID)) }
+	default:
+		return nil, fmt.Errorf("invalid timetable grid type %q, must be one of %v", gridType, TimetableGridTypes)
+	}
+
+	// 网格的大小, 排课结果超出排课方案时扩大
+	numDays := input.Schedule.NumWorkdays
+	numPeriods := input.Schedule.GetTotalClassesPerDay()
+	for _, result := range results {
+		numDays = max(numDays, int(result.Weekday)+1)
+		numPeriods = max(numPeriods, int(result.Period)+1)
+	}
+
+	grids := make(map[string]*TimetableGrid)
+	for _, result := range results {
+
+		key := keyFn(result)
+		grid, ok := grids[key]
+		if !ok {
+			grid = &TimetableGrid{Type: gridType, Key: key, Name: nameFn(result), Cells: make([][][]*GridLesson, numDays)}
+			for day := range grid.Cells {
+				grid.Cells[day] = make([][]*GridLesson, numPeriods)
+			}
+			grids[key] = grid
+		}
+
+		grid.Cells[result.Weekday][result.Period] = append(grid.Cells[result.Weekday][result.Period], input.gridLesson(result))
+	}
+
+	list := make([]*TimetableGrid, 0, len(grids))
+	for _, grid := range grids {
+		list = append(list, grid)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Key < list[j].Key
+	})
+	return list, nil
+}
+
+// 将排课结果转换为课表网格中的一节课
+func (s *ScheduleInput) gridLesson(result *models.ScheduleResult) *GridLesson {
+
+	lesson := &GridLesson{
+		SubjectID:   result.SubjectID,
+		TeacherID:   result.TeacherID,
+		TeacherName: s.teacherName(int(result.TeacherID)),
+		GradeID:     result.GradeID,
+		ClassID:     result.ClassID,
+		ClassName:   s.className(int(result.GradeID), int(result.ClassID)),
+		VenueID:     result.VenueID,
+		VenueName:   s.venueName(int(result.VenueID)),
+		StartTime:   result.StartTime,
+		EndTime:     result.EndTime,
+	}
+
+	if subject, err := models.FindSubjectByID(int(result.SubjectID), s.Subjects); err == nil {
+		lesson.SubjectName = subject.Name
+	}
+	return lesson
+}
+
+// 班级名称 年级名称+班级名称, 教学班使用教学班名称
+func (s *ScheduleInput) className(gradeID, classID int) string {
+
+	grade, err := models.FindGradeByID(gradeID, s.Grades)
+	if err != nil {
+		return ""
+	}
+
+	for _, class := range grade.Classes {
+		if class.ClassID == classID {
+			return grade.Name + class.Name
+		}
+	}
+
+	if group, err := models.FindTeachingGroupByID(gradeID, classID, s.TeachingGroups); err == nil {
+		return grade.Name + group.Name
+	}
+	return grade.Name
+}
+
+// 教师名称
+func (s *ScheduleInput) teacherName(teacherID int) string {
+	if teacher, err := models.FindTeacherByID(teacherID, s.Teachers); err == nil {
+		return teacher.Name
+	}
+	return ""
+}
+
+// 教室名称
+func (s *ScheduleInput) venueName(venueID int) string {
+	if venue, err := models.FindVenueByID(venueID, s.Venues); err == nil {
+		return venue.Name
+	}
+	return ""
+}
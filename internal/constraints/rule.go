@@ -29,6 +29,11 @@ func GetFixedRules(subjects []*models.Subject, teachers []*models.Teacher, const
 			teacherConstraints := constraintValue.([]*Teacher)
 			teacherRules := GetTeacherRules(teachers, teacherConstraints)
 			rules = append(rules, teacherRules...)
+
+		case "SegmentEligibility":
+			segmentEligibilityConstraints := constraintValue.([]*SegmentEligibility)
+			segmentEligibilityRules := GetSegmentEligibilityRules(subjects, teachers, segmentEligibilityConstraints)
+			rules = append(rules, segmentEligibilityRules...)
 		}
 	}
 
@@ -46,7 +51,9 @@ func GetDynamicRules(schedule *models.Schedule, constraints map[string]interface
 	// rules = append(rules, subjectConnectedRule)
 
 	// 同一个年级,班级,科目相同节次的排课是否超过数量限制
-	rules = append(rules, subjectPeriodLimitRule)
+	// 有科目固定节次约束的课班除外
+	periodConsistencyConstraints, _ := constraints["SubjectPeriodConsistency"].([]*SubjectPeriodConsistency)
+	rules = append(rules, getSubjectPeriodLimitRule(periodConsistencyConstraints))
 
 	// 科目课时小于天数,禁止同一天排多次相同科目的课
 	rules = append(rules, subjectSameDayRule)
@@ -65,6 +72,23 @@ func GetDynamicRules(schedule *models.Schedule, constraints map[string]interface
 			subjectOrderConstraints := constraintValue.([]*SubjectOrder)
 			rules = append(rules, GetSubjectOrderRules(subjectOrderConstraints)...)
 
+		case "SubjectAdjacency":
+
+			// 科目相邻限制(体育课后不排书写多的科目)
+			subjectAdjacencyConstraints := constraintValue.([]*SubjectAdjacency)
+			rules = append(rules, GetSubjectAdjacencyRules(subjectAdjacencyConstraints)...)
+
+		case "SubjectSpread":
+
+			// 科目分布限制(数学5节课排在5天)
+			subjectSpreadConstraints := constraintValue.([]*SubjectSpread)
+			rules = append(rules, GetSubjectSpreadRules(subjectSpreadConstraints)...)
+
+		case "SubjectPeriodConsistency":
+
+			// 科目固定节次(英语每天都排在第1节)
+			rules = append(rules, GetSubjectPeriodConsistencyRules(periodConsistencyConstraints)...)
+
 		case "SubjectDayLimit":
 
 			// 每天限制(科目,教师每天的排课数量限制)
@@ -95,6 +119,20 @@ func GetDynamicRules(schedule *models.Schedule, constraints map[string]interface
 			teacherRangeLimitConstraints := constraintValue.([]*TeacherRangeLimit)
 			rules = append(rules, GetTeacherRangeLimitRules(teacherRangeLimitConstraints)...)
 
+		case "TeacherWorkload":
+
+			// 教师工作量限制
+			teacherWorkloadConstraints := constraintValue.([]*TeacherWorkload)
+			rules = append(rules, GetTeacherWorkloadRules(teacherWorkloadConstraints)...)
+
+		case "TeacherTravel":
+
+			// 教师跨教学楼上课限制
+			teacherTravelConstraints := constraintValue.([]*TeacherTravel)
+			venues, _ := constraints["Venue"].([]*models.Venue)
+			travelTimes, _ := constraints["TravelTime"].([]*models.TravelTime)
+			rules = append(rules, GetTeacherTravelRules(venues, travelTimes, teacherTravelConstraints)...)
+
 		case "SubjectConnectedDay":
 			// 连堂课每天限制
 			subjectConnectedDayConstraints := constraintValue.([]*SubjectConnectedDay)
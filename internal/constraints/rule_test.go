@@ -0,0 +1,88 @@
+package constraints
+
+import (
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/types"
+	"course_scheduler/internal/utils"
+	"testing"
+)
+
+// 测试用的课表方案: 每周5天, 每天上午4节, 下午4节, 时间段编号为 天*8+节次
+func newTestSchedule() *models.Schedule {
+	return &models.Schedule{Name: "test", NumWorkdays: 5, NumDaysOff: 2, NumForenoonClasses: 4, NumAfternoonClasses: 4}
+}
+
+// 新建课班适应性矩阵元素, classSN 为 科目_年级_班级
+func newTestElement(t *testing.T, classSN string, teacherID, venueID int, timeSlots ...int) *types.Element {
+
+	sn, err := types.ParseSN(classSN)
+	if err != nil {
+		t.Fatalf("parse sn %s failed. %s", classSN, err)
+	}
+	return types.NewElement(classSN, sn.SubjectID, sn.GradeID, sn.ClassID, teacherID, venueID, timeSlots)
+}
+
+// 新建课班适应性矩阵, used 为已经排课的元素
+func newTestClassMatrix(teachers []*models.Teacher, used ...*types.Element) *types.ClassMatrix {
+
+	cm := &types.ClassMatrix{
+		Teachers: teachers,
+		Elements: make(map[string]map[int]map[int]map[string]*types.Element),
+	}
+
+	for _, element := range used {
+		element.Val.Used = 1
+		if cm.Elements[element.ClassSN] == nil {
+			cm.Elements[element.ClassSN] = make(map[int]map[int]map[string]*types.Element)
+		}
+		if cm.Elements[element.ClassSN][element.TeacherID] == nil {
+			cm.Elements[element.ClassSN][element.TeacherID] = make(map[int]map[string]*types.Element)
+		}
+		if cm.Elements[element.ClassSN][element.TeacherID][element.VenueID] == nil {
+			cm.Elements[element.ClassSN][element.TeacherID][element.VenueID] = make(map[string]*types.Element)
+		}
+		cm.Elements[element.ClassSN][element.TeacherID][element.VenueID][utils.TimeSlotsToStr(element.TimeSlots)] = element
+	}
+	return cm
+}
+
+// 规则的检查结果
+const (
+	skipped = "skipped" // 不满足前置条件
+	passed  = "passed"  // 满足约束, 增加score
+	failed  = "failed"  // 不满足约束, 增加penalty
+)
+
+// 执行规则, 返回检查结果
+func checkRule(t *testing.T, rule *types.Rule, cm *types.ClassMatrix, element *types.Element, schedule *models.Schedule) string {
+	return checkRuleWithTasks(t, rule, cm, element, schedule, nil)
+}
+
+// 执行需要教学任务的规则, 返回检查结果
+func checkRuleWithTasks(t *testing.T, rule *types.Rule, cm *types.ClassMatrix, element *types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) string {
+
+	preCheckPassed, result, err := rule.Fn(cm, *element, schedule, teachingTasks)
+	if err != nil {
+		t.Fatalf("rule %s failed. %s", rule.Name, err)
+	}
+
+	if !preCheckPassed {
+		return skipped
+	}
+	if result {
+		return passed
+	}
+	return failed
+}
+
+// 按照名称查找规则
+func findRule(t *testing.T, rules []*types.Rule, name string) *types.Rule {
+
+	for _, rule := range rules {
+		if rule.Name == name {
+			return rule
+		}
+	}
+	t.Fatalf("rule %s not found", name)
+	return nil
+}
@@ -0,0 +1,124 @@
+// segment_eligibility.go
+// 时间区间排课限制
+
+package constraints
+
+import (
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/types"
+	"fmt"
+	"math"
+
+	"github.com/samber/lo"
+)
+
+// ###### 时间区间排课限制
+// 限制某个时间区间(早读, 上午, 下午, 晚自习)只能排哪些科目, 只能由哪些教师上课
+// 不满足条件的课程, 禁止排在该时间区间
+
+// | 时间区间 | 年级   | 班级 | 可排科目    | 可上课教师分组 | 描述                   |
+// | -------- | ------ | ---- | ----------- | -------------- | ---------------------- |
+// | 早读     |        |      | 语文, 英语  |                | 早读只上语文, 英语     |
+// | 晚自习   |        |      | 晚自习      | 班主任         | 晚自习由班主任看班     |
+// | 晚自习   | 三年级 |      | 数学        |                | 三年级晚自习只上数学   |
+
+// 年级, 班级为空表示对所有班级生效
+// 可排科目, 可排科目分组, 可上课教师分组为空(或为0)表示不限制
+type SegmentEligibility struct {
+	ID              int    `json:"id" mapstructure:"id"`                               // 自增ID
+	Segment         string `json:"segment" mapstructure:"segment"`                     // 时间区间 早读: morning_reading, 上午: forenoon, 下午: afternoon, 晚自习: night
+	GradeID         int    `json:"grade_id" mapstructure:"grade_id"`                   // 年级ID, 可以为空
+	ClassID         int    `json:"class_id" mapstructure:"class_id"`                   // 班级ID, 可以为空
+	SubjectIDs      []int  `json:"subject_ids" mapstructure:"subject_ids"`             // 可排科目ID
+	SubjectGroupIDs []int  `json:"subject_group_ids" mapstructure:"subject_group_ids"` // 可排科目分组ID
+	TeacherGroupID  int    `json:"teacher_group_id" mapstructure:"teacher_group_id"`   // 可上课教师分组ID, 如: 班主任
+	Desc            string `json:"desc" mapstructure:"desc"`                           // 描述
+}
+
+// 生成字符串
+func (s *SegmentEligibility) String() string {
+	return fmt.Sprintf("ID: %d, Segment: %s, GradeID: %d, ClassID: %d, SubjectIDs: %v, SubjectGroupIDs: %v, TeacherGroupID: %d, Desc: %s",
+		s.ID, s.Segment, s.GradeID, s.ClassID, s.SubjectIDs, s.SubjectGroupIDs, s.TeacherGroupID, s.Desc)
+}
+
+// 获取规则
+func GetSegmentEligibilityRules(subjects []*models.Subject, teachers []*models.Teacher, constraints []*SegmentEligibility) []*types.Rule {
+	// constraints := loadSegmentEligibilityConstraintsFromDB()
+	var rules []*types.Rule
+	for _, s := range constraints {
+		rule := s.genRule(subjects, teachers)
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// 生成规则
+func (s *SegmentEligibility) genRule(subjects []*models.Subject, teachers []*models.Teacher) *types.Rule {
+	fn := s.genConstraintFn(subjects, teachers)
+	return &types.Rule{
+		Name:     "segmentEligibility",
+		Type:     "fixed",
+		Fn:       fn,
+		Score:    0,
+		Penalty:  math.MaxInt32,
+		Weight:   1,
+		Priority: 1,
+	}
+}
+
+// 加载时间区间排课限制规则
+func loadSegmentEligibilityConstraintsFromDB() []*SegmentEligibility {
+	var constraints []*SegmentEligibility
+	return constraints
+}
+
+// 生成规则校验方法
+func (s *SegmentEligibility) genConstraintFn(subjects []*models.Subject, teachers []*models.Teacher) types.ConstraintFn {
+	return func(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
+
+		// 当前元素是否有时间段在约束的时间区间内
+		isInSegment := lo.ContainsBy(element.TimeSlots, func(timeSlot int) bool {
+			return schedule.GetTimeSlot(timeSlot).Segment == s.Segment
+		})
+
+		isClassMatched := (s.GradeID == 0 || s.GradeID == element.GradeID) && (s.ClassID == 0 || s.ClassID == element.ClassID)
+		if !isInSegment || !isClassMatched {
+			return false, false, nil
+		}
+
+		isEligible, err := s.isEligible(element, subjects, teachers)
+		if err != nil {
+			return false, false, err
+		}
+
+		// 禁排: 不满足条件的课程, 排了就处罚
+		preCheckPassed := !isEligible
+		return preCheckPassed, false, nil
+	}
+}
+
+// 判断科目和教师是否可以排在该时间区间
+func (s *SegmentEligibility) isEligible(element types.Element, subjects []*models.Subject, teachers []*models.Teacher) (bool, error) {
+
+	if len(s.SubjectIDs) > 0 || len(s.SubjectGroupIDs) > 0 {
+
+		subject, err := models.FindSubjectByID(element.SubjectID, subjects)
+		if err != nil {
+			return false, err
+		}
+
+		isSubjectEligible := lo.Contains(s.SubjectIDs, element.SubjectID) || len(lo.Intersect(s.SubjectGroupIDs, subject.SubjectGroupIDs)) > 0
+		if !isSubjectEligible {
+			return false, nil
+		}
+	}
+
+	// 协同上课的教师也需要在可上课教师分组内
+	if s.TeacherGroupID > 0 && !lo.EveryBy(element.GetTeacherIDs(), func(teacherID int) bool {
+		return models.IsTeacherInGroup(teacherID, s.TeacherGroupID, teachers)
+	}) {
+		return false, nil
+	}
+
+	return true, nil
+}
@@ -0,0 +1,80 @@
+package constraints
+
+import (
+	"course_scheduler/internal/models"
+	"testing"
+)
+
+func TestSegmentEligibilityRules(t *testing.T) {
+
+	// 每天早读1节, 上午4节, 下午3节, 晚自习2节
+	// 星期一的时间段: 早读 0, 上午 1-4, 下午 5-7, 晚自习 8-9
+	schedule := &models.Schedule{Name: "test", NumWorkdays: 5, NumMorningReadingClasses: 1, NumForenoonClasses: 4, NumAfternoonClasses: 3, NumNightClasses: 2}
+	subjects := []*models.Subject{
+		{SubjectID: 1, Name: "语文", SubjectGroupIDs: []int{1}},
+		{SubjectID: 2, Name: "数学", SubjectGroupIDs: []int{1}},
+		{SubjectID: 3, Name: "英语", SubjectGroupIDs: []int{2}},
+	}
+	teachers := []*models.Teacher{
+		{TeacherID: 1, TeacherGroupIDs: []int{10}},
+		{TeacherID: 2},
+	}
+	cm := newTestClassMatrix(teachers)
+
+	tests := []struct {
+		name       string
+		constraint *SegmentEligibility
+		classSN    string
+		teacherID  int
+		timeSlots  []int
+		want       string
+	}{
+		{"subject eligible", &SegmentEligibility{Segment: "morning_reading", SubjectIDs: []int{1, 3}}, "1_1_1", 1, []int{0}, skipped},
+		{"subject not eligible", &SegmentEligibility{Segment: "morning_reading", SubjectIDs: []int{1, 3}}, "2_1_1", 1, []int{0}, failed},
+		{"subject group eligible", &SegmentEligibility{Segment: "morning_reading", SubjectGroupIDs: []int{2}}, "3_1_1", 1, []int{0}, skipped},
+		{"subject group not eligible", &SegmentEligibility{Segment: "morning_reading", SubjectGroupIDs: []int{2}}, "1_1_1", 1, []int{0}, failed},
+		{"other segment", &SegmentEligibility{Segment: "morning_reading", SubjectIDs: []int{1}}, "2_1_1", 1, []int{1}, skipped},
+		{"teacher group eligible", &SegmentEligibility{Segment: "night", TeacherGroupID: 10}, "2_1_1", 1, []int{8}, skipped},
+		{"teacher group not eligible", &SegmentEligibility{Segment: "night", TeacherGroupID: 10}, "2_1_1", 2, []int{8}, failed},
+		// 连堂课有一节在时间区间内也受限制
+		{"connected into segment", &SegmentEligibility{Segment: "night", TeacherGroupID: 10}, "2_1_1", 2, []int{7, 8}, failed},
+		{"other grade", &SegmentEligibility{Segment: "night", GradeID: 3, SubjectIDs: []int{2}}, "1_1_1", 1, []int{8}, skipped},
+		{"same grade", &SegmentEligibility{Segment: "night", GradeID: 1, SubjectIDs: []int{2}}, "1_1_1", 1, []int{8}, failed},
+		{"other class", &SegmentEligibility{Segment: "night", GradeID: 1, ClassID: 2, SubjectIDs: []int{2}}, "1_1_1", 1, []int{8}, skipped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			rules := GetSegmentEligibilityRules(subjects, teachers, []*SegmentEligibility{tt.constraint})
+			element := newTestElement(t, tt.classSN, tt.teacherID, 101, tt.timeSlots...)
+			if got := checkRule(t, rules[0], cm, element, schedule); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// 协同上课的教师也需要在可上课教师分组内
+func TestSegmentEligibilityCoTeacher(t *testing.T) {
+
+	schedule := &models.Schedule{Name: "test", NumWorkdays: 5, NumMorningReadingClasses: 1, NumForenoonClasses: 4, NumAfternoonClasses: 3, NumNightClasses: 2}
+	teachers := []*models.Teacher{
+		{TeacherID: 1, TeacherGroupIDs: []int{10}},
+		{TeacherID: 2},
+		{TeacherID: 3, TeacherGroupIDs: []int{10}},
+	}
+	cm := newTestClassMatrix(teachers)
+	rules := GetSegmentEligibilityRules(nil, teachers, []*SegmentEligibility{{Segment: "night", TeacherGroupID: 10}})
+
+	element := newTestElement(t, "2_1_1", 1, 101, 8)
+	element.CoTeacherIDs = []int{3}
+	if got := checkRule(t, rules[0], cm, element, schedule); got != skipped {
+		t.Errorf("co-teacher in group: got %s, want %s", got, skipped)
+	}
+
+	element.CoTeacherIDs = []int{2}
+	if got := checkRule(t, rules[0], cm, element, schedule); got != failed {
+		t.Errorf("co-teacher not in group: got %s, want %s", got, failed)
+	}
+}
@@ -0,0 +1,253 @@
+// subject_adjacency.go
+// 科目相邻限制
+
+package constraints
+
+import (
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/types"
+	"course_scheduler/internal/utils"
+	"fmt"
+	"sort"
+
+	"github.com/samber/lo"
+)
+
+// ###### 科目相邻限制
+// 同一天内, 同一个时间区间相邻的两节课
+
+// | 年级 | 班级 | 限制类型     | 科目A(分组) | 科目B(分组) | 次数 | 描述                       |
+// | ---- | ---- | ------------ | ----------- | ----------- | ---- | -------------------------- |
+// |      |      | 禁止相邻     | 体育        | 书写类      |      | 体育课后不排书写多的科目   |
+// | 一年 |      | 必须相邻     | 语文        | 语文作辅    |      | 语文和语文作辅排在一起     |
+// |      |      | 连续上课限制 | 主课        |             | 2    | 主课每天最多连着上2次      |
+
+// 限制类型
+// not_adjacent: 科目A后面不能紧接着上科目B
+// must_adjacent: 科目A和科目B需要相邻(前后都可以)
+// max_consecutive: 科目A(分组)内的科目, 每天相邻上课的次数不超过MaxCount, 不需要设置科目B
+
+// 年级, 班级为空表示对所有班级生效
+// 科目和科目分组二选一, 指定科目分组时, 对分组内所有科目生效
+type SubjectAdjacency struct {
+	ID              int    `json:"id" mapstructure:"id"`                                 // 自增ID
+	GradeID         int    `json:"grade_id" mapstructure:"grade_id"`                     // 年级ID, 可以为空
+	ClassID         int    `json:"class_id" mapstructure:"class_id"`                     // 班级ID, 可以为空
+	Mode            string `json:"mode" mapstructure:"mode"`                             // 限制类型 not_adjacent: 禁止相邻, must_adjacent: 必须相邻, max_consecutive: 连续上课限制
+	SubjectAID      int    `json:"subject_a_id" mapstructure:"subject_a_id"`             // 科目A ID
+	SubjectAGroupID int    `json:"subject_a_group_id" mapstructure:"subject_a_group_id"` // 科目分组A ID
+	SubjectBID      int    `json:"subject_b_id" mapstructure:"subject_b_id"`             // 科目B ID
+	SubjectBGroupID int    `json:"subject_b_group_id" mapstructure:"subject_b_group_id"` // 科目分组B ID
+	MaxCount        int    `json:"max_count" mapstructure:"max_count"`                   // 每天最多相邻上课次数, 连续上课限制使用
+	Desc            string `json:"desc" mapstructure:"desc"`                             // 描述
+}
+
+// 科目相邻限制的限制类型
+var SubjectAdjacencyModes = []string{"not_adjacent", "must_adjacent", "max_consecutive"}
+
+// 生成字符串
+func (s *SubjectAdjacency) String() string {
+	return fmt.Sprintf("ID: %d, GradeID: %d, ClassID: %d, Mode: %s, SubjectAID: %d, SubjectAGroupID: %d, SubjectBID: %d, SubjectBGroupID: %d, MaxCount: %d, Desc: %s",
+		s.ID, s.GradeID, s.ClassID, s.Mode, s.SubjectAID, s.SubjectAGroupID, s.SubjectBID, s.SubjectBGroupID, s.MaxCount, s.Desc)
+}
+
+// 获取规则
+func GetSubjectAdjacencyRules(constraints []*SubjectAdjacency) []*types.Rule {
+	// constraints := loadSubjectAdjacencyConstraintsFromDB()
+	var rules []*types.Rule
+	for _, c := range constraints {
+		rule := c.genRule()
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// 生成规则
+func (s *SubjectAdjacency) genRule() *types.Rule {
+	fn := s.genConstraintFn()
+	return &types.Rule{
+		Name:     "subjectAdjacency",
+		Type:     "dynamic",
+		Fn:       fn,
+		Score:    s.getScore(),
+		Penalty:  s.getPenalty(),
+		Weight:   1,
+		Priority: 1,
+	}
+}
+
+// 加载科目相邻限制
+func loadSubjectAdjacencyConstraintsFromDB() []*SubjectAdjacency {
+	var constraints []*SubjectAdjacency
+	return constraints
+}
+
+// 生成规则校验方法
+func (s *SubjectAdjacency) genConstraintFn() types.ConstraintFn {
+	return func(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
+
+		isClassMatched := (s.GradeID == 0 || s.GradeID == element.GradeID) && (s.ClassID == 0 || s.ClassID == element.ClassID)
+		if !isClassMatched {
+			return false, false, nil
+		}
+
+		subjects := classMatrix.Subjects
+		isA := isSubjectMatched(element.SubjectID, s.SubjectAID, s.SubjectAGroupID, subjects)
+		isB := isSubjectMatched(element.SubjectID, s.SubjectBID, s.SubjectBGroupID, subjects)
+
+		preCheckPassed := isA || (isB && s.Mode != "max_consecutive")
+		if !preCheckPassed {
+			return false, false, nil
+		}
+
+		// 班级一周的排课, 包括当前元素
+		timeSlotSubjects, err := classTimeSlotSubjects(classMatrix, element)
+		if err != nil {
+			return false, false, err
+		}
+		day := schedule.GetTimeSlot(element.TimeSlots[0]).Day
+
+		shouldPenalize := false
+		switch s.Mode {
+		case "not_adjacent":
+			// 科目A后面紧接着科目B
+			for _, timeSlot := range element.TimeSlots {
+				if isA && s.isAdjacentSubject(timeSlot, timeSlot+1, timeSlotSubjects, schedule, s.SubjectBID, s.SubjectBGroupID, subjects) {
+					shouldPenalize = true
+				}
+				if isB && s.isAdjacentSubject(timeSlot, timeSlot-1, timeSlotSubjects, schedule, s.SubjectAID, s.SubjectAGroupID, subjects) {
+					shouldPenalize = true
+				}
+			}
+
+		case "must_adjacent":
+			// 连堂课取第一节的前一节和最后一节的后一节
+			first := element.TimeSlots[0]
+			last := element.TimeSlots[len(element.TimeSlots)-1]
+
+			subjectID, groupID := s.SubjectBID, s.SubjectBGroupID
+			if !isA {
+				subjectID, groupID = s.SubjectAID, s.SubjectAGroupID
+			}
+
+			isAdjacent := s.isAdjacentSubject(first, first-1, timeSlotSubjects, schedule, subjectID, groupID, subjects) ||
+				s.isAdjacentSubject(last, last+1, timeSlotSubjects, schedule, subjectID, groupID, subjects)
+			shouldPenalize = !isAdjacent
+
+		case "max_consecutive":
+			count := s.countDayConsecutive(day, timeSlotSubjects, schedule, subjects)
+			shouldPenalize = count > s.MaxCount
+		}
+
+		return preCheckPassed, !shouldPenalize, nil
+	}
+}
+
+// 判断相邻的时间段other, 是否排了指定的科目(分组)
+// 时间段有多节课(如: 教学班, 选修课)时, 任意一节课是指定的科目都算作相邻
+func (s *SubjectAdjacency) isAdjacentSubject(timeSlot, other int, timeSlotSubjects map[int][]int, schedule *models.Schedule, subjectID, subjectGroupID int, subjects []*models.Subject) bool {
+
+	otherSubjectIDs, ok := timeSlotSubjects[other]
+	if !ok || !schedule.GetTimeSlot(timeSlot).IsAdjacent(schedule.GetTimeSlot(other)) {
+		return false
+	}
+	return lo.ContainsBy(otherSubjectIDs, func(otherSubjectID int) bool {
+		return isSubjectMatched(otherSubjectID, subjectID, subjectGroupID, subjects)
+	})
+}
+
+// 统计某天科目A(分组)内的科目相邻上课的次数
+// 连堂课也算作一次相邻
+func (s *SubjectAdjacency) countDayConsecutive(day int, timeSlotSubjects map[int][]int, schedule *models.Schedule, subjects []*models.Subject) int {
+
+	timeSlots := lo.Filter(lo.Keys(timeSlotSubjects), func(timeSlot int, _ int) bool {
+		return schedule.GetTimeSlot(timeSlot).Day == day && lo.ContainsBy(timeSlotSubjects[timeSlot], func(subjectID int) bool {
+			return isSubjectMatched(subjectID, s.SubjectAID, s.SubjectAGroupID, subjects)
+		})
+	})
+	sort.Ints(timeSlots)
+
+	count := 0
+	for i := 1; i < len(timeSlots); i++ {
+		if schedule.GetTimeSlot(timeSlots[i-1]).IsAdjacent(schedule.GetTimeSlot(timeSlots[i])) {
+			count++
+		}
+	}
+	return count
+}
+
+// 获取奖励分
+func (s *SubjectAdjacency) getScore() int {
+	if s.Mode == "must_adjacent" {
+		return 4
+	}
+	return 0
+}
+
+// 获取处罚分
+func (s *SubjectAdjacency) getPenalty() int {
+	switch s.Mode {
+	case "not_adjacent":
+		return 6
+	case "must_adjacent", "max_consecutive":
+		return 4
+	default:
+		return 0
+	}
+}
+
+// 判断科目是否是指定的科目, 或者属于指定的科目分组
+func isSubjectMatched(subjectID, targetSubjectID, targetSubjectGroupID int, subjects []*models.Subject) bool {
+
+	if targetSubjectID > 0 && subjectID == targetSubjectID {
+		return true
+	}
+
+	if targetSubjectGroupID > 0 {
+		subject, err := models.FindSubjectByID(subjectID, subjects)
+		if err == nil && lo.Contains(subject.SubjectGroupIDs, targetSubjectGroupID) {
+			return true
+		}
+	}
+	return false
+}
+
+// 获取元素所在班级一周的排课情况
+// 此时是假设当前元素会排课, 所以需要将当前元素也计算在内
+// 教学班, 选修课占用的行政班也计算在内, 同一个时间段可能有多个科目
+// key: 时间段, value: 科目id列表
+func classTimeSlotSubjects(classMatrix *types.ClassMatrix, element types.Element) (map[int][]int, error) {
+
+	classKeys := element.GetClassKeys()
+	timeSlotSubjects := make(map[int][]int)
+	for sn, teacherMap := range classMatrix.Elements {
+
+		SN, err := types.ParseSN(sn)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, venueMap := range teacherMap {
+			for _, timeSlotMap := range venueMap {
+				for timeSlotStr, e := range timeSlotMap {
+					if e.Val.Used != 1 || len(lo.Intersect(classKeys, e.GetClassKeys())) == 0 {
+						continue
+					}
+
+					for _, timeSlot := range utils.ParseTimeSlotStr(timeSlotStr) {
+						if !lo.Contains(timeSlotSubjects[timeSlot], SN.SubjectID) {
+							timeSlotSubjects[timeSlot] = append(timeSlotSubjects[timeSlot], SN.SubjectID)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for _, timeSlot := range element.TimeSlots {
+		if !lo.Contains(timeSlotSubjects[timeSlot], element.SubjectID) {
+			timeSlotSubjects[timeSlot] = append(timeSlotSubjects[timeSlot], element.SubjectID)
+		}
+	}
+	return timeSlotSubjects, nil
+}
@@ -0,0 +1,133 @@
+package constraints
+
+import (
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/types"
+	"fmt"
+	"testing"
+)
+
+func TestSubjectAdjacencyRules(t *testing.T) {
+
+	// 时间段 0-3 是星期一上午, 4-7 是星期一下午
+	schedule := newTestSchedule()
+	teachers := []*models.Teacher{{TeacherID: 1}}
+	subjects := []*models.Subject{
+		{SubjectID: 1, Name: "语文", SubjectGroupIDs: []int{1}},
+		{SubjectID: 2, Name: "数学", SubjectGroupIDs: []int{1}},
+		{SubjectID: 3, Name: "体育"},
+		{SubjectID: 4, Name: "书法", SubjectGroupIDs: []int{2}},
+		{SubjectID: 5, Name: "语文作辅"},
+	}
+
+	notAdjacent := &SubjectAdjacency{Mode: "not_adjacent", SubjectAID: 3, SubjectBGroupID: 2}
+	mustAdjacent := &SubjectAdjacency{Mode: "must_adjacent", SubjectAID: 1, SubjectBID: 5}
+	maxConsecutive := &SubjectAdjacency{Mode: "max_consecutive", SubjectAGroupID: 1, MaxCount: 1}
+
+	// used 为1班已经排课的科目, key: 时间段, value: 科目id
+	tests := []struct {
+		name       string
+		constraint *SubjectAdjacency
+		used       map[int]int
+		subjectID  int
+		timeSlots  []int
+		want       string
+	}{
+		{"not adjacent b after a", notAdjacent, map[int]int{0: 3}, 4, []int{1}, failed},
+		{"not adjacent a before b", notAdjacent, map[int]int{1: 4}, 3, []int{0}, failed},
+		{"not adjacent b before a", notAdjacent, map[int]int{0: 4}, 3, []int{1}, passed},
+		{"not adjacent across segments", notAdjacent, map[int]int{3: 3}, 4, []int{4}, passed},
+		{"not adjacent other subject", notAdjacent, map[int]int{0: 3}, 1, []int{1}, skipped},
+		{"not adjacent other class", notAdjacent, nil, 4, []int{1}, passed},
+
+		{"must adjacent before", mustAdjacent, map[int]int{1: 5}, 1, []int{0}, passed},
+		{"must adjacent after", mustAdjacent, map[int]int{1: 5}, 1, []int{2}, passed},
+		{"must adjacent b", mustAdjacent, map[int]int{1: 1}, 5, []int{2}, passed},
+		{"must adjacent b before a", mustAdjacent, map[int]int{3: 1}, 5, []int{2}, passed},
+		{"must adjacent missing", mustAdjacent, map[int]int{1: 5}, 1, []int{3}, failed},
+		{"must adjacent connected", mustAdjacent, map[int]int{3: 5}, 1, []int{1, 2}, passed},
+
+		{"max consecutive ok", maxConsecutive, map[int]int{0: 1}, 2, []int{1}, passed},
+		{"max consecutive exceeded", maxConsecutive, map[int]int{0: 1, 1: 2}, 1, []int{2}, failed},
+		{"max consecutive other day", maxConsecutive, map[int]int{0: 1, 1: 2}, 1, []int{10}, passed},
+		{"max consecutive b ignored", maxConsecutive, map[int]int{0: 1}, 5, []int{1}, skipped},
+
+		{"other grade", &SubjectAdjacency{Mode: "not_adjacent", GradeID: 2, SubjectAID: 3, SubjectBID: 4}, map[int]int{0: 3}, 4, []int{1}, skipped},
+		{"same class", &SubjectAdjacency{Mode: "not_adjacent", GradeID: 1, ClassID: 1, SubjectAID: 3, SubjectBID: 4}, map[int]int{0: 3}, 4, []int{1}, failed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var used []*types.Element
+			for timeSlot, subjectID := range tt.used {
+				used = append(used, newTestElement(t, fmt.Sprintf("%d_1_1", subjectID), 1, 101, timeSlot))
+			}
+			// 2班星期一第1节是体育, 不影响1班
+			used = append(used, newTestElement(t, "3_1_2", 1, 102, 0))
+
+			cm := newTestClassMatrix(teachers, used...)
+			cm.Subjects = subjects
+
+			rules := GetSubjectAdjacencyRules([]*SubjectAdjacency{tt.constraint})
+			element := newTestElement(t, fmt.Sprintf("%d_1_1", tt.subjectID), 1, 101, tt.timeSlots...)
+			if got := checkRule(t, rules[0], cm, element, schedule); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// 教学班, 选修课占用行政班的时间段, 也和行政班的课程相邻
+func TestSubjectAdjacencyTeachingGroups(t *testing.T) {
+
+	schedule := newTestSchedule()
+	teachers := []*models.Teacher{{TeacherID: 1}, {TeacherID: 2}, {TeacherID: 3}}
+	subjects := []*models.Subject{
+		{SubjectID: 1, Name: "语文"},
+		{SubjectID: 3, Name: "体育"},
+		{SubjectID: 4, Name: "书法", SubjectGroupIDs: []int{2}},
+		{SubjectID: 5, Name: "语文作辅"},
+		{SubjectID: 6, Name: "美术"},
+	}
+
+	// 星期一第2节, 1班的学生分别去上书法(教学班11)和美术(教学班12)
+	calligraphy := newTestElement(t, "4_1_11", 2, 201, 1)
+	calligraphy.OccupiedClassIDs = []int{1}
+	art := newTestElement(t, "6_1_12", 3, 202, 1)
+	art.OccupiedClassIDs = []int{1, 2}
+	// 星期一第3节是语文作辅
+	companion := newTestElement(t, "5_1_13", 3, 203, 2)
+	companion.OccupiedClassIDs = []int{1}
+
+	tests := []struct {
+		name       string
+		constraint *SubjectAdjacency
+		classSN    string
+		timeSlots  []int
+		want       string
+	}{
+		{"not adjacent teaching group", &SubjectAdjacency{Mode: "not_adjacent", SubjectAID: 3, SubjectBGroupID: 2}, "3_1_1", []int{0}, failed},
+		{"not adjacent other class", &SubjectAdjacency{Mode: "not_adjacent", SubjectAID: 3, SubjectBGroupID: 2}, "3_1_2", []int{0}, passed},
+		{"must adjacent any subject in slot", &SubjectAdjacency{Mode: "must_adjacent", SubjectAID: 1, SubjectBID: 6}, "1_1_1", []int{0}, passed},
+		{"must adjacent after", &SubjectAdjacency{Mode: "must_adjacent", SubjectAID: 1, SubjectBID: 5}, "1_1_1", []int{3}, passed},
+		{"must adjacent missing", &SubjectAdjacency{Mode: "must_adjacent", SubjectAID: 1, SubjectBID: 5}, "1_1_1", []int{5}, failed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			cm := newTestClassMatrix(teachers, calligraphy, art, companion)
+			cm.Subjects = subjects
+
+			rules := GetSubjectAdjacencyRules([]*SubjectAdjacency{tt.constraint})
+			element := newTestElement(t, tt.classSN, 1, 101, tt.timeSlots...)
+			// 同一个时间段有多个科目时, 结果和遍历顺序无关
+			for i := 0; i < 20; i++ {
+				if got := checkRule(t, rules[0], cm, element, schedule); got != tt.want {
+					t.Fatalf("got %s, want %s", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -58,7 +58,6 @@ func isSubjectConnected(classMatrix *types.ClassMatrix, element types.Element, s
 	subjectID := element.SubjectID
 
 	// 每天课节数
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
 	subjectTimeSlots := make([]int, 0)
 
 	// key: [课班(科目_年级_班级)][教师][教室][时间段], value: Element
@@ -93,14 +92,14 @@ func isSubjectConnected(classMatrix *types.ClassMatrix, element types.Element, s
 	dayTimeSlots := make(map[int][]int)
 	for i := 0; i < len(subjectTimeSlots); i++ {
 
-		day := subjectTimeSlots[i] / totalClassesPerDay
+		day := schedule.GetTimeSlot(subjectTimeSlots[i]).Day
 		dayTimeSlots[day] = append(dayTimeSlots[day], subjectTimeSlots[i])
 	}
 
 	for _, timeSlot := range element.TimeSlots {
 
 		// 计算当前时间节点是第几天
-		elementDay := timeSlot / totalClassesPerDay
+		elementDay := schedule.GetTimeSlot(timeSlot).Day
 		// 遍历同一天的时间段
 		timeSlots := dayTimeSlots[elementDay]
 		for i := 0; i < len(timeSlots)-1; i++ {
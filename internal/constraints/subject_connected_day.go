@@ -53,7 +53,6 @@ func (s *SubjectConnectedDay) genConstraintFn() types.ConstraintFn {
 
 	return func(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
 
-		totalClassesPerDay := schedule.GetTotalClassesPerDay()
 		eleTeacherID := element.GetTeacherID()
 		eleGradeID := element.GradeID
 		eleClassID := element.ClassID
@@ -67,7 +66,7 @@ func (s *SubjectConnectedDay) genConstraintFn() types.ConstraintFn {
 		var weekdayConnectedCountMap map[int]int
 
 		// 这里使用第1个时间段
-		eleWeekday := eleTimeSlots[0]/totalClassesPerDay + 1
+		eleWeekday := schedule.GetTimeSlot(eleTimeSlots[0]).Weekday()
 
 		// 如果年级(班级)科目不为空,则计算年级(班级)科目的连堂课数量
 		if eleIsConnected && eleGradeID == s.GradeID && (eleClassID == s.ClassID || s.ClassID == 0) && eleSubjectID == s.SubjectID && (eleWeekday == s.Weekday || s.Weekday == 0) {
@@ -115,7 +114,6 @@ func (s *SubjectConnectedDay) getPoints() int {
 // key: 星期几, value:
 func (s *SubjectConnectedDay) countSubjectDayConnectedClasses(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule) (map[int]int, error) {
 
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
 	// key: 星期几, val: 连堂课数量
 	weekdayConnectedCountMap := make(map[int]int)
 
@@ -157,7 +155,7 @@ func (s *SubjectConnectedDay) countSubjectDayConnectedClasses(classMatrix *types
 					for timeSlotStr, e := range venueMap {
 						if e.Val.Used == 1 && e.IsConnected {
 							timeSlots := utils.ParseTimeSlotStr(timeSlotStr)
-							weekday := timeSlots[0]/totalClassesPerDay + 1
+							weekday := schedule.GetTimeSlot(timeSlots[0]).Weekday()
 							// 星期几
 							weekdayConnectedCountMap[weekday]++
 						}
@@ -174,7 +172,6 @@ func (s *SubjectConnectedDay) countSubjectDayConnectedClasses(classMatrix *types
 // 计算特定教师的每天的连堂课数量
 func (s *SubjectConnectedDay) countTeacherDayConnectedClasses(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule) map[int]int {
 
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
 	// key: 星期几, val: 连堂课数量
 	weekdayConnectedCountMap := make(map[int]int)
 
@@ -186,7 +183,7 @@ func (s *SubjectConnectedDay) countTeacherDayConnectedClasses(classMatrix *types
 
 						if e.Val.Used == 1 && e.IsConnected {
 							timeSlots := utils.ParseTimeSlotStr(timeSlotStr)
-							weekday := timeSlots[0]/totalClassesPerDay + 1
+							weekday := schedule.GetTimeSlot(timeSlots[0]).Weekday()
 							// 星期几
 							weekdayConnectedCountMap[weekday]++
 						}
@@ -65,7 +65,6 @@ func (s *SubjectDayLimit) genConstraintFn() types.ConstraintFn {
 
 	return func(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
 
-		totalClassesPerDay := schedule.GetTotalClassesPerDay()
 		teacherID := element.GetTeacherID()
 		gradeID := element.GradeID
 		classID := element.ClassID
@@ -84,7 +83,7 @@ func (s *SubjectDayLimit) genConstraintFn() types.ConstraintFn {
 		}
 
 		// 这里使用第1个时间段
-		weekday := timeSlots[0]/totalClassesPerDay + 1
+		weekday := schedule.GetTimeSlot(timeSlots[0]).Weekday()
 		count = weekdayCountMap[weekday]
 
 		// 对象是科目
@@ -182,11 +181,10 @@ func (s *SubjectDayLimit) countElementSubjectDayClasses(classMatrix *types.Class
 	// 如果type是subject则统计年级(班级)科目下的每天排课数量
 	// 如果type是teacher则统计教师下面的每天的排课数量
 	// 此时是假设当前元素会排课,所以需要将当前元素也计算在内
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
 	// key: 星期几, val: 数量
 	weekdayCountMap := make(map[int]int)
 	// 当前元素是周几
-	eleWeekday := element.TimeSlots[0]/totalClassesPerDay + 1
+	eleWeekday := schedule.GetTimeSlot(element.TimeSlots[0]).Weekday()
 
 	// 示例: 初一 语文 每天固定 1节课
 	if s.Object == "subject" {
@@ -215,7 +213,7 @@ func (s *SubjectDayLimit) countElementSubjectDayClasses(classMatrix *types.Class
 
 									eleTimeSlots := utils.ParseTimeSlotStr(timeSlotStr)
 									// 这里把连堂课,也视为1节课
-									weekday := eleTimeSlots[0]/totalClassesPerDay + 1
+									weekday := schedule.GetTimeSlot(eleTimeSlots[0]).Weekday()
 									// 星期几
 									weekdayCountMap[weekday]++
 								}
@@ -236,11 +234,10 @@ func (s *SubjectDayLimit) countElementTeacherDayClasses(classMatrix *types.Class
 	// 如果type是subject则统计年级(班级)科目下的每天排课数量
 	// 如果type是teacher则统计教师下面的每天的排课数量
 	// 此时是假设当前元素会排课,所以需要将当前元素也计算在内
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
 	// key: 星期几, val: 数量
 	weekdayCountMap := make(map[int]int)
 	// 当前元素是周几
-	eleWeekday := element.TimeSlots[0]/totalClassesPerDay + 1
+	eleWeekday := schedule.GetTimeSlot(element.TimeSlots[0]).Weekday()
 
 	// 示例: : 王老师 每天固定 1节课
 	if s.Object == "teacher" {
@@ -260,7 +257,7 @@ func (s *SubjectDayLimit) countElementTeacherDayClasses(classMatrix *types.Class
 
 									eleTimeSlots := utils.ParseTimeSlotStr(timeSlotStr)
 									// 这里把连堂课,也视为1节课
-									weekday := eleTimeSlots[0]/totalClassesPerDay + 1
+									weekday := schedule.GetTimeSlot(eleTimeSlots[0]).Weekday()
 									// 星期几
 									weekdayCountMap[weekday]++
 								}
@@ -88,10 +88,9 @@ func (s *SubjectMutex) genConstraintFn() types.ConstraintFn {
 func isElementSubjectOnSameDay(subjectAID, subjectBID int, classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule) (bool, error) {
 
 	timeSlots := element.GetTimeSlots()
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
 
 	// 这里使用第一个时间段
-	elementDay := timeSlots[0] / totalClassesPerDay
+	elementDay := schedule.GetTimeSlot(timeSlots[0]).Day
 
 	// key: day, val:bool
 	subjectADays := make(map[int]bool)
@@ -113,9 +112,9 @@ func isElementSubjectOnSameDay(subjectAID, subjectBID int, classMatrix *types.Cl
 						ts := utils.ParseTimeSlotStr(timeSlotStr)
 						for _, t := range ts {
 							if SN.SubjectID == subjectAID {
-								subjectADays[t/totalClassesPerDay] = true // 将时间段转换为天数
+								subjectADays[schedule.GetTimeSlot(t).Day] = true // 将时间段转换为天数
 							} else if SN.SubjectID == subjectBID {
-								subjectBDays[t/totalClassesPerDay] = true // 将时间段转换为天数
+								subjectBDays[schedule.GetTimeSlot(t).Day] = true // 将时间段转换为天数
 							}
 						}
 					}
@@ -92,7 +92,6 @@ func (s *SubjectOrder) genConstraintFn() types.ConstraintFn {
 // 判断当前元素的排课课程是否会出现课程A(体育)是在课程B(数学)之前的结果
 func isElementSubjectABeforeSubjectB(subjectAID, subjectBID int, classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule) (bool, error) {
 
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
 	// 遍历课程表，同时记录课程A和课程B的上课时间段
 	var timeSlotsA, timeSlotsB []int
 	timeSlots := element.GetTimeSlots()
@@ -140,8 +139,8 @@ func isElementSubjectABeforeSubjectB(subjectAID, subjectBID int, classMatrix *ty
 	for _, timeSlotA := range timeSlotsA {
 		for _, timeSlotB := range timeSlotsB {
 
-			dayA := timeSlotA / totalClassesPerDay
-			dayB := timeSlotB / totalClassesPerDay
+			dayA := schedule.GetTimeSlot(timeSlotA).Day
+			dayB := schedule.GetTimeSlot(timeSlotB).Day
 
 			if dayA == dayB && timeSlotB == timeSlotA+1 && (lo.Contains(timeSlots, timeSlotA) || lo.Contains(timeSlots, timeSlotB)) {
 				return true, nil
@@ -0,0 +1,130 @@
+// subject_period_consistency.go
+// 科目固定节次
+
+package constraints
+
+import (
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/types"
+	"course_scheduler/internal/utils"
+	"fmt"
+	"sort"
+
+	"github.com/samber/lo"
+)
+
+// ###### 科目固定节次
+// 科目每天尽量排在相同的节次, 课表更有规律, 如: 英语每天都是第1节
+
+// | 年级   | 班级 | 科目 | 最多节次数 | 描述                   |
+// | ------ | ---- | ---- | ---------- | ---------------------- |
+// |        |      | 英语 | 1          | 英语每天都排在同一节   |
+// | 一年级 | 1班  | 数学 | 2          | 数学最多排在两个节次   |
+
+// 年级, 班级为空表示对所有班级生效
+// 最多节次数为0时和1相同, 表示都排在同一个节次
+// 课程排在该课班已排课次数最多的几个节次时奖励, 否则处罚, 当前课程不计入已排课次数
+// 连堂课按照第一节的节次计算
+// 受该约束的课班, 不再受"相同节次排课数量限制"(subjectPeriodLimit)的约束
+type SubjectPeriodConsistency struct {
+	ID                 int    `json:"id" mapstructure:"id"`                                     // 自增ID
+	GradeID            int    `json:"grade_id" mapstructure:"grade_id"`                         // 年级ID, 可以为空
+	ClassID            int    `json:"class_id" mapstructure:"class_id"`                         // 班级ID, 可以为空
+	SubjectID          int    `json:"subject_id" mapstructure:"subject_id"`                     // 科目ID
+	MaxDistinctPeriods int    `json:"max_distinct_periods" mapstructure:"max_distinct_periods"` // 最多排在几个不同的节次
+	Desc               string `json:"desc" mapstructure:"desc"`                                 // 描述
+}
+
+// 生成字符串
+func (s *SubjectPeriodConsistency) String() string {
+	return fmt.Sprintf("ID: %d, GradeID: %d, ClassID: %d, SubjectID: %d, MaxDistinctPeriods: %d, Desc: %s",
+		s.ID, s.GradeID, s.ClassID, s.SubjectID, s.MaxDistinctPeriods, s.Desc)
+}
+
+// 获取规则
+func GetSubjectPeriodConsistencyRules(constraints []*SubjectPeriodConsistency) []*types.Rule {
+	// constraints := loadSubjectPeriodConsistencyConstraintsFromDB()
+	var rules []*types.Rule
+	for _, c := range constraints {
+		rule := c.genRule()
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// 生成规则
+func (s *SubjectPeriodConsistency) genRule() *types.Rule {
+	fn := s.genConstraintFn()
+	return &types.Rule{
+		Name:     "subjectPeriodConsistency",
+		Type:     "dynamic",
+		Fn:       fn,
+		Score:    4,
+		Penalty:  2,
+		Weight:   1,
+		Priority: 1,
+	}
+}
+
+// 加载科目固定节次约束
+func loadSubjectPeriodConsistencyConstraintsFromDB() []*SubjectPeriodConsistency {
+	var constraints []*SubjectPeriodConsistency
+	return constraints
+}
+
+// 生成规则校验方法
+func (s *SubjectPeriodConsistency) genConstraintFn() types.ConstraintFn {
+	return func(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
+
+		preCheckPassed := s.isMatched(element)
+		if !preCheckPassed {
+			return false, false, nil
+		}
+
+		// 课班已排课的节次, 不包括当前元素
+		// key: 节次, value: 次数
+		elementTimeSlotStr := utils.TimeSlotsToStr(element.TimeSlots)
+		elementPeriod := schedule.GetTimeSlot(element.TimeSlots[0]).Period
+		periodCount := make(map[int]int)
+		for _, venueMap := range classMatrix.Elements[element.ClassSN] {
+			for _, timeSlotMap := range venueMap {
+				for timeSlotStr, e := range timeSlotMap {
+					if e.Val.Used == 1 && timeSlotStr != elementTimeSlotStr {
+						periodCount[schedule.GetTimeSlot(e.TimeSlots[0]).Period]++
+					}
+				}
+			}
+		}
+
+		isReward := s.isConsistentPeriod(elementPeriod, periodCount)
+		return preCheckPassed, isReward, nil
+	}
+}
+
+// 判断节次是否和已排课的节次一致
+// 已排课的节次数还没有达到最多节次数时, 排在任意节次都可以
+// 否则需要排在已排课次数最多的几个节次, 次数和第MaxDistinctPeriods个节次相同的也可以
+func (s *SubjectPeriodConsistency) isConsistentPeriod(period int, periodCount map[int]int) bool {
+
+	maxDistinctPeriods := lo.Max([]int{s.MaxDistinctPeriods, 1})
+	if len(periodCount) < maxDistinctPeriods {
+		return true
+	}
+
+	counts := lo.Values(periodCount)
+	sort.Sort(sort.Reverse(sort.IntSlice(counts)))
+	return periodCount[period] > 0 && periodCount[period] >= counts[maxDistinctPeriods-1]
+}
+
+// 判断元素是否在约束范围内
+func (s *SubjectPeriodConsistency) isMatched(element types.Element) bool {
+	return element.SubjectID == s.SubjectID && (s.GradeID == 0 || s.GradeID == element.GradeID) && (s.ClassID == 0 || s.ClassID == element.ClassID)
+}
+
+// 判断课班是否受科目固定节次约束
+// 计算科目分散度时, 这些课班排在相同的节次不处罚
+func IsSubjectPeriodConsistencyMatched(subjectID, gradeID, classID int, constraints []*SubjectPeriodConsistency) bool {
+	return lo.ContainsBy(constraints, func(s *SubjectPeriodConsistency) bool {
+		return s.SubjectID == subjectID && (s.GradeID == 0 || s.GradeID == gradeID) && (s.ClassID == 0 || s.ClassID == classID)
+	})
+}
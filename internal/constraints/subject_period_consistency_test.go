@@ -0,0 +1,69 @@
+package constraints
+
+import (
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/types"
+	"testing"
+)
+
+func TestSubjectPeriodConsistencyRules(t *testing.T) {
+
+	// 时间段 = 天*8+节次, 节次从0开始
+	schedule := newTestSchedule()
+	teachers := []*models.Teacher{{TeacherID: 1}}
+
+	tests := []struct {
+		name       string
+		constraint *SubjectPeriodConsistency
+		used       [][]int // 已经排课的时间段
+		timeSlots  []int
+		want       string
+	}{
+		{"first lesson", &SubjectPeriodConsistency{SubjectID: 1}, nil, []int{5}, passed},
+		{"same period", &SubjectPeriodConsistency{SubjectID: 1}, [][]int{{3}}, []int{11}, passed},
+		// 已经有一节课排在第4节, 当前元素排在第2节不能因为次数相同而奖励
+		{"lower period tie", &SubjectPeriodConsistency{SubjectID: 1}, [][]int{{3}}, []int{9}, failed},
+		{"higher period", &SubjectPeriodConsistency{SubjectID: 1}, [][]int{{1}}, []int{11}, failed},
+		{"most frequent period", &SubjectPeriodConsistency{SubjectID: 1}, [][]int{{3}, {11}, {17}}, []int{27}, passed},
+		{"less frequent period", &SubjectPeriodConsistency{SubjectID: 1}, [][]int{{3}, {11}, {17}}, []int{25}, failed},
+		// 已排课的节次次数相同时, 都可以
+		{"tied existing periods", &SubjectPeriodConsistency{SubjectID: 1}, [][]int{{1}, {11}}, []int{19}, passed},
+		{"tied existing periods lower", &SubjectPeriodConsistency{SubjectID: 1}, [][]int{{1}, {11}}, []int{17}, passed},
+		{"tied existing periods other", &SubjectPeriodConsistency{SubjectID: 1}, [][]int{{1}, {11}}, []int{16}, failed},
+
+		{"max distinct periods room", &SubjectPeriodConsistency{SubjectID: 1, MaxDistinctPeriods: 2}, [][]int{{3}}, []int{8}, passed},
+		{"max distinct periods full", &SubjectPeriodConsistency{SubjectID: 1, MaxDistinctPeriods: 2}, [][]int{{3}, {8}}, []int{21}, failed},
+		{"max distinct periods second", &SubjectPeriodConsistency{SubjectID: 1, MaxDistinctPeriods: 2}, [][]int{{3}, {11}, {8}}, []int{16}, passed},
+		// 连堂课按照第一节的节次计算
+		{"connected", &SubjectPeriodConsistency{SubjectID: 1}, [][]int{{1, 2}}, []int{9, 10}, passed},
+
+		{"other subject", &SubjectPeriodConsistency{SubjectID: 2}, [][]int{{3}}, []int{9}, skipped},
+		{"other class", &SubjectPeriodConsistency{GradeID: 1, ClassID: 2, SubjectID: 1}, [][]int{{3}}, []int{9}, skipped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var used []*types.Element
+			for _, timeSlots := range tt.used {
+				used = append(used, newTestElement(t, "1_1_1", 1, 101, timeSlots...))
+			}
+			cm := newTestClassMatrix(teachers, used...)
+
+			rules := GetSubjectPeriodConsistencyRules([]*SubjectPeriodConsistency{tt.constraint})
+			element := newTestElement(t, "1_1_1", 1, 101, tt.timeSlots...)
+			if got := checkRule(t, rules[0], cm, element, schedule); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// 已经排课的元素计算得分时, 不重复计算自己
+	first := newTestElement(t, "1_1_1", 1, 101, 3)
+	second := newTestElement(t, "1_1_1", 1, 101, 9)
+	cm := newTestClassMatrix(teachers, first, second)
+	rule := GetSubjectPeriodConsistencyRules([]*SubjectPeriodConsistency{{SubjectID: 1}})[0]
+	if got := checkRule(t, rule, cm, second, schedule); got != failed {
+		t.Errorf("used element: got %s, want %s", got, failed)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"course_scheduler/internal/models"
 	"course_scheduler/internal/types"
 	"course_scheduler/internal/utils"
+
+	"github.com/samber/lo"
 )
 
 // #### 同一个年级,班级,科目相同节次的排课是否超过数量限制
@@ -22,10 +24,31 @@ var subjectPeriodLimitRule = &types.Rule{
 	Priority: 1,
 }
 
+// 获取相同节次排课数量限制规则
+// 有科目固定节次约束的课班, 需要排在相同的节次, 不再受该规则限制
+func getSubjectPeriodLimitRule(consistencies []*SubjectPeriodConsistency) *types.Rule {
+
+	if len(consistencies) == 0 {
+		return subjectPeriodLimitRule
+	}
+
+	rule := *subjectPeriodLimitRule
+	rule.Fn = func(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
+
+		isExempt := lo.ContainsBy(consistencies, func(c *SubjectPeriodConsistency) bool {
+			return c.isMatched(element)
+		})
+		if isExempt {
+			return false, false, nil
+		}
+		return splRuleFn(classMatrix, element, schedule, teachingTasks)
+	}
+	return &rule
+}
+
 // 相同节次的排课是否超过数量限制
 func splRuleFn(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
 
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
 	classSN := element.GetClassSN()
 	teacherID := element.GetTeacherID()
 	venueID := element.GetVenueID()
@@ -39,7 +62,7 @@ func splRuleFn(classMatrix *types.ClassMatrix, element types.Element, schedule *
 
 	for _, timeSlot := range timeSlots {
 
-		period := timeSlot % totalClassesPerDay
+		period := schedule.GetTimeSlot(timeSlot).Period
 		count, preCheckPassed = periodCount[period]
 
 		if preCheckPassed {
@@ -57,7 +80,6 @@ func splRuleFn(classMatrix *types.ClassMatrix, element types.Element, schedule *
 // countPeriodClasses 计算每个时间段的科目数量
 func countPeriodClasses(classMatrix *types.ClassMatrix, sn string, teacherID, venueID int, schedule *models.Schedule) map[int]int {
 
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
 	// key: 节次, val: 数量
 	periodCount := make(map[int]int)
 
@@ -66,7 +88,7 @@ func countPeriodClasses(classMatrix *types.ClassMatrix, sn string, teacherID, ve
 		timeSlots := utils.ParseTimeSlotStr(timeSlotStr)
 		for _, timeSlot := range timeSlots {
 			if element.Val.Used == 1 {
-				period := timeSlot % totalClassesPerDay
+				period := schedule.GetTimeSlot(timeSlot).Period
 				periodCount[period]++
 			}
 		}
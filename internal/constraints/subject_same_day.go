@@ -40,7 +40,10 @@ func ssdRuleFn(classMatrix *types.ClassMatrix, element types.Element, schedule *
 	// 科目周课时
 	total := models.GetNumClassesPerWeek(gradeID, classID, subjectID, teachingTasks)
 	connectedCount := models.GetNumConnectedClassesPerWeek(gradeID, classID, subjectID, teachingTasks)
-	count := total - connectedCount
+	connectedLength := models.GetConnectedLength(gradeID, classID, subjectID, teachingTasks)
+
+	// 每周上课次数, 一次连堂课算一次
+	count := total - connectedCount*(connectedLength-1)
 
 	preCheckPassed := count <= numWorkdays
 
@@ -56,9 +59,8 @@ func ssdRuleFn(classMatrix *types.ClassMatrix, element types.Element, schedule *
 // 检查同一科目是否在同一天已经排课
 func isSubjectSameDay(classMatrix *types.ClassMatrix, sn string, timeSlots []int, schedule *models.Schedule) bool {
 
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
 	count := 0
-	day := timeSlots[0] / totalClassesPerDay
+	day := schedule.GetTimeSlot(timeSlots[0]).Day
 
 	for _, teacherMap := range classMatrix.Elements[sn] {
 		for _, venueMap := range teacherMap {
@@ -68,7 +70,7 @@ func isSubjectSameDay(classMatrix *types.ClassMatrix, sn string, timeSlots []int
 				intersect := lo.Intersect(timeSlots, timeSlots1)
 
 				if e.Val.Used == 1 && len(intersect) == 0 {
-					day1 := timeSlots1[0] / totalClassesPerDay
+					day1 := schedule.GetTimeSlot(timeSlots1[0]).Day
 					if day == day1 {
 						count++
 					}
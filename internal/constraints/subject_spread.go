@@ -0,0 +1,159 @@
+// subject_spread.go
+// 科目分布限制
+
+package constraints
+
+import (
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/types"
+	"course_scheduler/internal/utils"
+	"fmt"
+	"sort"
+
+	"github.com/samber/lo"
+)
+
+// ###### 科目分布限制
+// 根据班级一周的排课情况, 限制科目在一周内的分布
+
+// | 年级   | 班级 | 科目 | 最少间隔天数 | 最少上课天数 | 禁止的上课日组合 | 描述                     |
+// | ------ | ---- | ---- | ------------ | ------------ | ---------------- | ------------------------ |
+// |        |      | 数学 |              | 5            |                  | 数学5节课排在5天         |
+// | 一年级 |      | 道法 | 2            |              |                  | 两节道法至少间隔2天      |
+// |        |      | 美术 |              |              | 星期一, 星期五   | 不能只排在星期一和星期五 |
+
+// 年级, 班级为空表示对所有班级生效
+// 一次连堂课算作一次上课
+type SubjectSpread struct {
+	ID                   int     `json:"id" mapstructure:"id"`                                         // 自增ID
+	GradeID              int     `json:"grade_id" mapstructure:"grade_id"`                             // 年级ID, 可以为空
+	ClassID              int     `json:"class_id" mapstructure:"class_id"`                             // 班级ID, 可以为空
+	SubjectID            int     `json:"subject_id" mapstructure:"subject_id"`                         // 科目ID
+	MinGapDays           int     `json:"min_gap_days" mapstructure:"min_gap_days"`                     // 相邻两次上课最少间隔天数, 如: 2 表示星期一上课后, 最早星期三再上课
+	MinDistinctDays      int     `json:"min_distinct_days" mapstructure:"min_distinct_days"`           // 最少上课天数
+	ForbiddenDayPatterns [][]int `json:"forbidden_day_patterns" mapstructure:"forbidden_day_patterns"` // 禁止的上课日组合, 如: [[1, 5]] 表示不能只排在星期一和星期五
+	Desc                 string  `json:"desc" mapstructure:"desc"`                                     // 描述
+}
+
+// 生成字符串
+func (s *SubjectSpread) String() string {
+	return fmt.Sprintf("ID: %d, GradeID: %d, ClassID: %d, SubjectID: %d, MinGapDays: %d, MinDistinctDays: %d, ForbiddenDayPatterns: %v, Desc: %s",
+		s.ID, s.GradeID, s.ClassID, s.SubjectID, s.MinGapDays, s.MinDistinctDays, s.ForbiddenDayPatterns, s.Desc)
+}
+
+// 获取规则
+func GetSubjectSpreadRules(constraints []*SubjectSpread) []*types.Rule {
+	// constraints := loadSubjectSpreadConstraintsFromDB()
+	var rules []*types.Rule
+	for _, c := range constraints {
+		rule := c.genRule()
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// 生成规则
+func (s *SubjectSpread) genRule() *types.Rule {
+	fn := s.genConstraintFn()
+	return &types.Rule{
+		Name:     "subjectSpread",
+		Type:     "dynamic",
+		Fn:       fn,
+		Score:    2,
+		Penalty:  6,
+		Weight:   1,
+		Priority: 1,
+	}
+}
+
+// 加载科目分布限制
+func loadSubjectSpreadConstraintsFromDB() []*SubjectSpread {
+	var constraints []*SubjectSpread
+	return constraints
+}
+
+// 生成规则校验方法
+func (s *SubjectSpread) genConstraintFn() types.ConstraintFn {
+	return func(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
+
+		preCheckPassed := element.SubjectID == s.SubjectID && (s.GradeID == 0 || s.GradeID == element.GradeID) && (s.ClassID == 0 || s.ClassID == element.ClassID)
+		if !preCheckPassed {
+			return false, false, nil
+		}
+
+		// 每周上课次数, 一次连堂课算一次
+		total := models.GetNumClassesPerWeek(element.GradeID, element.ClassID, element.SubjectID, teachingTasks)
+		connectedCount := models.GetNumConnectedClassesPerWeek(element.GradeID, element.ClassID, element.SubjectID, teachingTasks)
+		connectedLength := models.GetConnectedLength(element.GradeID, element.ClassID, element.SubjectID, teachingTasks)
+		count := total - connectedCount*(connectedLength-1)
+
+		days := classSubjectLessonDays(classMatrix, element, schedule)
+		shouldPenalize := s.isGapTooSmall(days) || s.isDistinctDaysTooFew(days, count) || s.isForbiddenPattern(days, count)
+		return preCheckPassed, !shouldPenalize, nil
+	}
+}
+
+// 相邻两次上课的间隔天数是否小于最少间隔天数
+func (s *SubjectSpread) isGapTooSmall(days []int) bool {
+
+	if s.MinGapDays <= 0 {
+		return false
+	}
+
+	for i := 1; i < len(days); i++ {
+		if days[i]-days[i-1] < s.MinGapDays {
+			return true
+		}
+	}
+	return false
+}
+
+// 剩余的课全部排在不同的天, 上课天数也达不到最少上课天数
+func (s *SubjectSpread) isDistinctDaysTooFew(days []int, count int) bool {
+
+	if s.MinDistinctDays <= 0 {
+		return false
+	}
+
+	remaining := lo.Max([]int{count - len(days), 0})
+	return len(lo.Uniq(days))+remaining < s.MinDistinctDays
+}
+
+// 全部课排完后, 上课日是否是禁止的组合
+func (s *SubjectSpread) isForbiddenPattern(days []int, count int) bool {
+
+	if len(s.ForbiddenDayPatterns) == 0 || len(days) < count {
+		return false
+	}
+
+	// 上课日, 周几从1开始
+	weekdays := lo.Map(lo.Uniq(days), func(day int, _ int) int {
+		return day + 1
+	})
+
+	return lo.ContainsBy(s.ForbiddenDayPatterns, func(pattern []int) bool {
+		return lo.Every(pattern, weekdays) && len(lo.Uniq(pattern)) == len(weekdays)
+	})
+}
+
+// 获取课班一周每次上课所在的天, 按照先后顺序排列
+// 此时是假设当前元素会排课, 所以需要将当前元素也计算在内
+func classSubjectLessonDays(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule) []int {
+
+	elementTimeSlotStr := utils.TimeSlotsToStr(element.TimeSlots)
+
+	var days []int
+	for _, venueMap := range classMatrix.Elements[element.ClassSN] {
+		for _, timeSlotMap := range venueMap {
+			for timeSlotStr, e := range timeSlotMap {
+				if e.Val.Used == 1 && timeSlotStr != elementTimeSlotStr {
+					days = append(days, schedule.GetTimeSlot(e.TimeSlots[0]).Day)
+				}
+			}
+		}
+	}
+
+	days = append(days, schedule.GetTimeSlot(element.TimeSlots[0]).Day)
+	sort.Ints(days)
+	return days
+}
@@ -0,0 +1,70 @@
+package constraints
+
+import (
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/types"
+	"fmt"
+	"testing"
+)
+
+func TestSubjectSpreadRules(t *testing.T) {
+
+	// 时间段 = 天*8+节次, 星期一是第0天
+	schedule := newTestSchedule()
+	teachers := []*models.Teacher{{TeacherID: 1}}
+
+	tests := []struct {
+		name         string
+		constraint   *SubjectSpread
+		numClasses   int     // 每周节数
+		numConnected int     // 每周连堂课次数
+		used         [][]int // 已经排课的时间段
+		subjectID    int
+		timeSlots    []int
+		want         string
+	}{
+		{"gap too small", &SubjectSpread{SubjectID: 1, MinGapDays: 2}, 3, 0, [][]int{{0}}, 1, []int{8}, failed},
+		{"gap ok", &SubjectSpread{SubjectID: 1, MinGapDays: 2}, 3, 0, [][]int{{0}}, 1, []int{16}, passed},
+		{"gap between used", &SubjectSpread{SubjectID: 1, MinGapDays: 2}, 3, 0, [][]int{{0}, {32}}, 1, []int{16}, passed},
+		{"gap same day", &SubjectSpread{SubjectID: 1, MinGapDays: 1}, 3, 0, [][]int{{0}}, 1, []int{1}, failed},
+
+		// 剩余的课全部排在不同的天也达不到最少上课天数
+		{"distinct days too few", &SubjectSpread{SubjectID: 1, MinDistinctDays: 3}, 3, 0, [][]int{{0}}, 1, []int{1}, failed},
+		{"distinct days ok", &SubjectSpread{SubjectID: 1, MinDistinctDays: 3}, 3, 0, [][]int{{0}}, 1, []int{8}, passed},
+		// 4节课, 其中1次连堂课, 一周上课3次
+		{"distinct days connected", &SubjectSpread{SubjectID: 1, MinDistinctDays: 3}, 4, 1, [][]int{{0, 1}}, 1, []int{2}, failed},
+		{"distinct days connected ok", &SubjectSpread{SubjectID: 1, MinDistinctDays: 3}, 4, 1, [][]int{{0, 1}}, 1, []int{8}, passed},
+
+		// 星期一, 星期五
+		{"forbidden pattern", &SubjectSpread{SubjectID: 1, ForbiddenDayPatterns: [][]int{{1, 5}}}, 2, 0, [][]int{{0}}, 1, []int{32}, failed},
+		{"forbidden pattern other days", &SubjectSpread{SubjectID: 1, ForbiddenDayPatterns: [][]int{{1, 5}}}, 2, 0, [][]int{{0}}, 1, []int{16}, passed},
+		{"forbidden pattern not finished", &SubjectSpread{SubjectID: 1, ForbiddenDayPatterns: [][]int{{1, 5}}}, 3, 0, [][]int{{0}}, 1, []int{32}, passed},
+		{"forbidden pattern more days", &SubjectSpread{SubjectID: 1, ForbiddenDayPatterns: [][]int{{1, 5}}}, 3, 0, [][]int{{0}, {16}}, 1, []int{32}, passed},
+		{"forbidden pattern same day twice", &SubjectSpread{SubjectID: 1, ForbiddenDayPatterns: [][]int{{1, 5}}}, 3, 0, [][]int{{0}, {1}}, 1, []int{32}, failed},
+
+		{"other subject", &SubjectSpread{SubjectID: 1, MinGapDays: 2}, 3, 0, nil, 2, []int{8}, skipped},
+		{"other grade", &SubjectSpread{GradeID: 2, SubjectID: 1, MinGapDays: 2}, 3, 0, [][]int{{0}}, 1, []int{8}, skipped},
+		{"other class", &SubjectSpread{GradeID: 1, ClassID: 2, SubjectID: 1, MinGapDays: 2}, 3, 0, [][]int{{0}}, 1, []int{8}, skipped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var used []*types.Element
+			for _, timeSlots := range tt.used {
+				used = append(used, newTestElement(t, "1_1_1", 1, 101, timeSlots...))
+			}
+			cm := newTestClassMatrix(teachers, used...)
+
+			teachingTasks := []*models.TeachingTask{
+				{GradeID: 1, ClassID: 1, SubjectID: 1, TeacherID: 1, NumClassesPerWeek: tt.numClasses, NumConnectedClassesPerWeek: tt.numConnected},
+			}
+
+			rules := GetSubjectSpreadRules([]*SubjectSpread{tt.constraint})
+			element := newTestElement(t, fmt.Sprintf("%d_1_1", tt.subjectID), 1, 101, tt.timeSlots...)
+			if got := checkRuleWithTasks(t, rules[0], cm, element, schedule, teachingTasks); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
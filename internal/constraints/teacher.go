@@ -41,6 +41,7 @@ func (t *Teacher) String() string {
 }
 
 // 获取班级固排禁排规则
+// 教师分组的约束只生成一条规则, 规则中判断教师是否属于分组, 分组的大小不影响得分
 func GetTeacherRules(teachers []*models.Teacher, constraints []*Teacher) []*types.Rule {
 	// constraints := loadTeacherConstraintsFromDB()
 	var rules []*types.Rule
@@ -75,11 +76,8 @@ func loadTeacherConstraintsFromDB() []*Teacher {
 func (t *Teacher) genConstraintFn(teachers []*models.Teacher) types.ConstraintFn {
 	return func(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
 
-		teacherGroupID := t.TeacherGroupID
-		teacherID := t.TeacherID
 		currTeacherID := element.GetTeacherID()
-		currTeacher, err := models.FindTeacherByID(currTeacherID, teachers)
-		if err != nil {
+		if _, err := models.FindTeacherByID(currTeacherID, teachers); err != nil {
 			return false, false, err
 		}
 
@@ -93,12 +91,16 @@ func (t *Teacher) genConstraintFn(teachers []*models.Teacher) types.ConstraintFn
 		// 固排,优先排是: 排了有奖励,不排有处罚
 		if t.Limit == "fixed" || t.Limit == "prefer" {
 			preCheckPassed = isContain
-			isReward = preCheckPassed && (teacherGroupID == 0 || lo.Contains(currTeacher.TeacherGroupIDs, teacherGroupID)) && (teacherID == 0 || teacherID == currTeacherID)
+			isReward = preCheckPassed && t.isTeacherMatched(currTeacherID, teachers)
 		}
 
 		// 禁排,尽量不排是: 不排没关系, 排了就处罚
+		// 协同上课的教师, 也不能排在禁排时间
 		if t.Limit == "not" || t.Limit == "avoid" {
-			preCheckPassed = isContain && (teacherGroupID == 0 || lo.Contains(currTeacher.TeacherGroupIDs, teacherGroupID)) && (teacherID == 0 || teacherID == currTeacherID)
+			isTeacherMatched := lo.ContainsBy(element.GetTeacherIDs(), func(id int) bool {
+				return t.isTeacherMatched(id, teachers)
+			})
+			preCheckPassed = isContain && isTeacherMatched
 			isReward = false
 		}
 		return preCheckPassed, isReward, nil
@@ -131,18 +133,23 @@ func (t *Teacher) getPenalty() int {
 func GetTeacherNotTimeSlots(teacherID int, teachers []*models.Teacher, constraints []*Teacher) ([]int, error) {
 
 	var timeSlots []int
-	teacher, err := models.FindTeacherByID(teacherID, teachers)
+	_, err := models.FindTeacherByID(teacherID, teachers)
 	if err != nil {
 		return nil, err
 	}
 
-	teacherGroupIDs := teacher.TeacherGroupIDs
 	for _, constraint := range constraints {
 		// 禁排
-		if constraint.Limit == "not" && (constraint.TeacherID == teacherID || lo.Contains(teacherGroupIDs, constraint.TeacherGroupID)) {
+		if constraint.Limit == "not" && constraint.isTeacherMatched(teacherID, teachers) {
 			timeSlots = append(timeSlots, constraint.TimeSlots...)
 		}
 	}
 
-	return timeSlots, nil
+	return lo.Uniq(timeSlots), nil
+}
+
+// 判断教师是否在约束范围内
+// 同时指定了教师分组和教师时, 两个条件都需要满足
+func (t *Teacher) isTeacherMatched(teacherID int, teachers []*models.Teacher) bool {
+	return (t.TeacherGroupID == 0 || models.IsTeacherInGroup(teacherID, t.TeacherGroupID, teachers)) && (t.TeacherID == 0 || t.TeacherID == teacherID)
 }
@@ -7,23 +7,29 @@ import (
 	"course_scheduler/internal/types"
 	"course_scheduler/internal/utils"
 	"fmt"
+
+	"github.com/samber/lo"
 )
 
 // | 教师 A | 教师 B |
 // | ------ | ------ |
 // | 张三   | 李四   |
 // | 王五   | 赵六   |
+// | 语文组 | 数学组 |
 
 // 教师互斥，教师 A, 教师 B不同时上课
+// 教师A(B)也可以是教师分组, 此时分组内的所有教师都受约束
 type TeacherMutex struct {
-	ID         int `json:"id" mapstructure:"id"`                     // 自增ID
-	TeacherAID int `json:"teacher_a_id" mapstructure:"teacher_a_id"` // Teacher A's ID
-	TeacherBID int `json:"teacher_b_id" mapstructure:"teacher_b_id"` // Teacher B's ID
+	ID              int `json:"id" mapstructure:"id"`                                 // 自增ID
+	TeacherAID      int `json:"teacher_a_id" mapstructure:"teacher_a_id"`             // Teacher A's ID
+	TeacherBID      int `json:"teacher_b_id" mapstructure:"teacher_b_id"`             // Teacher B's ID
+	TeacherAGroupID int `json:"teacher_a_group_id" mapstructure:"teacher_a_group_id"` // Teacher A's group ID
+	TeacherBGroupID int `json:"teacher_b_group_id" mapstructure:"teacher_b_group_id"` // Teacher B's group ID
 }
 
 // 生成字符串
 func (t *TeacherMutex) String() string {
-	return fmt.Sprintf("ID: %d, TeacherAID: %d, TeacherBID: %d", t.ID, t.TeacherAID, t.TeacherBID)
+	return fmt.Sprintf("ID: %d, TeacherAID: %d, TeacherBID: %d, TeacherAGroupID: %d, TeacherBGroupID: %d", t.ID, t.TeacherAID, t.TeacherBID, t.TeacherAGroupID, t.TeacherBGroupID)
 }
 
 // 获取班级固排禁排规则
@@ -62,61 +68,81 @@ func (t *TeacherMutex) genConstraintFn() types.ConstraintFn {
 
 	return func(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
 
-		teacherAID := t.TeacherAID
-		teacherBID := t.TeacherBID
+		// 教师A, 教师B展开后的教师集合
+		teacherAIDs := mutexTeacherIDs(t.TeacherAID, t.TeacherAGroupID, classMatrix.Teachers)
+		teacherBIDs := mutexTeacherIDs(t.TeacherBID, t.TeacherBGroupID, classMatrix.Teachers)
 
-		teacherID := element.GetTeacherID()
+		// 协同上课的教师也受约束
+		teacherIDs := element.GetTeacherIDs()
 
-		preCheckPassed := teacherID == teacherAID || teacherID == teacherBID
+		preCheckPassed := len(lo.Intersect(teacherAIDs, teacherIDs)) > 0 || len(lo.Intersect(teacherBIDs, teacherIDs)) > 0
 
 		shouldPenalize := false
 		if preCheckPassed {
-			shouldPenalize = isElementTeacherOnSameDay(teacherAID, teacherBID, classMatrix, element, schedule)
+			shouldPenalize = isElementTeacherOnSameDay(teacherAIDs, teacherBIDs, classMatrix, element, schedule)
 		}
 
 		return preCheckPassed, !shouldPenalize, nil
 	}
 }
 
+// 互斥的教师集合
+// 指定了教师时, 只包含该教师, 否则包含教师分组内的所有教师
+func mutexTeacherIDs(teacherID, teacherGroupID int, teachers []*models.Teacher) []int {
+
+	if teacherID > 0 {
+		return []int{teacherID}
+	}
+
+	if teacherGroupID > 0 {
+		return models.GroupTeacherIDs(teacherGroupID, teachers)
+	}
+	return nil
+}
+
 // 判断教师A,教师B是否同一天都有课
-func isElementTeacherOnSameDay(teacherAID, teacherBID int, classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule) bool {
+// 课程的上课教师和协同上课教师都计算在内
+func isElementTeacherOnSameDay(teacherAIDs, teacherBIDs []int, classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule) bool {
 
 	teacherADays := make(map[int]bool)
 	teacherBDays := make(map[int]bool)
 
-	onSameDay := false
-
 	timeSlots := element.GetTimeSlots()
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
 
-	elementDay := timeSlots[0] / totalClassesPerDay
+	elementDay := schedule.GetTimeSlot(timeSlots[0]).Day
+	elementTeacherIDs := element.GetTeacherIDs()
 
 	for _, classMap := range classMatrix.Elements {
-		for id, teacherMap := range classMap {
-			if id == teacherAID {
-				for _, timeSlotMap := range teacherMap {
-					for timeSlotStr, element := range timeSlotMap {
-						if element.Val.Used == 1 {
-
-							timeSlots1 := utils.ParseTimeSlotStr(timeSlotStr)
-							for _, timeSlot := range timeSlots1 {
-								day := timeSlot / totalClassesPerDay
-								teacherADays[day] = true // 将时间段转换为天数
-							}
+		for _, teacherMap := range classMap {
+			for _, timeSlotMap := range teacherMap {
+				for timeSlotStr, e := range timeSlotMap {
+					if e.Val.Used != 1 {
+						continue
+					}
 
-						}
+					var days []int
+					for _, timeSlot := range utils.ParseTimeSlotStr(timeSlotStr) {
+						days = append(days, schedule.GetTimeSlot(timeSlot).Day) // 将时间段转换为天数
 					}
-				}
-			} else if id == teacherBID {
-				for _, timeSlotMap := range teacherMap {
-					for timeSlotStr, element := range timeSlotMap {
-						if element.Val.Used == 1 {
-
-							timeSlots1 := utils.ParseTimeSlotStr(timeSlotStr)
-							for _, timeSlot := range timeSlots1 {
-								day := timeSlot / totalClassesPerDay
-								teacherBDays[day] = true // 将时间段转换为天数
-							}
+
+					for _, id := range e.GetTeacherIDs() {
+
+						// 教师本人的排课不计算在内
+						if lo.Contains(elementTeacherIDs, id) {
+							continue
+						}
+
+						var teacherDays map[int]bool
+						if lo.Contains(teacherAIDs, id) {
+							teacherDays = teacherADays
+						} else if lo.Contains(teacherBIDs, id) {
+							teacherDays = teacherBDays
+						} else {
+							continue
+						}
+
+						for _, day := range days {
+							teacherDays[day] = true
 						}
 					}
 				}
@@ -124,15 +150,14 @@ func isElementTeacherOnSameDay(teacherAID, teacherBID int, classMatrix *types.Cl
 		}
 	}
 
-	if element.TeacherID == teacherAID {
+	onSameDay := false
+	if len(lo.Intersect(teacherAIDs, elementTeacherIDs)) > 0 {
 		onSameDay = teacherBDays[elementDay]
 	}
 
-	if element.TeacherID == teacherBID {
-		onSameDay = teacherADays[elementDay]
+	if len(lo.Intersect(teacherBIDs, elementTeacherIDs)) > 0 {
+		onSameDay = onSameDay || teacherADays[elementDay]
 	}
 
-	// log.Printf("teacher mutex, element.timeSlots: %v, element.TeacherID: %d, teacherAID: %d, teacherBID: %d, elementDay: %d, onSameDay: %v\n", element.TimeSlots, element.TeacherID, teacherAID, teacherBID, elementDay, onSameDay)
-
 	return onSameDay
 }
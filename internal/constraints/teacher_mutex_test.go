@@ -0,0 +1,49 @@
+package constraints
+
+import (
+	"course_scheduler/internal/models"
+	"testing"
+)
+
+// 协同上课的教师也受互斥约束
+func TestTeacherMutexCoTeacher(t *testing.T) {
+
+	schedule := newTestSchedule()
+	teachers := []*models.Teacher{{TeacherID: 1}, {TeacherID: 2}, {TeacherID: 3}}
+	rules := GetTeacherMutexRules([]*TeacherMutex{{TeacherAID: 1, TeacherBID: 2}})
+
+	// 教师2星期一第1节有课
+	cm := newTestClassMatrix(teachers, newTestElement(t, "2_1_2", 2, 102, 0))
+
+	// 教师3的课, 教师1协同上课
+	element := newTestElement(t, "1_1_1", 3, 101, 2)
+	if got := checkRule(t, rules[0], cm, element, schedule); got != skipped {
+		t.Errorf("without co-teacher: got %s, want %s", got, skipped)
+	}
+
+	element.CoTeacherIDs = []int{1}
+	if got := checkRule(t, rules[0], cm, element, schedule); got != failed {
+		t.Errorf("co-teacher same day: got %s, want %s", got, failed)
+	}
+
+	element = newTestElement(t, "1_1_1", 3, 101, 8)
+	element.CoTeacherIDs = []int{1}
+	if got := checkRule(t, rules[0], cm, element, schedule); got != passed {
+		t.Errorf("co-teacher other day: got %s, want %s", got, passed)
+	}
+
+	// 教师2星期二协同上课
+	coTaught := newTestElement(t, "2_1_2", 3, 102, 9)
+	coTaught.CoTeacherIDs = []int{2}
+	cm = newTestClassMatrix(teachers, coTaught)
+
+	element = newTestElement(t, "1_1_1", 1, 101, 10)
+	if got := checkRule(t, rules[0], cm, element, schedule); got != failed {
+		t.Errorf("scheduled co-teacher same day: got %s, want %s", got, failed)
+	}
+
+	element = newTestElement(t, "1_1_1", 1, 101, 0)
+	if got := checkRule(t, rules[0], cm, element, schedule); got != passed {
+		t.Errorf("scheduled co-teacher other day: got %s, want %s", got, passed)
+	}
+}
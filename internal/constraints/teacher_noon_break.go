@@ -91,8 +91,7 @@ func (t *TeacherNoonBreak) genConstraintFn() types.ConstraintFn {
 // 判断如果给当前元素的排课,是否会出现特定教师跨上午,下午排课
 func isTeacherInBothPeriods(element types.Element, teacherID int, forenoonEndPeriod, afternoonStartPeriod int, classMatrix *types.ClassMatrix, schedule *models.Schedule) bool {
 
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
-	elementDay := element.TimeSlots[0] / totalClassesPerDay
+	elementDay := schedule.GetTimeSlot(element.TimeSlots[0]).Day
 	dayPeriodCount := calcTeacherDayClasses(classMatrix, teacherID, schedule)
 
 	// 判断元素所在的天,是否已经有排课
@@ -118,25 +117,24 @@ func isTeacherInBothPeriods(element types.Element, teacherID int, forenoonEndPer
 // countTeacherPeriodClasses 计算老师目前每天的排课节数列表
 func calcTeacherDayClasses(classMatrix *types.ClassMatrix, teacherID int, schedule *models.Schedule) map[int][]int {
 
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
 	// key: 天, val: 当前排课的节次列表
 	dayPeriodCount := make(map[int][]int)
 
 	// key: [课班(科目_年级_班级)][教师][教室][时间段], value: Element
+	// 教师作为协同上课教师的课程也计算在内
 	for _, teacherMap := range classMatrix.Elements {
-		for id, venueMap := range teacherMap {
-			if teacherID == id {
-				for _, timeSlotMap := range venueMap {
-					for timeSlotStr, element := range timeSlotMap {
-
-						timeSlots := utils.ParseTimeSlotStr(timeSlotStr)
-						for _, timeSlot := range timeSlots {
-							if element.Val.Used == 1 {
-								period := timeSlot % totalClassesPerDay
-								day := timeSlot / totalClassesPerDay
-								dayPeriodCount[day] = append(dayPeriodCount[day], period)
-							}
-						}
+		for _, venueMap := range teacherMap {
+			for _, timeSlotMap := range venueMap {
+				for timeSlotStr, element := range timeSlotMap {
+
+					if element.Val.Used != 1 || !lo.Contains(element.GetTeacherIDs(), teacherID) {
+						continue
+					}
+
+					timeSlots := utils.ParseTimeSlotStr(timeSlotStr)
+					for _, timeSlot := range timeSlots {
+						ts := schedule.GetTimeSlot(timeSlot)
+						dayPeriodCount[ts.Day] = append(dayPeriodCount[ts.Day], ts.Period)
 					}
 				}
 			}
@@ -98,7 +98,6 @@ func (t *TeacherPeriodLimit) genConstraintFn() types.ConstraintFn {
 // 计算教师某节课的上课次数
 func countTeacherClassInPeriod(teacherID int, period int, classMatrix *types.ClassMatrix, schedule *models.Schedule) int {
 
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
 	count := 0
 
 	// key: [课班(科目_年级_班级)][教师][教室][时间段], value: Element
@@ -111,7 +110,7 @@ func countTeacherClassInPeriod(teacherID int, period int, classMatrix *types.Cla
 						if element.Val.Used == 1 {
 							timeSlots := utils.ParseTimeSlotStr(timeSlotStr)
 							for _, timeSlot := range timeSlots {
-								elementPeriod := timeSlot % totalClassesPerDay
+								elementPeriod := schedule.GetTimeSlot(timeSlot).Period
 								if elementPeriod == period {
 									count++
 								}
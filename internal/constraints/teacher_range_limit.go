@@ -9,27 +9,34 @@ import (
 	"course_scheduler/internal/utils"
 	"fmt"
 	"log"
+
+	"github.com/samber/lo"
 )
 
 // ###### 教师时间段限制
 
 // | 教师   | 时间段           | 最多排课节数 |
 // | ------ | ---------------- | ------------ |
+// | 王老师 | 早读             | 1 节         |
 // | 王老师 | 上午             | 1 节         |
 // | 王老师 | 下午             | 2 节         |
 // | 王老师 | 全天(不含晚自习) | 3 节         |
 // | 王老师 | 晚自习           | 1 节         |
+// | 行政组 | 上午             | 2 节         |
+
+// 指定教师分组时, 分组内的每个教师都受约束
 
 type TeacherRangeLimit struct {
 	ID              int    `json:"id" mapstructure:"id"`                               // 自增ID
+	TeacherGroupID  int    `json:"teacher_group_id" mapstructure:"teacher_group_id"`   // 教师分组ID
 	TeacherID       int    `json:"teacher_id" mapstructure:"teacher_id"`               // 教师ID
-	Range           string `json:"range" mapstructure:"range"`                         // 时间区间 上午: forenoon, 下午: afternoon, 全天: all_day, 晚自习: night
+	Range           string `json:"range" mapstructure:"range"`                         // 时间区间 早读: morning_reading, 上午: forenoon, 下午: afternoon, 全天(不含晚自习): all_day, 晚自习: night
 	MaxClassesCount int    `json:"max_classes_count" mapstructure:"max_classes_count"` // 最多排课次数
 }
 
 // 生成字符串
 func (t *TeacherRangeLimit) String() string {
-	return fmt.Sprintf("ID: %d, TeacherID: %d, Range: %s, MaxClasses: %d", t.ID, t.TeacherID, t.Range, t.MaxClassesCount)
+	return fmt.Sprintf("ID: %d, TeacherGroupID: %d, TeacherID: %d, Range: %s, MaxClasses: %d", t.ID, t.TeacherGroupID, t.TeacherID, t.Range, t.MaxClassesCount)
 }
 
 // 获取规则
@@ -69,60 +76,78 @@ func (t *TeacherRangeLimit) genConstraintFn() types.ConstraintFn {
 
 	return func(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
 
-		totalClassesPerDay := schedule.GetTotalClassesPerDay()
 		// 规则参数
-		teacherID := t.TeacherID
 		maxClasses := t.MaxClassesCount
 
 		// 将range转为时间段的起止时间段
 		startPeriod, endPeriod := schedule.GetPeriodWithRange(t.Range)
 
-		count := countTeacherClassesInRange(teacherID, startPeriod, endPeriod, classMatrix, schedule)
-		currTeacherID := element.GetTeacherID()
+		// 协同上课的教师也受约束
+		teacherIDs := lo.Filter(element.GetTeacherIDs(), func(teacherID int, _ int) bool {
+			return t.isTeacherMatched(teacherID, classMatrix.Teachers)
+		})
 
 		isValidPeriod := false
 		currTimeSlots := element.GetTimeSlots()
 		currPeriod := -1
 		for _, currTimeSlot := range currTimeSlots {
-			currPeriod = currTimeSlot % totalClassesPerDay
+			currPeriod = schedule.GetTimeSlot(currTimeSlot).Period
 			if currPeriod >= startPeriod && currPeriod <= endPeriod {
 				isValidPeriod = true
 				break
 			}
 		}
 
-		preCheckPassed := currTeacherID == teacherID && isValidPeriod
+		preCheckPassed := len(teacherIDs) > 0 && isValidPeriod
 
+		shouldPenalize := false
 		if preCheckPassed {
-			log.Printf("element.TimeSlots: %v, currTeacherID: %d, currPeriod: %d, count: %d, isValidPeriod: %v, preCheckPassed: %v\n", element.TimeSlots, currTeacherID, currPeriod, count, isValidPeriod, preCheckPassed)
+			for _, teacherID := range teacherIDs {
+				count := countTeacherClassesInRange(teacherID, startPeriod, endPeriod, classMatrix, schedule)
+				log.Printf("element.TimeSlots: %v, teacherID: %d, currPeriod: %d, count: %d, isValidPeriod: %v, preCheckPassed: %v\n", element.TimeSlots, teacherID, currPeriod, count, isValidPeriod, preCheckPassed)
+
+				// 这里count+1是指,假设给当前节点排课count会+1
+				if count+1 > maxClasses {
+					shouldPenalize = true
+					break
+				}
+			}
 		}
-		// 这里count++是指,假设给当前节点排课count会+1
-		count++
-
-		shouldPenalize := preCheckPassed && count > maxClasses
 
 		return preCheckPassed, !shouldPenalize, nil
 	}
 }
 
+// 判断教师是否在约束范围内
+func (t *TeacherRangeLimit) isTeacherMatched(teacherID int, teachers []*models.Teacher) bool {
+
+	if t.TeacherID > 0 {
+		return t.TeacherID == teacherID
+	}
+
+	if t.TeacherGroupID > 0 {
+		return models.IsTeacherInGroup(teacherID, t.TeacherGroupID, teachers)
+	}
+	return false
+}
+
 // 统计特定教师在某个时间区间的的排课节数
 func countTeacherClassesInRange(teacherID int, startPeriod, endPeriod int, classMatrix *types.ClassMatrix, schedule *models.Schedule) int {
 
 	count := 0
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
 
 	// key: [课班(科目_年级_班级)][教师][教室][时间段], value: Element
-	// 统计矩阵内的其他元素
+	// 统计矩阵内的其他元素, 教师作为协同上课教师的课程也计算在内
 	for _, classMap := range classMatrix.Elements {
 
 		for _, teacherMap := range classMap {
 			for _, venueMap := range teacherMap {
 				for timeSlotStr, e := range venueMap {
-					if e.Val.Used == 1 && e.TeacherID == teacherID {
+					if e.Val.Used == 1 && lo.Contains(e.GetTeacherIDs(), teacherID) {
 
 						timeSlots := utils.ParseTimeSlotStr(timeSlotStr)
 						for _, timeSlot := range timeSlots {
-							period := timeSlot % totalClassesPerDay
+							period := schedule.GetTimeSlot(timeSlot).Period
 							// log.Printf("countTeacherClassesInRange, teacherID: %d, startPeriod: %d, endPeriod: %d, period: %d, count: %d\n", teacherID, startPeriod, endPeriod, period, count)
 							if period >= startPeriod && period <= endPeriod {
 								count++
@@ -0,0 +1,50 @@
+package constraints
+
+import (
+	"course_scheduler/internal/models"
+	"testing"
+)
+
+// 协同上课的教师也受时间段限制
+func TestTeacherRangeLimitCoTeacher(t *testing.T) {
+
+	schedule := newTestSchedule()
+	teachers := []*models.Teacher{{TeacherID: 1}, {TeacherID: 2}, {TeacherID: 3}}
+	rules := GetTeacherRangeLimitRules([]*TeacherRangeLimit{{TeacherID: 2, Range: "forenoon", MaxClassesCount: 1}})
+
+	// 教师2星期一上午协同上课1节
+	coTaught := newTestElement(t, "1_1_1", 1, 101, 0)
+	coTaught.CoTeacherIDs = []int{2}
+	cm := newTestClassMatrix(teachers, coTaught)
+
+	// 教师2上午的第2节课
+	element := newTestElement(t, "2_1_2", 2, 102, 1)
+	if got := checkRule(t, rules[0], cm, element, schedule); got != failed {
+		t.Errorf("scheduled co-teacher: got %s, want %s", got, failed)
+	}
+
+	// 教师3的课, 教师2协同上课
+	element = newTestElement(t, "2_1_2", 3, 102, 1)
+	if got := checkRule(t, rules[0], cm, element, schedule); got != skipped {
+		t.Errorf("without co-teacher: got %s, want %s", got, skipped)
+	}
+
+	element.CoTeacherIDs = []int{2}
+	if got := checkRule(t, rules[0], cm, element, schedule); got != failed {
+		t.Errorf("co-teacher exceeded: got %s, want %s", got, failed)
+	}
+
+	// 下午不受限制
+	element = newTestElement(t, "2_1_2", 3, 102, 5)
+	element.CoTeacherIDs = []int{2}
+	if got := checkRule(t, rules[0], cm, element, schedule); got != skipped {
+		t.Errorf("co-teacher other range: got %s, want %s", got, skipped)
+	}
+
+	// 没有其他排课时不超过限制
+	element = newTestElement(t, "2_1_2", 3, 102, 1)
+	element.CoTeacherIDs = []int{2}
+	if got := checkRule(t, rules[0], newTestClassMatrix(teachers), element, schedule); got != passed {
+		t.Errorf("co-teacher within limit: got %s, want %s", got, passed)
+	}
+}
@@ -0,0 +1,99 @@
+package constraints
+
+import (
+	"course_scheduler/internal/models"
+	"reflect"
+	"testing"
+)
+
+// 教师分组的约束只生成一条规则, 分组内的教师都受约束
+func TestTeacherGroupRules(t *testing.T) {
+
+	schedule := newTestSchedule()
+	teachers := []*models.Teacher{
+		{TeacherID: 1, TeacherGroupIDs: []int{10}},
+		{TeacherID: 2, TeacherGroupIDs: []int{10}},
+		{TeacherID: 3, TeacherGroupIDs: []int{10}},
+		{TeacherID: 4},
+	}
+	cm := newTestClassMatrix(teachers)
+
+	tests := []struct {
+		name      string
+		limit     string
+		teacherID int
+		timeSlot  int
+		want      string
+	}{
+		{"avoid member", "avoid", 2, 3, failed},
+		{"avoid not member", "avoid", 4, 3, skipped},
+		{"avoid other time slot", "avoid", 2, 4, skipped},
+		{"prefer member", "prefer", 1, 3, passed},
+		{"prefer not member", "prefer", 4, 3, failed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			constraint := &Teacher{TeacherGroupID: 10, TimeSlots: []int{3}, Limit: tt.limit}
+			rules := GetTeacherRules(teachers, []*Teacher{constraint})
+			if len(rules) != 1 {
+				t.Fatalf("got %d rules, want 1", len(rules))
+			}
+
+			element := newTestElement(t, "1_1_1", tt.teacherID, 101, tt.timeSlot)
+			if got := checkRule(t, rules[0], cm, element, schedule); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// 得分范围和分组的大小无关
+	constraintMap := map[string]interface{}{"Teacher": []*Teacher{{TeacherGroupID: 10, TimeSlots: []int{3}, Limit: "avoid"}}}
+	single := map[string]interface{}{"Teacher": []*Teacher{{TeacherID: 1, TimeSlots: []int{3}, Limit: "avoid"}}}
+	if got, want := GetElementsMinScore(schedule, nil, teachers, constraintMap), GetElementsMinScore(schedule, nil, teachers, single); got != want {
+		t.Errorf("group min score %d, want %d", got, want)
+	}
+}
+
+// 协同上课的教师也不能排在禁排时间
+func TestTeacherRulesCoTeacher(t *testing.T) {
+
+	schedule := newTestSchedule()
+	teachers := []*models.Teacher{{TeacherID: 1}, {TeacherID: 2}}
+	cm := newTestClassMatrix(teachers)
+	rules := GetTeacherRules(teachers, []*Teacher{{TeacherID: 2, TimeSlots: []int{3}, Limit: "not"}})
+
+	element := newTestElement(t, "1_1_1", 1, 101, 3)
+	if got := checkRule(t, rules[0], cm, element, schedule); got != skipped {
+		t.Errorf("without co-teacher: got %s, want %s", got, skipped)
+	}
+
+	element.CoTeacherIDs = []int{2}
+	if got := checkRule(t, rules[0], cm, element, schedule); got != failed {
+		t.Errorf("with co-teacher: got %s, want %s", got, failed)
+	}
+}
+
+func TestGetTeacherNotTimeSlots(t *testing.T) {
+
+	teachers := []*models.Teacher{
+		{TeacherID: 1, TeacherGroupIDs: []int{10}},
+		{TeacherID: 2},
+	}
+	constraints := []*Teacher{
+		{TeacherGroupID: 10, TimeSlots: []int{3, 4}, Limit: "not"},
+		{TeacherID: 1, TimeSlots: []int{4, 5}, Limit: "not"},
+		{TeacherID: 1, TimeSlots: []int{6}, Limit: "avoid"},
+	}
+
+	timeSlots, err := GetTeacherNotTimeSlots(1, teachers, constraints)
+	if err != nil || !reflect.DeepEqual(timeSlots, []int{3, 4, 5}) {
+		t.Errorf("teacher 1: %v, %v", timeSlots, err)
+	}
+
+	timeSlots, err = GetTeacherNotTimeSlots(2, teachers, constraints)
+	if err != nil || len(timeSlots) != 0 {
+		t.Errorf("teacher 2: %v, %v", timeSlots, err)
+	}
+}
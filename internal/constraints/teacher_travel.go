@@ -0,0 +1,145 @@
+// teacher_travel.go
+// 教师跨教学楼上课限制
+
+package constraints
+
+import (
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/types"
+	"course_scheduler/internal/utils"
+	"fmt"
+	"math"
+
+	"github.com/samber/lo"
+)
+
+// ###### 教师跨教学楼上课限制
+// 教师在两个教学楼(校区)连续上课时, 课间需要足够走过去
+// 教学楼之间的通行时间见 models.TravelTime, 课间时长根据作息时间计算, 没有配置作息时间表时不检查
+
+// 教师分组和教师二选一, 都为空表示对所有教师生效
+// 限制类型 not: 禁止, avoid: 尽量避免
+
+// | 教师分组 | 教师   | 限制类型 | 描述                     |
+// | -------- | ------ | -------- | ------------------------ |
+// |          |        | 禁止     | 课间来不及换校区         |
+// | 实验组   |        | 尽量避免 | 实验楼和主楼尽量不要连排 |
+type TeacherTravel struct {
+	ID             int    `json:"id" mapstructure:"id"`                             // 自增ID
+	TeacherGroupID int    `json:"teacher_group_id" mapstructure:"teacher_group_id"` // 教师分组ID, 可以为空
+	TeacherID      int    `json:"teacher_id" mapstructure:"teacher_id"`             // 教师ID, 可以为空
+	Limit          string `json:"limit" mapstructure:"limit"`                       // 限制类型 not: 禁止, avoid: 尽量避免
+	Desc           string `json:"desc" mapstructure:"desc"`                         // 描述
+}
+
+// 生成字符串
+func (t *TeacherTravel) String() string {
+	return fmt.Sprintf("ID: %d, TeacherGroupID: %d, TeacherID: %d, Limit: %s, Desc: %s",
+		t.ID, t.TeacherGroupID, t.TeacherID, t.Limit, t.Desc)
+}
+
+// 获取规则
+func GetTeacherTravelRules(venues []*models.Venue, travelTimes []*models.TravelTime, constraints []*TeacherTravel) []*types.Rule {
+	// constraints := loadTeacherTravelConstraintsFromDB()
+	var rules []*types.Rule
+	for _, c := range constraints {
+		rule := c.genRule(venues, travelTimes)
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// 生成规则
+func (t *TeacherTravel) genRule(venues []*models.Venue, travelTimes []*models.TravelTime) *types.Rule {
+	fn := t.genConstraintFn(venues, travelTimes)
+	return &types.Rule{
+		Name:     "teacherTravel",
+		Type:     "dynamic",
+		Fn:       fn,
+		Score:    0,
+		Penalty:  t.getPenalty(),
+		Weight:   1,
+		Priority: 1,
+	}
+}
+
+// 加载教师跨教学楼上课限制
+func loadTeacherTravelConstraintsFromDB() []*TeacherTravel {
+	var constraints []*TeacherTravel
+	return constraints
+}
+
+// 生成规则校验方法
+func (t *TeacherTravel) genConstraintFn(venues []*models.Venue, travelTimes []*models.TravelTime) types.ConstraintFn {
+	return func(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
+
+		teacherIDs := lo.Filter(element.GetTeacherIDs(), func(teacherID int, _ int) bool {
+			return t.isTeacherMatched(teacherID, classMatrix.Teachers)
+		})
+
+		preCheckPassed := len(teacherIDs) > 0 && models.GetVenueBuilding(element.VenueID, venues) != ""
+		if !preCheckPassed {
+			return false, false, nil
+		}
+
+		elementTimeSlotStr := utils.TimeSlotsToStr(element.TimeSlots)
+		shouldPenalize := false
+		for _, teacherMap := range classMatrix.Elements {
+			for _, venueMap := range teacherMap {
+				for _, timeSlotMap := range venueMap {
+					for timeSlotStr, e := range timeSlotMap {
+
+						// 跳过未排课的元素和当前元素
+						if e.Val.Used != 1 || (e.ClassSN == element.ClassSN && e.TeacherID == element.TeacherID && e.VenueID == element.VenueID && timeSlotStr == elementTimeSlotStr) {
+							continue
+						}
+
+						if len(lo.Intersect(teacherIDs, e.GetTeacherIDs())) == 0 {
+							continue
+						}
+
+						if models.IsTravelTooShort(schedule, venues, travelTimes, element.VenueID, element.TimeSlots, e.VenueID, e.TimeSlots) {
+							shouldPenalize = true
+						}
+					}
+				}
+			}
+		}
+
+		return preCheckPassed, !shouldPenalize, nil
+	}
+}
+
+// 判断教师是否在约束范围内
+// 教师分组和教师都为空时, 对所有教师生效
+func (t *TeacherTravel) isTeacherMatched(teacherID int, teachers []*models.Teacher) bool {
+
+	if t.TeacherID > 0 {
+		return t.TeacherID == teacherID
+	}
+
+	if t.TeacherGroupID > 0 {
+		return models.IsTeacherInGroup(teacherID, t.TeacherGroupID, teachers)
+	}
+
+	return true
+}
+
+// 获取处罚分
+func (t *TeacherTravel) getPenalty() int {
+	penalty := 0
+	if t.Limit == "not" {
+		penalty = math.MaxInt32
+	} else if t.Limit == "avoid" {
+		penalty = 4
+	}
+	return penalty
+}
+
+// 判断教师是否禁止课间来不及换教学楼的连续排课
+// 用于修复个体冲突时, 过滤教师不可用的时间段
+func IsTeacherTravelForbidden(teacherID int, teachers []*models.Teacher, constraints []*TeacherTravel) bool {
+	return lo.ContainsBy(constraints, func(c *TeacherTravel) bool {
+		return c.Limit == "not" && c.isTeacherMatched(teacherID, teachers)
+	})
+}
@@ -0,0 +1,82 @@
+package constraints
+
+import (
+	"course_scheduler/internal/models"
+	"math"
+	"testing"
+)
+
+func TestTeacherTravelRules(t *testing.T) {
+
+	// 第1节, 第2节之间10分钟, 第2节, 第3节之间30分钟大课间
+	schedule := newTestSchedule()
+	schedule.BellSchedules = []*models.BellSchedule{
+		{
+			Weekday: 0,
+			Periods: []*models.PeriodTime{
+				{Period: 1, StartTime: "08:00", EndTime: "08:45"},
+				{Period: 2, StartTime: "08:55", EndTime: "09:40"},
+				{Period: 3, StartTime: "10:10", EndTime: "10:55"},
+				{Period: 4, StartTime: "11:05", EndTime: "11:50"},
+			},
+		},
+	}
+	venues := []*models.Venue{
+		{VenueID: 101, Name: "东校区101", Building: "东校区"},
+		{VenueID: 102, Name: "东校区102", Building: "东校区"},
+		{VenueID: 201, Name: "西校区201", Building: "西校区"},
+		{VenueID: 301, Name: "操场"},
+	}
+	travelTimes := []*models.TravelTime{{FromBuilding: "东校区", ToBuilding: "西校区", Minutes: 20}}
+	teachers := []*models.Teacher{{TeacherID: 1, TeacherGroupIDs: []int{10}}, {TeacherID: 2}, {TeacherID: 3}}
+
+	// 教师1星期一第1节在东校区上课
+	cm := newTestClassMatrix(teachers, newTestElement(t, "1_1_1", 1, 101, 0))
+
+	tests := []struct {
+		name         string
+		constraint   *TeacherTravel
+		teacherID    int
+		coTeacherIDs []int
+		venueID      int
+		timeSlot     int
+		want         string
+	}{
+		{"gap too short", &TeacherTravel{Limit: "not"}, 1, nil, 201, 1, failed},
+		{"long break", &TeacherTravel{Limit: "not"}, 1, nil, 201, 2, passed},
+		{"same building", &TeacherTravel{Limit: "not"}, 1, nil, 102, 1, passed},
+		{"other day", &TeacherTravel{Limit: "not"}, 1, nil, 201, 9, passed},
+		{"other teacher", &TeacherTravel{Limit: "not"}, 2, nil, 201, 1, passed},
+		{"co-teacher", &TeacherTravel{Limit: "not"}, 2, []int{1}, 201, 1, failed},
+		{"venue without building", &TeacherTravel{Limit: "not"}, 1, nil, 301, 1, skipped},
+		{"teacher not matched", &TeacherTravel{TeacherID: 2, Limit: "not"}, 1, nil, 201, 1, skipped},
+		{"teacher group", &TeacherTravel{TeacherGroupID: 10, Limit: "avoid"}, 1, nil, 201, 1, failed},
+		{"teacher not in group", &TeacherTravel{TeacherGroupID: 10, Limit: "avoid"}, 3, nil, 201, 1, skipped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			rules := GetTeacherTravelRules(venues, travelTimes, []*TeacherTravel{tt.constraint})
+			element := newTestElement(t, "2_1_2", tt.teacherID, tt.venueID, tt.timeSlot)
+			element.CoTeacherIDs = tt.coTeacherIDs
+			if got := checkRule(t, rules[0], cm, element, schedule); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// 没有配置作息时间表时不检查
+	schedule.BellSchedules = nil
+	rules := GetTeacherTravelRules(venues, travelTimes, []*TeacherTravel{{Limit: "not"}})
+	element := newTestElement(t, "2_1_2", 1, 201, 1)
+	if got := checkRule(t, rules[0], cm, element, schedule); got != passed {
+		t.Errorf("without bell schedule: got %s, want %s", got, passed)
+	}
+
+	// 禁止为硬约束, 尽量避免为软约束
+	notRules := GetTeacherTravelRules(venues, travelTimes, []*TeacherTravel{{Limit: "not"}, {Limit: "avoid"}})
+	if notRules[0].Penalty != math.MaxInt32 || notRules[1].Penalty != 4 {
+		t.Errorf("penalty: got %d, %d", notRules[0].Penalty, notRules[1].Penalty)
+	}
+}
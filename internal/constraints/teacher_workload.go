@@ -0,0 +1,286 @@
+// teacher_workload.go
+// 教师工作量限制
+
+package constraints
+
+import (
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/types"
+	"fmt"
+	"sort"
+
+	"github.com/samber/lo"
+)
+
+// ###### 教师工作量限制
+
+// 教师分组和教师二选一, 教师分组对组内所有教师生效
+// 各项为0(或为空)表示不限制
+
+// | 教师分组 | 教师   | 最多连续节数 | 每天最多 | 每天最少 | 每周最多 | 每周最多上课天数 | 休息日   |
+// | -------- | ------ | ------------ | -------- | -------- | -------- | ---------------- | -------- |
+// |          | 王老师 | 3 节         | 5 节     |          | 20 节    |                  |          |
+// | 行政组   |        |              |          | 2 节     |          | 4 天             | 星期五   |
+// |          | 李老师 |              |          |          |          |                  | 星期三   |
+type TeacherWorkload struct {
+	ID                    int   `json:"id" mapstructure:"id"`                                           // 自增ID
+	TeacherGroupID        int   `json:"teacher_group_id" mapstructure:"teacher_group_id"`               // 教师分组ID
+	TeacherID             int   `json:"teacher_id" mapstructure:"teacher_id"`                           // 教师ID
+	MaxConsecutiveClasses int   `json:"max_consecutive_classes" mapstructure:"max_consecutive_classes"` // 最多连续上课节数
+	MaxDailyClasses       int   `json:"max_daily_classes" mapstructure:"max_daily_classes"`             // 每天最多上课节数
+	MinDailyClasses       int   `json:"min_daily_classes" mapstructure:"min_daily_classes"`             // 有课的日子, 每天最少上课节数
+	MaxWeeklyClasses      int   `json:"max_weekly_classes" mapstructure:"max_weekly_classes"`           // 每周最多上课节数
+	MaxTeachingDays       int   `json:"max_teaching_days" mapstructure:"max_teaching_days"`             // 每周最多上课天数
+	DaysOff               []int `json:"days_off" mapstructure:"days_off"`                               // 必须休息的日子, 可选项为"1: 星期一"、"2: 星期二"、"3: 星期三"、"4: 星期四"、"5: 星期五"
+}
+
+// 生成字符串
+func (t *TeacherWorkload) String() string {
+	return fmt.Sprintf("ID: %d, TeacherGroupID: %d, TeacherID: %d, MaxConsecutiveClasses: %d, MaxDailyClasses: %d, MinDailyClasses: %d, MaxWeeklyClasses: %d, MaxTeachingDays: %d, DaysOff: %v",
+		t.ID, t.TeacherGroupID, t.TeacherID, t.MaxConsecutiveClasses, t.MaxDailyClasses, t.MinDailyClasses, t.MaxWeeklyClasses, t.MaxTeachingDays, t.DaysOff)
+}
+
+// 获取规则
+// 每个约束条件按照限制项, 生成多条规则
+func GetTeacherWorkloadRules(constraints []*TeacherWorkload) []*types.Rule {
+	// constraints := loadTeacherWorkloadConstraintsFromDB()
+	var rules []*types.Rule
+	for _, c := range constraints {
+		rules = append(rules, c.genRules()...)
+	}
+	return rules
+}
+
+// 生成规则
+func (t *TeacherWorkload) genRules() []*types.Rule {
+
+	var rules []*types.Rule
+
+	if t.MaxConsecutiveClasses > 0 {
+		rules = append(rules, t.genRule("teacherWorkloadConsecutive", 0, 4, t.genConsecutiveFn()))
+	}
+
+	if t.MaxDailyClasses > 0 {
+		rules = append(rules, t.genRule("teacherWorkloadDailyMax", 0, 4, t.genDailyMaxFn()))
+	}
+
+	// 最少, 刚好达到最少节数时奖励, 有课但是不够最少节数时处罚
+	if t.MinDailyClasses > 0 {
+		rules = append(rules, t.genRule("teacherWorkloadDailyMin", 2, 4, t.genDailyMinFn()))
+	}
+
+	if t.MaxWeeklyClasses > 0 {
+		rules = append(rules, t.genRule("teacherWorkloadWeeklyMax", 0, 4, t.genWeeklyMaxFn()))
+	}
+
+	if t.MaxTeachingDays > 0 {
+		rules = append(rules, t.genRule("teacherWorkloadTeachingDays", 0, 4, t.genTeachingDaysFn()))
+	}
+
+	if len(t.DaysOff) > 0 {
+		rules = append(rules, t.genRule("teacherWorkloadDaysOff", 0, 6, t.genDaysOffFn()))
+	}
+
+	return rules
+}
+
+// 生成单条规则
+func (t *TeacherWorkload) genRule(name string, score, penalty int, fn types.ConstraintFn) *types.Rule {
+	return &types.Rule{
+		Name:     name,
+		Type:     "dynamic",
+		Fn:       fn,
+		Score:    score,
+		Penalty:  penalty,
+		Weight:   1,
+		Priority: 1,
+	}
+}
+
+// 加载规则
+func loadTeacherWorkloadConstraintsFromDB() []*TeacherWorkload {
+	var constraints []*TeacherWorkload
+	return constraints
+}
+
+// 最多连续上课节数
+// 协同上课的教师也受约束, 其中任意一位教师超过限制时处罚
+func (t *TeacherWorkload) genConsecutiveFn() types.ConstraintFn {
+	return func(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
+
+		teacherIDs := t.matchedTeacherIDs(element, classMatrix.Teachers)
+		if len(teacherIDs) == 0 {
+			return false, false, nil
+		}
+
+		elementDay := schedule.GetTimeSlot(element.TimeSlots[0]).Day
+		shouldPenalize := lo.SomeBy(teacherIDs, func(teacherID int) bool {
+
+			periods := calcTeacherDayClasses(classMatrix, teacherID, schedule)[elementDay]
+
+			// 假设在当前元素排课
+			if element.Val.Used == 0 {
+				periods = append(periods, types.GetElementPeriods(element, schedule)...)
+			}
+			return maxConsecutivePeriods(periods) > t.MaxConsecutiveClasses
+		})
+		return true, !shouldPenalize, nil
+	}
+}
+
+// 每天最多上课节数
+func (t *TeacherWorkload) genDailyMaxFn() types.ConstraintFn {
+	return func(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
+
+		teacherIDs := t.matchedTeacherIDs(element, classMatrix.Teachers)
+		if len(teacherIDs) == 0 {
+			return false, false, nil
+		}
+
+		shouldPenalize := lo.SomeBy(teacherIDs, func(teacherID int) bool {
+			return countElementDayClasses(classMatrix, teacherID, element, schedule) > t.MaxDailyClasses
+		})
+		return true, !shouldPenalize, nil
+	}
+}
+
+// 每天最少上课节数
+// 当天的课时数(包括当前元素)还不够最少节数时处罚, 当天的每节课都会被处罚, 排课结束时不够最少节数的天会降低适应度
+// 当前元素使当天刚好达到最少节数时奖励, 超过最少节数时不处理
+// 有协同上课的教师时, 任意一位教师不够最少节数就处罚
+func (t *TeacherWorkload) genDailyMinFn() types.ConstraintFn {
+	return func(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
+
+		teacherIDs := t.matchedTeacherIDs(element, classMatrix.Teachers)
+		if len(teacherIDs) == 0 {
+			return false, false, nil
+		}
+
+		counts := lo.Map(teacherIDs, func(teacherID int, _ int) int {
+			return countElementDayClasses(classMatrix, teacherID, element, schedule)
+		})
+
+		minCount := lo.Min(counts)
+		if minCount > t.MinDailyClasses {
+			return false, false, nil
+		}
+
+		isReward := minCount == t.MinDailyClasses
+		return true, isReward, nil
+	}
+}
+
+// 每周最多上课节数
+func (t *TeacherWorkload) genWeeklyMaxFn() types.ConstraintFn {
+	return func(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
+
+		teacherIDs := t.matchedTeacherIDs(element, classMatrix.Teachers)
+		if len(teacherIDs) == 0 {
+			return false, false, nil
+		}
+
+		shouldPenalize := lo.SomeBy(teacherIDs, func(teacherID int) bool {
+
+			count := 0
+			dayPeriods := calcTeacherDayClasses(classMatrix, teacherID, schedule)
+			for _, periods := range dayPeriods {
+				count += len(periods)
+			}
+
+			if element.Val.Used == 0 {
+				count += len(element.TimeSlots)
+			}
+			return count > t.MaxWeeklyClasses
+		})
+		return true, !shouldPenalize, nil
+	}
+}
+
+// 每周最多上课天数
+func (t *TeacherWorkload) genTeachingDaysFn() types.ConstraintFn {
+	return func(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
+
+		teacherIDs := t.matchedTeacherIDs(element, classMatrix.Teachers)
+		if len(teacherIDs) == 0 {
+			return false, false, nil
+		}
+
+		elementDay := schedule.GetTimeSlot(element.TimeSlots[0]).Day
+		shouldPenalize := lo.SomeBy(teacherIDs, func(teacherID int) bool {
+
+			dayPeriods := calcTeacherDayClasses(classMatrix, teacherID, schedule)
+			days := lo.Keys(dayPeriods)
+			if !lo.Contains(days, elementDay) {
+				days = append(days, elementDay)
+			}
+			return len(days) > t.MaxTeachingDays
+		})
+		return true, !shouldPenalize, nil
+	}
+}
+
+// 必须休息的日子
+func (t *TeacherWorkload) genDaysOffFn() types.ConstraintFn {
+	return func(classMatrix *types.ClassMatrix, element types.Element, schedule *models.Schedule, teachingTasks []*models.TeachingTask) (bool, bool, error) {
+
+		weekday := schedule.GetTimeSlot(element.TimeSlots[0]).Weekday()
+
+		// 禁排: 不排没关系, 排了就处罚
+		preCheckPassed := len(t.matchedTeacherIDs(element, classMatrix.Teachers)) > 0 && lo.Contains(t.DaysOff, weekday)
+		return preCheckPassed, false, nil
+	}
+}
+
+// 判断教师是否在约束范围内
+// 指定了教师分组时, 分组内的所有教师都受约束
+func (t *TeacherWorkload) isTeacherMatched(teacherID int, teachers []*models.Teacher) bool {
+
+	if t.TeacherID > 0 {
+		return t.TeacherID == teacherID
+	}
+
+	if t.TeacherGroupID > 0 {
+		return models.IsTeacherInGroup(teacherID, t.TeacherGroupID, teachers)
+	}
+
+	return false
+}
+
+// 获取元素中受约束的教师, 包括协同上课的教师
+func (t *TeacherWorkload) matchedTeacherIDs(element types.Element, teachers []*models.Teacher) []int {
+	return lo.Filter(element.GetTeacherIDs(), func(teacherID int, _ int) bool {
+		return t.isTeacherMatched(teacherID, teachers)
+	})
+}
+
+// 统计教师在当前元素所在天的排课节数
+// 此时是假设当前元素会排课,所以需要将当前元素也计算在内
+func countElementDayClasses(classMatrix *types.ClassMatrix, teacherID int, element types.Element, schedule *models.Schedule) int {
+
+	elementDay := schedule.GetTimeSlot(element.TimeSlots[0]).Day
+	count := len(calcTeacherDayClasses(classMatrix, teacherID, schedule)[elementDay])
+
+	if element.Val.Used == 0 {
+		count += len(element.TimeSlots)
+	}
+	return count
+}
+
+// 计算节次列表中, 最长的连续节次数
+func maxConsecutivePeriods(periods []int) int {
+
+	periods = lo.Uniq(periods)
+	sort.Ints(periods)
+
+	maxCount := 0
+	count := 0
+	for i, period := range periods {
+		if i > 0 && period == periods[i-1]+1 {
+			count++
+		} else {
+			count = 1
+		}
+		maxCount = lo.Max([]int{maxCount, count})
+	}
+	return maxCount
+}
@@ -0,0 +1,125 @@
+package constraints
+
+import (
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/types"
+	"testing"
+)
+
+func TestTeacherWorkloadRules(t *testing.T) {
+
+	schedule := newTestSchedule()
+	teachers := []*models.Teacher{
+		{TeacherID: 1, Name: "王老师", TeacherGroupIDs: []int{10}},
+		{TeacherID: 2, Name: "李老师"},
+	}
+
+	tests := []struct {
+		name       string
+		constraint *TeacherWorkload
+		rule       string
+		used       [][]int // 教师1已经排课的时间段
+		element    []int   // 当前元素的时间段
+		teacherID  int
+		want       string
+	}{
+		// 星期一第1, 2节已经有课, 再排第3节是连续3节
+		{"consecutive ok", &TeacherWorkload{TeacherID: 1, MaxConsecutiveClasses: 3}, "teacherWorkloadConsecutive", [][]int{{0}, {1}}, []int{2}, 1, passed},
+		{"consecutive exceeded", &TeacherWorkload{TeacherID: 1, MaxConsecutiveClasses: 2}, "teacherWorkloadConsecutive", [][]int{{0}, {1}}, []int{2}, 1, failed},
+		{"consecutive other teacher", &TeacherWorkload{TeacherID: 1, MaxConsecutiveClasses: 2}, "teacherWorkloadConsecutive", nil, []int{2}, 2, skipped},
+
+		{"daily max ok", &TeacherWorkload{TeacherID: 1, MaxDailyClasses: 3}, "teacherWorkloadDailyMax", [][]int{{0}, {1}}, []int{5}, 1, passed},
+		{"daily max exceeded", &TeacherWorkload{TeacherID: 1, MaxDailyClasses: 2}, "teacherWorkloadDailyMax", [][]int{{0}, {1}}, []int{5}, 1, failed},
+		{"daily max other day", &TeacherWorkload{TeacherID: 1, MaxDailyClasses: 2}, "teacherWorkloadDailyMax", [][]int{{0}, {1}}, []int{8}, 1, passed},
+
+		// 当天只有1节课, 不够每天最少2节
+		{"daily min deficit", &TeacherWorkload{TeacherID: 1, MinDailyClasses: 2}, "teacherWorkloadDailyMin", nil, []int{8}, 1, failed},
+		{"daily min reached", &TeacherWorkload{TeacherID: 1, MinDailyClasses: 2}, "teacherWorkloadDailyMin", [][]int{{9}}, []int{8}, 1, passed},
+		{"daily min exceeded", &TeacherWorkload{TeacherID: 1, MinDailyClasses: 2}, "teacherWorkloadDailyMin", [][]int{{9}, {10}}, []int{8}, 1, skipped},
+
+		{"weekly max ok", &TeacherWorkload{TeacherID: 1, MaxWeeklyClasses: 3}, "teacherWorkloadWeeklyMax", [][]int{{0}, {8}}, []int{16}, 1, passed},
+		{"weekly max exceeded", &TeacherWorkload{TeacherID: 1, MaxWeeklyClasses: 2}, "teacherWorkloadWeeklyMax", [][]int{{0}, {8}}, []int{16}, 1, failed},
+
+		{"teaching days ok", &TeacherWorkload{TeacherID: 1, MaxTeachingDays: 2}, "teacherWorkloadTeachingDays", [][]int{{0}, {8}}, []int{9}, 1, passed},
+		{"teaching days exceeded", &TeacherWorkload{TeacherID: 1, MaxTeachingDays: 2}, "teacherWorkloadTeachingDays", [][]int{{0}, {8}}, []int{16}, 1, failed},
+
+		// 星期三(时间段16-23)休息
+		{"days off", &TeacherWorkload{TeacherGroupID: 10, DaysOff: []int{3}}, "teacherWorkloadDaysOff", nil, []int{16}, 1, failed},
+		{"days off other day", &TeacherWorkload{TeacherGroupID: 10, DaysOff: []int{3}}, "teacherWorkloadDaysOff", nil, []int{8}, 1, skipped},
+		{"days off not in group", &TeacherWorkload{TeacherGroupID: 10, DaysOff: []int{3}}, "teacherWorkloadDaysOff", nil, []int{16}, 2, skipped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var used []*types.Element
+			for _, timeSlots := range tt.used {
+				used = append(used, newTestElement(t, "1_1_1", 1, 101, timeSlots...))
+			}
+			cm := newTestClassMatrix(teachers, used...)
+
+			rule := findRule(t, GetTeacherWorkloadRules([]*TeacherWorkload{tt.constraint}), tt.rule)
+			element := newTestElement(t, "2_1_1", tt.teacherID, 101, tt.element...)
+			if got := checkRule(t, rule, cm, element, schedule); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// 排课结束后, 不够每天最少节数的那一天的每节课都被处罚
+func TestTeacherWorkloadDailyMinDeficit(t *testing.T) {
+
+	schedule := newTestSchedule()
+	teachers := []*models.Teacher{{TeacherID: 1}}
+	rule := findRule(t, GetTeacherWorkloadRules([]*TeacherWorkload{{TeacherID: 1, MinDailyClasses: 3}}), "teacherWorkloadDailyMin")
+
+	// 星期一3节, 星期二只有1节
+	monday := []*types.Element{newTestElement(t, "1_1_1", 1, 101, 0), newTestElement(t, "1_1_1", 1, 101, 1), newTestElement(t, "1_1_1", 1, 101, 2)}
+	tuesday := newTestElement(t, "1_1_1", 1, 101, 8)
+	cm := newTestClassMatrix(teachers, append(monday, tuesday)...)
+
+	for _, element := range monday {
+		if got := checkRule(t, rule, cm, element, schedule); got != passed {
+			t.Errorf("monday %v: got %s, want %s", element.TimeSlots, got, passed)
+		}
+	}
+
+	if got := checkRule(t, rule, cm, tuesday, schedule); got != failed {
+		t.Errorf("tuesday: got %s, want %s", got, failed)
+	}
+}
+
+// 协同上课的教师也受工作量限制
+func TestTeacherWorkloadCoTeacher(t *testing.T) {
+
+	schedule := newTestSchedule()
+	teachers := []*models.Teacher{{TeacherID: 1}, {TeacherID: 2}}
+	rules := GetTeacherWorkloadRules([]*TeacherWorkload{{TeacherID: 2, MaxDailyClasses: 2}})
+	rule := findRule(t, rules, "teacherWorkloadDailyMax")
+
+	// 教师2星期一协同上课1节, 自己上课1节
+	coTaught := newTestElement(t, "1_1_1", 1, 101, 0)
+	coTaught.CoTeacherIDs = []int{2}
+	cm := newTestClassMatrix(teachers, coTaught, newTestElement(t, "2_1_2", 2, 102, 1))
+
+	// 教师1的课, 教师2协同上课, 星期一第3节是教师2当天的第3节课
+	element := newTestElement(t, "1_1_1", 1, 101, 2)
+	element.CoTeacherIDs = []int{2}
+	if got := checkRule(t, rule, cm, element, schedule); got != failed {
+		t.Errorf("co-teacher exceeded: got %s, want %s", got, failed)
+	}
+
+	// 星期二教师2没有课
+	element = newTestElement(t, "1_1_1", 1, 101, 8)
+	element.CoTeacherIDs = []int{2}
+	if got := checkRule(t, rule, cm, element, schedule); got != passed {
+		t.Errorf("co-teacher other day: got %s, want %s", got, passed)
+	}
+
+	// 没有协同上课时, 教师1不受约束
+	element = newTestElement(t, "1_1_1", 1, 101, 2)
+	if got := checkRule(t, rule, cm, element, schedule); got != skipped {
+		t.Errorf("without co-teacher: got %s, want %s", got, skipped)
+	}
+}
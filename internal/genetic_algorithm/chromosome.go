@@ -16,16 +16,22 @@ func (c *Chromosome) Copy() *Chromosome {
 		newGene := &Gene{
 			ClassSN:            gene.ClassSN,
 			TeacherID:          gene.TeacherID,
+			CoTeacherIDs:       gene.CoTeacherIDs,
+			OccupiedClassIDs:   gene.OccupiedClassIDs,
 			VenueID:            gene.VenueID,
 			TimeSlots:          gene.TimeSlots,
 			IsConnected:        gene.IsConnected,
+			Locked:             gene.Locked,
 			FailedConstraints:  make([]string, len(gene.FailedConstraints)),
 			PassedConstraints:  make([]string, len(gene.PassedConstraints)),
 			SkippedConstraints: make([]string, len(gene.SkippedConstraints)),
+
+			HardFailedConstraints: make([]string, len(gene.HardFailedConstraints)),
 		}
 		copy(newGene.FailedConstraints, gene.FailedConstraints)
 		copy(newGene.PassedConstraints, gene.PassedConstraints)
 		copy(newGene.SkippedConstraints, gene.SkippedConstraints)
+		copy(newGene.HardFailedConstraints, gene.HardFailedConstraints)
 		newGenes[i] = newGene
 	}
 
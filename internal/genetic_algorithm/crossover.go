@@ -2,7 +2,6 @@
 package genetic_algorithm
 
 import (
-	"course_scheduler/internal/constraints"
 	"course_scheduler/internal/models"
 	"fmt"
 	"log"
@@ -23,20 +22,18 @@ import (
 //	grades: 年级信息
 //	subjectVenueMap: 科目与教学场地
 //	constraintMap: 约束条件
+//	reschedule: 部分重排参数, 不是部分重排时为空
 //
 // 返回值:
 //
 //	返回 交叉后的个体、准备交叉次数、实际交叉次数、错误信息
 
-func Crossover(selected []*Individual, crossoverRate float64, schedule *models.Schedule, teachingTasks []*models.TeachingTask, subjects []*models.Subject, teachers []*models.Teacher, grades []*models.Grade, subjectVenueMap map[string][]int, constraintMap map[string]interface{}) ([]*Individual, int, int, error) {
+func Crossover(selected []*Individual, crossoverRate float64, schedule *models.Schedule, teachingTasks []*models.TeachingTask, subjects []*models.Subject, teachers []*models.Teacher, grades []*models.Grade, subjectVenueMap map[string][]int, constraintMap map[string]interface{}, reschedule *Reschedule) ([]*Individual, int, int, error) {
 
 	offspring := make([]*Individual, 0, len(selected))
 	prepared := 0
 	executed := 0
 
-	constr1 := constraintMap["Class"].([]*constraints.Class)
-	constr2 := constraintMap["Teacher"].([]*constraints.Teacher)
-
 	fmt.Printf("selected count: %d, crossoverRate: %f", len(selected), crossoverRate)
 
 	for i := 0; i < len(selected)-1; i += 2 {
@@ -51,7 +48,7 @@ func Crossover(selected []*Individual, crossoverRate float64, schedule *models.S
 			parent2 := selected[i+1].Copy()
 
 			// 执行交叉操作并进行后续检查
-			offspring1, offspring2, err := crossoverAndValidate(parent1, parent2, crossPoint, schedule, grades, teachers, constr1, constr2)
+			offspring1, offspring2, err := crossoverAndValidate(parent1, parent2, crossPoint, schedule, grades, teachers, constraintMap)
 
 			// 如果交叉操作出现错误, 则撤销当前交叉操作
 			if err == nil {
@@ -64,8 +61,8 @@ func Crossover(selected []*Individual, crossoverRate float64, schedule *models.S
 					return offspring, prepared, executed, fmt.Errorf("ERROR: offspring evaluate fitness failed. err1: %s, err2: %s", err1.Error(), err2.Error())
 				}
 
-				fitness1, err1 := offspring1.evaluateFitness(offspringClassMatrix1, schedule, subjects, teachers, constraintMap)
-				fitness2, err2 := offspring2.evaluateFitness(offspringClassMatrix2, schedule, subjects, teachers, constraintMap)
+				fitness1, err1 := offspring1.evaluateFitness(offspringClassMatrix1, schedule, subjects, teachers, constraintMap, reschedule)
+				fitness2, err2 := offspring2.evaluateFitness(offspringClassMatrix2, schedule, subjects, teachers, constraintMap, reschedule)
 
 				if err1 != nil || err2 != nil {
 					return offspring, prepared, executed, fmt.Errorf("ERROR: offspring evaluate fitness failed. err1: %s, err2: %s", err1.Error(), err2.Error())
@@ -99,10 +96,10 @@ func Crossover(selected []*Individual, crossoverRate float64, schedule *models.S
 }
 
 // 可换算法验证 用于验证染色体上的基因在进行基因互换杂交时是否符合基因的约束条件
-func crossoverAndValidate(parent1, parent2 *Individual, crossPoint int, schedule *models.Schedule, grades []*models.Grade, teachers []*models.Teacher, constr1 []*constraints.Class, constr2 []*constraints.Teacher) (*Individual, *Individual, error) {
+func crossoverAndValidate(parent1, parent2 *Individual, crossPoint int, schedule *models.Schedule, grades []*models.Grade, teachers []*models.Teacher, constraintMap map[string]interface{}) (*Individual, *Individual, error) {
 
 	// 交叉操作
-	offspring1, offspring2, err := crossoverIndividuals(parent1, parent2, crossPoint, schedule, grades, teachers, constr1, constr2)
+	offspring1, offspring2, err := crossoverIndividuals(parent1, parent2, crossPoint, schedule, grades, teachers, constraintMap)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -160,7 +157,7 @@ func crossoverAndValidate(parent1, parent2 *Individual, crossPoint int, schedule
 
 // 两个个体之间进行交叉操作，生成两个子代个体
 // 返回两个子代个体和错误信息（如果有）
-func crossoverIndividuals(parent1, parent2 *Individual, crossPoint int, schedule *models.Schedule, grades []*models.Grade, teachers []*models.Teacher, constr1 []*constraints.Class, constr2 []*constraints.Teacher) (*Individual, *Individual, error) {
+func crossoverIndividuals(parent1, parent2 *Individual, crossPoint int, schedule *models.Schedule, grades []*models.Grade, teachers []*models.Teacher, constraintMap map[string]interface{}) (*Individual, *Individual, error) {
 
 	// 检查交叉点是否在有效范围内
 	if crossPoint <= 0 || crossPoint >= len(parent1.Chromosomes) {
@@ -217,12 +214,12 @@ func crossoverIndividuals(parent1, parent2 *Individual, crossPoint int, schedule
 	}
 
 	// 修复时间段冲突
-	count1, err1 := offspring1.resolveConflicts(schedule, teachers, constr1, constr2)
+	count1, err1 := offspring1.resolveConflicts(schedule, teachers, constraintMap)
 	if err1 != nil {
 		return nil, nil, err1
 	}
 
-	count2, err2 := offspring2.resolveConflicts(schedule, teachers, constr1, constr2)
+	count2, err2 := offspring2.resolveConflicts(schedule, teachers, constraintMap)
 	if err2 != nil {
 		return nil, nil, err2
 	}
@@ -246,7 +243,7 @@ func crossoverIndividuals(parent1, parent2 *Individual, crossPoint int, schedule
 // 旧的实现方法备份
 // 两个个体之间进行交叉操作，生成两个子代个体
 // 返回两个子代个体和错误信息（如果有）
-func crossoverIndividualsBAK(parent1, parent2 *Individual, crossPoint int, schedule *models.Schedule, grades []*models.Grade, teachers []*models.Teacher, constr1 []*constraints.Class, constr2 []*constraints.Teacher) (*Individual, *Individual, error) {
+func crossoverIndividualsBAK(parent1, parent2 *Individual, crossPoint int, schedule *models.Schedule, grades []*models.Grade, teachers []*models.Teacher, constraintMap map[string]interface{}) (*Individual, *Individual, error) {
 
 	// 检查交叉点是否在有效范围内
 	if crossPoint <= 0 || crossPoint >= len(parent1.Chromosomes) {
@@ -296,12 +293,12 @@ func crossoverIndividualsBAK(parent1, parent2 *Individual, crossPoint int, sched
 	}
 
 	// 修复时间段冲突
-	count1, err1 := offspring1.resolveConflicts(schedule, teachers, constr1, constr2)
+	count1, err1 := offspring1.resolveConflicts(schedule, teachers, constraintMap)
 	if err1 != nil {
 		return nil, nil, err1
 	}
 
-	count2, err2 := offspring2.resolveConflicts(schedule, teachers, constr1, constr2)
+	count2, err2 := offspring2.resolveConflicts(schedule, teachers, constraintMap)
 	if err2 != nil {
 		return nil, nil, err2
 	}
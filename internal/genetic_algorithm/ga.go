@@ -37,8 +37,8 @@ func Execute(input *base.ScheduleInput, monitor *base.Monitor, startTime time.Ti
 	foundSatIndividual := false
 	// 连续 n 代没有改进
 	genWithoutImprovement := 0
-	// 是否进入搜索循环
-	stop := false
+	// 终止原因, 为空时继续搜索循环
+	reason := ""
 	// 当前代数
 	gen := 0
 	// 最佳个体所在的代数
@@ -61,8 +61,11 @@ func Execute(input *base.ScheduleInput, monitor *base.Monitor, startTime time.Ti
 	// 约束条件
 	constraints := input.Constraints()
 
+	// 部分重排参数
+	reschedule := NewReschedule(input)
+
 	// 初始化当前种群
-	currentPopulation, err := InitPopulation(popSize, input.Schedule, input.TeachingTasks, input.Subjects, input.Teachers, input.SubjectVenueMap, constraints)
+	currentPopulation, err := InitPopulation(popSize, input.Schedule, input.TeachingTasks, input.Subjects, input.Teachers, input.SubjectVenueMap, constraints, reschedule)
 	if err != nil {
 		return bestIndividual, bestGen, err
 	}
@@ -74,7 +77,7 @@ func Execute(input *base.ScheduleInput, monitor *base.Monitor, startTime time.Ti
 	dupCount := CountDuplicates(currentPopulation)
 	log.Printf("Population size %d: duplicates count %d\n", popSize, dupCount)
 
-	for !stop {
+	for reason == "" {
 		log.Println("Current Generation:", gen)
 		// 获取当前最近个体标识符
 		uniqueId = bestIndividual.UniqueId
@@ -105,12 +108,23 @@ func Execute(input *base.ScheduleInput, monitor *base.Monitor, startTime time.Ti
 			genWithoutImprovement = 0
 		} else {
 			genWithoutImprovement++
-			if genWithoutImprovement >= maxStagnGen {
-				log.Println("Termination condition met: No improvement for", genWithoutImprovement, "generations.")
-				break
+		}
+		monitor.GenWithoutImprovementPerGen[gen] = genWithoutImprovement
+
+		// 这一代的监控数据已经完整, 在检查终止条件之前通知, 提前终止时最后一代也会保存和推送
+		if monitor.OnGeneration != nil {
+			if err := monitor.OnGeneration(gen + 1); err != nil {
+				return bestIndividual, bestGen, err
 			}
 		}
 
+		if genWithoutImprovement >= maxStagnGen {
+			log.Println("Termination condition met: No improvement for", genWithoutImprovement, "generations.")
+			reason = TerminationStagnation
+			gen++
+			break
+		}
+
 		// 检查是否找到满意的解
 		foundSatIndividual = IsSatIndividual(currentPopulation)
 		// if !foundSatIndividual {
@@ -132,7 +146,7 @@ func Execute(input *base.ScheduleInput, monitor *base.Monitor, startTime time.Ti
 
 		// 交叉
 		// 交叉前后的个体数量不变
-		offspring, prepared, executed, err := Crossover(selectedPopulation, crossoverRate, input.Schedule, input.TeachingTasks, input.Subjects, input.Teachers, input.Grades, input.SubjectVenueMap, constraints)
+		offspring, prepared, executed, err := Crossover(selectedPopulation, crossoverRate, input.Schedule, input.TeachingTasks, input.Subjects, input.Teachers, input.Grades, input.SubjectVenueMap, constraints, reschedule)
 		if err != nil {
 			return bestIndividual, bestGen, err
 		}
@@ -140,7 +154,7 @@ func Execute(input *base.ScheduleInput, monitor *base.Monitor, startTime time.Ti
 		monitor.NumExecutedCrossover[gen] = executed
 
 		// 变异
-		offspring, prepared, executed, err = Mutation(offspring, mutationRate, input.Schedule, input.TeachingTasks, input.Subjects, input.Teachers, input.Grades, input.SubjectVenueMap, constraints)
+		offspring, prepared, executed, err = Mutation(offspring, mutationRate, input.Schedule, input.TeachingTasks, input.Subjects, input.Teachers, input.Grades, input.SubjectVenueMap, constraints, reschedule)
 		if err != nil {
 			return bestIndividual, bestGen, err
 		}
@@ -158,9 +172,14 @@ func Execute(input *base.ScheduleInput, monitor *base.Monitor, startTime time.Ti
 
 		// 在每次循环迭代时更新 gen 的值
 		gen++
-		stop = TerminationCondition(gen, foundSatIndividual, genWithoutImprovement, startTime)
+		reason = TerminationCondition(gen, foundSatIndividual, genWithoutImprovement, startTime)
 	}
 
+	// 记录搜索结果, 用于生成排课质量报告
+	monitor.NumGenerations = gen
+	monitor.BestGen = bestGen
+	monitor.TerminationReason = reason
+
 	// 打印当前代中最好个体的适应度值
 	log.Printf("Generation %d: Best uniqueId= %s, bestGen=%d, Fitness = %d\n", gen, uniqueId, bestGen, bestIndividual.Fitness)
 	return bestIndividual, bestGen, nil
@@ -1,18 +1,26 @@
 // gene.go
 package genetic_algorithm
 
-import "course_scheduler/internal/utils"
+import (
+	"course_scheduler/internal/types"
+	"course_scheduler/internal/utils"
+)
 
 // 基因
 type Gene struct {
 	ClassSN            string   // 课班信息，科目_年级_班级 如:美术_一年级_1班
 	TeacherID          int      // 教师id
+	CoTeacherIDs       []int    // 协同上课的教师id
+	OccupiedClassIDs   []int    // 教学班上课时会占用的行政班id
 	VenueID            int      // 教室id
 	TimeSlots          []int    // 时间段 一周5天,每天8节课,TimeSlot值是{0,1,2,3...39}
 	IsConnected        bool     // 是否是连堂课
+	Locked             bool     // 是否锁定, 部分重排时锁定的基因不能移动
 	FailedConstraints  []string // 未满足的约束条件
 	PassedConstraints  []string // 已满足的约束条件
 	SkippedConstraints []string // 已满足的约束条件
+
+	HardFailedConstraints []string // 未满足的硬约束条件, 是FailedConstraints的子集
 }
 
 func (g *Gene) GetClassSN() string {
@@ -23,6 +31,17 @@ func (g *Gene) GetTeacherID() int {
 	return g.TeacherID
 }
 
+// 获取上课的所有教师, 包括协同上课的教师
+func (g *Gene) GetTeacherIDs() []int {
+	return append([]int{g.TeacherID}, g.CoTeacherIDs...)
+}
+
+// 获取课程占用的班级key列表, key: 年级_班级
+func (g *Gene) GetClassKeys() []string {
+	SN, _ := types.ParseSN(g.ClassSN)
+	return types.ClassKeys(SN.GradeID, SN.ClassID, g.OccupiedClassIDs)
+}
+
 func (g *Gene) GetVenueID() int {
 	return g.VenueID
 }
@@ -30,7 +30,7 @@ type Individual struct {
 // classMatrix 课班适应性矩阵
 // key: [课班(科目_年级_班级)][教师][教室][时间段], value: Val
 // key: [9][13][9][40],
-func newIndividual(classMatrix *types.ClassMatrix, schedule *models.Schedule, subjects []*models.Subject, teachers []*models.Teacher, constraintMap map[string]interface{}) (*Individual, error) {
+func newIndividual(classMatrix *types.ClassMatrix, schedule *models.Schedule, subjects []*models.Subject, teachers []*models.Teacher, constraintMap map[string]interface{}, reschedule *Reschedule) (*Individual, error) {
 
 	// 所有课班选择点位完毕后即可得到一个随机课表，作为种群一个个体
 	individual := &Individual{
@@ -58,12 +58,17 @@ func newIndividual(classMatrix *types.ClassMatrix, schedule *models.Schedule, su
 						gene := &Gene{
 							ClassSN:            sn,
 							TeacherID:          teacherID,
+							CoTeacherIDs:       e.CoTeacherIDs,
+							OccupiedClassIDs:   e.OccupiedClassIDs,
 							VenueID:            venueID,
 							TimeSlots:          timeSlots,
-							IsConnected:        len(timeSlots) == 2,
+							IsConnected:        len(timeSlots) > 1,
+							Locked:             e.Locked,
 							PassedConstraints:  e.GetPassedConstraints(),
 							FailedConstraints:  e.GetFailedConstraints(),
 							SkippedConstraints: e.GetSkippedConstraints(),
+
+							HardFailedConstraints: e.GetHardFailedConstraints(),
 						}
 						chromosome.Genes = append(chromosome.Genes, gene)
 						numGenesInChromosome++
@@ -82,7 +87,7 @@ func newIndividual(classMatrix *types.ClassMatrix, schedule *models.Schedule, su
 			} else {
 				ts1 := chromosome.Genes[i].TimeSlots
 				ts2 := chromosome.Genes[j].TimeSlots
-				return ts1[0] < ts2[0] || (ts1[0] == ts2[0] && ts1[1] < ts2[1])
+				return utils.LessTimeSlots(ts1, ts2)
 			}
 		})
 
@@ -101,7 +106,7 @@ func newIndividual(classMatrix *types.ClassMatrix, schedule *models.Schedule, su
 	}
 
 	// 设置适应度
-	fitness, err := individual.evaluateFitness(classMatrix, schedule, subjects, teachers, constraintMap)
+	fitness, err := individual.evaluateFitness(classMatrix, schedule, subjects, teachers, constraintMap, reschedule)
 	if err != nil {
 		return nil, err
 	}
@@ -196,6 +201,7 @@ func (i *Individual) toClassMatrix(schedule *models.Schedule, teachingTasks []*m
 			chromosome.Genes[i].PassedConstraints = element.GetPassedConstraints()
 			chromosome.Genes[i].FailedConstraints = element.GetFailedConstraints()
 			chromosome.Genes[i].SkippedConstraints = element.GetSkippedConstraints()
+			chromosome.Genes[i].HardFailedConstraints = element.GetHardFailedConstraints()
 		}
 	}
 	score := classMatrix.SumUsedElementsScore()
@@ -227,7 +233,9 @@ func (i *Individual) sortChromosomes() {
 // Fitness: 198
 // 给normalizedScore乘以100,目的是为了提升normalizedScore的重要性
 // 给subjectDispersionScore, teacherDispersionScore 乘以10, 目的是把数据归到同一个数量级和提升两者的重要度
-func (i *Individual) evaluateFitness(classMatrix *types.ClassMatrix, schedule *models.Schedule, subjects []*models.Subject, teachers []*models.Teacher, constraintMap map[string]interface{}) (int, error) {
+// 有学生选课信息时, 每个学生课程冲突再扣除StudentClashPenalty
+// 有已发布的课表时, 每个与已发布课表不同的基因再扣除DisruptionWeight
+func (i *Individual) evaluateFitness(classMatrix *types.ClassMatrix, schedule *models.Schedule, subjects []*models.Subject, teachers []*models.Teacher, constraintMap map[string]interface{}, reschedule *Reschedule) (int, error) {
 
 	// Calculate the total score of the class matrix
 	totalScore := classMatrix.Score
@@ -242,7 +250,8 @@ func (i *Individual) evaluateFitness(classMatrix *types.ClassMatrix, schedule *m
 	// log.Printf("Normalized score: %f\n", normalizedScore)
 
 	// Calculate the subject dispersion score
-	subjectDispersionScore, err := i.calcSubjectDispersionScore(schedule, true, config.SubjectPeriodLimitThreshold)
+	periodConsistencies, _ := constraintMap["SubjectPeriodConsistency"].([]*constraints.SubjectPeriodConsistency)
+	subjectDispersionScore, err := i.calcSubjectDispersionScore(schedule, true, config.SubjectPeriodLimitThreshold, periodConsistencies)
 	if err != nil {
 		return 0, err
 	}
@@ -254,6 +263,16 @@ func (i *Individual) evaluateFitness(classMatrix *types.ClassMatrix, schedule *m
 
 	// Calculate the fitness by multiplying the normalized score by a weight and adding the dispersion scores
 	fitness := int(normalizedScore*100 + float64(subjectDispersionScore)*10 + float64(teacherDispersionScore)*10)
+
+	// 学生课程冲突, 每个冲突扣除处罚分
+	if enrollments, ok := constraintMap["StudentEnrollment"].([]*models.StudentEnrollment); ok {
+		fitness -= len(i.StudentClashes(enrollments)) * config.StudentClashPenalty
+	}
+
+	// 与已发布的课表不同的基因, 每个基因扣除处罚分
+	if reschedule != nil && len(reschedule.BaseTimetable) > 0 {
+		fitness -= i.CountDisruptedGenes(schedule, reschedule.BaseTimetable) * reschedule.DisruptionWeight
+	}
 	// log.Printf("Fitness: %d\n", fitness)
 
 	return fitness, nil
@@ -278,31 +297,29 @@ func (i *Individual) HasTimeSlotConflicts() (bool, []string) {
 	for _, chromosome := range i.Chromosomes {
 		for _, gene := range chromosome.Genes {
 
-			sn := gene.ClassSN
-			SN, _ := types.ParseSN(sn)
-
-			// 年级
-			gradeID := SN.GradeID
-			classID := SN.ClassID
-
-			// 教师
-			teacherID := gene.TeacherID
-
 			for _, timeSlot := range gene.TimeSlots {
-				// 构造 key
-				classKey := fmt.Sprintf("gradeID(%d)_classID(%d)_timeSlot(%d)", gradeID, classID, timeSlot)
-				teacherKey := fmt.Sprintf("teacherID(%d)_timeSlot(%d)", teacherID, timeSlot)
 
-				if usedClassTimeSlots[classKey] {
-					conflicts = append(conflicts, classKey)
-				} else {
-					usedClassTimeSlots[classKey] = true
+				// 班级, 包括教学班占用的行政班
+				for _, key := range gene.GetClassKeys() {
+					// 构造 key
+					classKey := fmt.Sprintf("class(%s)_timeSlot(%d)", key, timeSlot)
+
+					if usedClassTimeSlots[classKey] {
+						conflicts = append(conflicts, classKey)
+					} else {
+						usedClassTimeSlots[classKey] = true
+					}
 				}
 
-				if usedTeacherTimeSlots[teacherKey] {
-					conflicts = append(conflicts, teacherKey)
-				} else {
-					usedTeacherTimeSlots[teacherKey] = true
+				// 教师, 包括协同上课的教师
+				for _, teacherID := range gene.GetTeacherIDs() {
+					teacherKey := fmt.Sprintf("teacherID(%d)_timeSlot(%d)", teacherID, timeSlot)
+
+					if usedTeacherTimeSlots[teacherKey] {
+						conflicts = append(conflicts, teacherKey)
+					} else {
+						usedTeacherTimeSlots[teacherKey] = true
+					}
 				}
 			}
 		}
@@ -341,9 +358,9 @@ func (i *Individual) GetTimeSlotsCount() int {
 // 获取班级时间段
 func (i *Individual) getClassTimeSlots(conflict bool) (map[string][]*Gene, map[string][]*Gene) {
 
-	classKeyFunc := func(gene *Gene) string {
-		SN, _ := types.ParseSN(gene.ClassSN)
-		return fmt.Sprintf("%d_%d", SN.GradeID, SN.ClassID)
+	// 教学班占用的行政班也需要统计
+	classKeyFunc := func(gene *Gene) []string {
+		return gene.GetClassKeys()
 	}
 	connected, normal := i.getTimeSlots(conflict, classKeyFunc)
 	return connected, normal
@@ -361,15 +378,35 @@ func (i *Individual) getClassTimeSlots(conflict bool) (map[string][]*Gene, map[s
 // 获取教师时间段
 func (i *Individual) getTeacherTimeSlots(conflict bool) (map[string][]*Gene, map[string][]*Gene) {
 
-	teacherKeyFunc := func(gene *Gene) string {
-		return cast.ToString(gene.TeacherID)
+	// 协同上课的教师也需要统计
+	teacherKeyFunc := func(gene *Gene) []string {
+		return lo.Map(gene.GetTeacherIDs(), func(teacherID int, _ int) string {
+			return cast.ToString(teacherID)
+		})
 	}
 	connected, normal := i.getTimeSlots(conflict, teacherKeyFunc)
 	return connected, normal
 }
 
 // 获取key函数
-type KeyFunc func(gene *Gene) string
+// 一个基因可能对应多个key, 如: 协同上课的多个教师
+type KeyFunc func(gene *Gene) []string
+
+// 获取个体中的所有基因, 锁定的基因排在前面
+func (i *Individual) genesLockedFirst() []*Gene {
+
+	var locked, unlocked []*Gene
+	for _, chromosome := range i.Chromosomes {
+		for _, gene := range chromosome.Genes {
+			if gene.Locked {
+				locked = append(locked, gene)
+			} else {
+				unlocked = append(unlocked, gene)
+			}
+		}
+	}
+	return append(locked, unlocked...)
+}
 
 // 获取已经使用,和冲突的时间段
 func (i *Individual) getTimeSlots(conflict bool, keyFunc KeyFunc) (map[string][]*Gene, map[string][]*Gene) {
@@ -385,32 +422,42 @@ func (i *Individual) getTimeSlots(conflict bool, keyFunc KeyFunc) (map[string][]
 	// 已使用
 	usage := make(map[string]map[int]bool)
 
-	for _, chromosome := range i.Chromosomes {
-		for _, gene := range chromosome.Genes {
+	// 锁定的基因优先占用时间段, 冲突时只修复未锁定的基因
+	for _, gene := range i.genesLockedFirst() {
 
-			key := keyFunc(gene)
+		usageMap, conflictMap := usageNormal, conflictNormal
+		if gene.IsConnected {
+			usageMap, conflictMap = usageConnected, conflictConnected
+		}
+
+		// 冲突的基因只记录在第一个冲突的key下, 避免重复修复
+		keys := keyFunc(gene)
+		conflictKey := ""
+		for _, key := range keys {
 			if usage[key] == nil {
 				usage[key] = make(map[int]bool)
 			}
 
-			if gene.IsConnected {
-				ts0, ts1 := gene.TimeSlots[0], gene.TimeSlots[1]
-				if usage[key][ts0] || usage[key][ts1] {
-					conflictConnected[key] = append(conflictConnected[key], gene)
-				} else {
-					usageConnected[key] = append(usageConnected[key], gene)
-				}
-				usage[key][ts0], usage[key][ts1] = true, true
-			} else {
-				ts0 := gene.TimeSlots[0]
-				if usage[key][ts0] {
-					conflictNormal[key] = append(conflictNormal[key], gene)
-				} else {
-					usageNormal[key] = append(usageNormal[key], gene)
-				}
-				usage[key][ts0] = true
+			isUsed := lo.SomeBy(gene.TimeSlots, func(ts int) bool {
+				return usage[key][ts]
+			})
+			if isUsed && conflictKey == "" {
+				conflictKey = key
 			}
+		}
+
+		for _, key := range keys {
+			if conflictKey == "" {
+				usageMap[key] = append(usageMap[key], gene)
+			}
+
+			for _, ts := range gene.TimeSlots {
+				usage[key][ts] = true
+			}
+		}
 
+		if conflictKey != "" {
+			conflictMap[conflictKey] = append(conflictMap[conflictKey], gene)
 		}
 	}
 
@@ -427,7 +474,7 @@ func (i *Individual) getTimeSlots(conflict bool, keyFunc KeyFunc) (map[string][]
 func (i *Individual) getClassValidTimeSlots(schedule *models.Schedule, constr []*constraints.Class) (map[string][]string, map[string][]string) {
 
 	// 全部时间段
-	allConnected := utils.GetAllConnectedTimeSlots(schedule)
+	allConnected := i.getAllConnectedTimeSlots(schedule)
 	allNormal := utils.GetAllNormalTimeSlots(schedule)
 
 	// 班级已使用的时间段
@@ -455,7 +502,7 @@ func (i *Individual) getClassValidTimeSlots(schedule *models.Schedule, constr []
 func (i *Individual) getTeacherValidTimeSlots(schedule *models.Schedule, teachers []*models.Teacher, constr []*constraints.Teacher) (map[string][]string, map[string][]string, error) {
 
 	// 全部时间段
-	allConnected := utils.GetAllConnectedTimeSlots(schedule)
+	allConnected := i.getAllConnectedTimeSlots(schedule)
 	allNormal := utils.GetAllNormalTimeSlots(schedule)
 
 	// 教师已使用的时间段
@@ -484,6 +531,27 @@ func (i *Individual) getTeacherValidTimeSlots(schedule *models.Schedule, teacher
 	return connected, normal, nil
 }
 
+// 全部的连堂课时间段
+// 包含个体中所有连堂课节数(2节, 3节...)的时间段
+func (i *Individual) getAllConnectedTimeSlots(schedule *models.Schedule) []string {
+
+	var lengths []int
+	for _, chromosome := range i.Chromosomes {
+		for _, gene := range chromosome.Genes {
+			if gene.IsConnected && !lo.Contains(lengths, len(gene.TimeSlots)) {
+				lengths = append(lengths, len(gene.TimeSlots))
+			}
+		}
+	}
+	sort.Ints(lengths)
+
+	var timeSlotStrs []string
+	for _, length := range lengths {
+		timeSlotStrs = append(timeSlotStrs, utils.GetAllConnectedTimeSlotsWithLength(schedule, length)...)
+	}
+	return timeSlotStrs
+}
+
 // 从教师可用时间中过滤掉教师的禁排时间
 // timeSlotsMap key: teacherID, value: 时间段列表
 func (i *Individual) filterTeacherTimeSlots(timeSlotsMap map[string][]string, teachers []*models.Teacher, constr []*constraints.Teacher) (map[string][]string, error) {
@@ -550,7 +618,13 @@ func (i *Individual) filterClassTimeSlots(timeSlotsMap map[string][]string, cons
 //     班级 普通课 冲突时间段修复 类似
 //     教师 连堂课 冲突时间段修复 类似
 //     教师 普通课 冲突时间段修复 类似
-func (i *Individual) resolveConflicts(schedule *models.Schedule, teachers []*models.Teacher, constr1 []*constraints.Class, constr2 []*constraints.Teacher) (int, error) {
+//
+//     教师有跨教学楼上课限制(禁止)时, 修复后的时间段还需要来得及往返教学楼
+func (i *Individual) resolveConflicts(schedule *models.Schedule, teachers []*models.Teacher, constraintMap map[string]interface{}) (int, error) {
+
+	constr1 := constraintMap["Class"].([]*constraints.Class)
+	constr2 := constraintMap["Teacher"].([]*constraints.Teacher)
+	isTravelValid := i.travelValidFunc(schedule, teachers, constraintMap)
 
 	// fmt.Println("===== 修复前")
 	i.PrintTimeSlots(false)
@@ -624,14 +698,14 @@ func (i *Individual) resolveConflicts(schedule *models.Schedule, teachers []*mod
 
 	// 修复班级连堂课,普通课冲突
 	// fmt.Printf("===== %p 开始修复 班级连堂课\n", i)
-	count1, err1 := i.resolveClassConflict(classConnectedConflictGenes, classValidTime, teacherValidTime)
+	count1, err1 := i.resolveClassConflict(classConnectedConflictGenes, classValidTime, teacherValidTime, isTravelValid)
 	if err1 != nil {
 		return 0, fmt.Errorf("resolve class connected conflicts failed. err1: %v", err1)
 	}
 	// fmt.Printf("===== %p 修复 班级连堂课成功: %d\n", i, count1)
 
 	// fmt.Printf("===== %p 开始修复 班级普通课\n", i)
-	count2, err2 := i.resolveClassConflict(classNormalConflictGenes, classValidTime, teacherValidTime)
+	count2, err2 := i.resolveClassConflict(classNormalConflictGenes, classValidTime, teacherValidTime, isTravelValid)
 	if err2 != nil {
 		return 0, fmt.Errorf("resolve class normal conflicts failed. err2: %v", err2)
 	}
@@ -639,14 +713,14 @@ func (i *Individual) resolveConflicts(schedule *models.Schedule, teachers []*mod
 
 	// 修复教师连堂课,普通课冲突
 	// fmt.Printf("===== %p 开始修复 教师连堂课\n", i)
-	count3, err3 := i.resolveTeacherConflict(teacherConnectedConflictGenes, classValidTime, teacherValidTime)
+	count3, err3 := i.resolveTeacherConflict(teacherConnectedConflictGenes, teacherValidTime, classValidTime, isTravelValid)
 	if err3 != nil {
 		return 0, fmt.Errorf("resolve teacher connected conflicts failed. err3: %v", err3)
 	}
 	// fmt.Printf("===== %p 修复 教师连堂课成功: %d\n", i, count3)
 
 	// fmt.Printf("===== %p 开始修复 教师普通课\n", i)
-	count4, err4 := i.resolveTeacherConflict(teacherNormalConflictGenes, classValidTime, teacherValidTime)
+	count4, err4 := i.resolveTeacherConflict(teacherNormalConflictGenes, teacherValidTime, classValidTime, isTravelValid)
 	if err4 != nil {
 		return 0, fmt.Errorf("resolve teacher normal conflicts failed. err4: %v", err4)
 	}
@@ -670,13 +744,19 @@ func (i *Individual) resolveConflicts(schedule *models.Schedule, teachers []*mod
 }
 
 // resolveClassConflict 用于解决班级的课程表冲突
-func (i *Individual) resolveClassConflict(conflictMap map[string][]*Gene, classValidTime map[string][]string, teacherValidTime map[string][]string) (int, error) {
+func (i *Individual) resolveClassConflict(conflictMap map[string][]*Gene, classValidTime map[string][]string, teacherValidTime map[string][]string, isTravelValid TravelValidFunc) (int, error) {
 
 	// fmt.Printf("开始执行 resolve class conflict, conflictMap: %v, classValidTime: %v, teacherValidTime: %v\n", conflictMap, classValidTime, teacherValidTime)
 
 	count := 0
 	for key, conflictList := range conflictMap {
 		for _, gene := range conflictList {
+
+			// 锁定的基因不能移动, 锁定的基因之间冲突时无法修复
+			if gene.Locked {
+				return count, fmt.Errorf("resolve class conflict failed. locked gene: %#v", gene)
+			}
+
 			repaired := false
 			teacherIDStr := cast.ToString(gene.TeacherID)
 			// 年级可用时间段
@@ -691,7 +771,8 @@ func (i *Individual) resolveClassConflict(conflictMap map[string][]*Gene, classV
 
 				// 找到一个班级可用的时间段，并且教师也可用
 				ts := utils.ParseTimeSlotStr(str)
-				if ((gene.IsConnected && len(ts) == 2) || (!gene.IsConnected && len(ts) == 1)) && lo.Contains(teacherValidList, str) {
+				// 时间段的节数需要和基因相同(普通课1节, 连堂课2节或者多节)
+				if len(ts) == len(gene.TimeSlots) && lo.Contains(teacherValidList, str) && isTeachersValidTime(gene, teacherValidTime, str) && isClassesValidTime(gene, classValidTime, str) && isTravelValid(gene, str) {
 
 					// 更新班级和教师的可用时间段
 					newTimeSlots := utils.ParseTimeSlotStr(str)
@@ -704,12 +785,12 @@ func (i *Individual) resolveClassConflict(conflictMap map[string][]*Gene, classV
 
 					// 从班级可用时间段中移除
 					// fmt.Printf("删除 classValidTime key: %s, 删除前: %v", key, classValidTime[key])
-					classValidTime[key] = utils.RemoveRelatedItems(classValidTime[key], str)
+					removeClassesValidTime(gene, classValidTime, str)
 					// fmt.Printf(" 删除后: %v\n", classValidTime[key])
 
 					// 从教师可用时间段中移除
 					// fmt.Printf("删除 teacherValidTime teacherIDStr: %s, 删除前: %v", key, teacherValidTime[teacherIDStr])
-					teacherValidTime[teacherIDStr] = utils.RemoveRelatedItems(teacherValidTime[teacherIDStr], str)
+					removeTeachersValidTime(gene, teacherValidTime, str)
 					// fmt.Printf(" 删除后: %v\n", teacherValidTime[teacherIDStr])
 
 					// // 如果是连堂课,则将连堂课对应的普通课时间段删掉
@@ -746,26 +827,26 @@ func (i *Individual) resolveClassConflict(conflictMap map[string][]*Gene, classV
 }
 
 // resolveTeacherConflict 用于解决教师的课程表冲突
-func (i *Individual) resolveTeacherConflict(conflictMap map[string][]*Gene, teacherValidTime map[string][]string, classValidTime map[string][]string) (int, error) {
+func (i *Individual) resolveTeacherConflict(conflictMap map[string][]*Gene, teacherValidTime map[string][]string, classValidTime map[string][]string, isTravelValid TravelValidFunc) (int, error) {
 
 	count := 0
 	for key, conflictList := range conflictMap {
 
 		for _, gene := range conflictList {
 
-			SN, _ := types.ParseSN(gene.ClassSN)
-			gradeID := SN.GradeID
-			classID := SN.ClassID
-			classKey := fmt.Sprintf("%d_%d", gradeID, classID)
+			// 锁定的基因不能移动, 锁定的基因之间冲突时无法修复
+			if gene.Locked {
+				return count, fmt.Errorf("resolve teacher conflict failed. locked gene: %#v", gene)
+			}
 
 			repaired := false
-			teacherIDStr := cast.ToString(gene.TeacherID)
 			teacherValidList := teacherValidTime[key]
-			classValidList := classValidTime[classKey]
 			for _, str := range teacherValidList {
 
 				// 找到一个可教师用的时间段，并且班级也可用
-				if lo.Contains(classValidList, str) {
+				// 时间段的节数需要和基因相同
+				ts := utils.ParseTimeSlotStr(str)
+				if len(ts) == len(gene.TimeSlots) && isClassesValidTime(gene, classValidTime, str) && isTeachersValidTime(gene, teacherValidTime, str) && isTravelValid(gene, str) {
 
 					// 更新基因的时间段
 					gene.TimeSlots = utils.ParseTimeSlotStr(str)
@@ -773,9 +854,9 @@ func (i *Individual) resolveTeacherConflict(conflictMap map[string][]*Gene, teac
 					count++
 
 					// 从班级可用时间段中移除
-					classValidTime[classKey] = utils.RemoveRelatedItems(classValidTime[classKey], str)
+					removeClassesValidTime(gene, classValidTime, str)
 					// 从教师可用时间段中移除
-					teacherValidTime[teacherIDStr] = utils.RemoveRelatedItems(teacherValidTime[teacherIDStr], str)
+					removeTeachersValidTime(gene, teacherValidTime, str)
 					break
 				}
 			}
@@ -790,6 +871,74 @@ func (i *Individual) resolveTeacherConflict(conflictMap map[string][]*Gene, teac
 	return count, nil
 }
 
+// 判断时间段对基因占用的所有班级(包括教学班占用的行政班)是否可用
+func isClassesValidTime(gene *Gene, classValidTime map[string][]string, str string) bool {
+	return lo.EveryBy(gene.GetClassKeys(), func(key string) bool {
+		return lo.Contains(classValidTime[key], str)
+	})
+}
+
+// 从基因占用的所有班级(包括教学班占用的行政班)的可用时间段中移除
+func removeClassesValidTime(gene *Gene, classValidTime map[string][]string, str string) {
+	for _, key := range gene.GetClassKeys() {
+		classValidTime[key] = utils.RemoveRelatedItems(classValidTime[key], str)
+	}
+}
+
+// 判断时间段对基因的所有教师(包括协同上课的教师)是否可用
+func isTeachersValidTime(gene *Gene, teacherValidTime map[string][]string, str string) bool {
+	return lo.EveryBy(gene.GetTeacherIDs(), func(teacherID int) bool {
+		return lo.Contains(teacherValidTime[cast.ToString(teacherID)], str)
+	})
+}
+
+// 从基因的所有教师(包括协同上课的教师)的可用时间段中移除
+func removeTeachersValidTime(gene *Gene, teacherValidTime map[string][]string, str string) {
+	for _, teacherID := range gene.GetTeacherIDs() {
+		teacherIDStr := cast.ToString(teacherID)
+		teacherValidTime[teacherIDStr] = utils.RemoveRelatedItems(teacherValidTime[teacherIDStr], str)
+	}
+}
+
+// 判断基因排在时间段时, 教师是否来得及往返教学楼
+type TravelValidFunc func(gene *Gene, str string) bool
+
+// 生成教师跨教学楼上课的检查函数
+// 只检查限制类型为禁止的教师, 与个体中该教师的其他课程比较
+func (i *Individual) travelValidFunc(schedule *models.Schedule, teachers []*models.Teacher, constraintMap map[string]interface{}) TravelValidFunc {
+
+	constr, _ := constraintMap["TeacherTravel"].([]*constraints.TeacherTravel)
+	venues, _ := constraintMap["Venue"].([]*models.Venue)
+	travelTimes, _ := constraintMap["TravelTime"].([]*models.TravelTime)
+
+	return func(gene *Gene, str string) bool {
+
+		if len(constr) == 0 || len(travelTimes) == 0 {
+			return true
+		}
+
+		timeSlots := utils.ParseTimeSlotStr(str)
+		for _, teacherID := range gene.GetTeacherIDs() {
+			if !constraints.IsTeacherTravelForbidden(teacherID, teachers, constr) {
+				continue
+			}
+
+			for _, chromosome := range i.Chromosomes {
+				for _, other := range chromosome.Genes {
+					if other == gene || !lo.Contains(other.GetTeacherIDs(), teacherID) {
+						continue
+					}
+
+					if models.IsTravelTooShort(schedule, venues, travelTimes, gene.VenueID, timeSlots, other.VenueID, other.TimeSlots) {
+						return false
+					}
+				}
+			}
+		}
+		return true
+	}
+}
+
 // 冲突去重
 // 从教师冲突中去重, 即如果既在班级冲突中存在, 又在教师冲突中存在的基因, 则从教师冲突中删除
 func (individual *Individual) rejectConflictGenes(teacherConflictGenes map[string][]*Gene, classConflictGenes map[string][]*Gene) {
@@ -864,7 +1013,8 @@ func calcStandardDeviation(schedule *models.Schedule, timeSlotsMap map[string][]
 }
 
 // 计算一个个体（全校所有年级所有班级的课程表）的科目分散度
-func (i Individual) calcSubjectDispersionScore(schedule *models.Schedule, punishSamePeriod bool, samePeriodThreshold int) (float64, error) {
+// periodConsistencies 科目固定节次约束, 受约束的课班需要排在相同的节次, 不计入相同节次的惩罚
+func (i Individual) calcSubjectDispersionScore(schedule *models.Schedule, punishSamePeriod bool, samePeriodThreshold int, periodConsistencies []*constraints.SubjectPeriodConsistency) (float64, error) {
 	// 调用 calcSubjectStandardDeviation 方法计算每个班级的科目分散度
 	classSubjectStdDev, err := i.calcSubjectStandardDeviation(schedule)
 	if err != nil {
@@ -882,12 +1032,19 @@ func (i Individual) calcSubjectDispersionScore(schedule *models.Schedule, punish
 	}
 
 	// 统计每节课出现的课程数量
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
 	periodCount := make(map[int]int)
 	for _, chromosome := range i.Chromosomes {
+		SN, err := types.ParseSN(chromosome.ClassSN)
+		if err != nil {
+			return 0.0, err
+		}
+		if constraints.IsSubjectPeriodConsistencyMatched(SN.SubjectID, SN.GradeID, SN.ClassID, periodConsistencies) {
+			continue
+		}
+
 		for _, gene := range chromosome.Genes {
 			for _, timeSlot := range gene.TimeSlots {
-				period := timeSlot % totalClassesPerDay
+				period := schedule.GetTimeSlot(timeSlot).Period
 				periodCount[period]++
 			}
 		}
@@ -918,7 +1075,7 @@ func (i *Individual) calcTeacherDispersionScore(schedule *models.Schedule) float
 
 	teacherDispersion := make(map[int]map[int]bool) // 记录每个教师在每个时间段是否已经排课
 	teacherCount := make(map[int]int)               // 记录每个教师的课时数
-	totalClassesPerWeek := schedule.TotalClassesPerWeek()
+	weekTimeSlots := schedule.GenWeekTimeSlots()
 
 	// 遍历每个基因，统计每个教师在每个时间段的排课情况
 	for _, chromosome := range i.Chromosomes {
@@ -927,8 +1084,8 @@ func (i *Individual) calcTeacherDispersionScore(schedule *models.Schedule) float
 			teacherCount[teacherID]++
 			if teacherDispersion[teacherID] == nil {
 				teacherDispersion[teacherID] = make(map[int]bool)
-				for i := 0; i < totalClassesPerWeek; i++ {
-					teacherDispersion[teacherID][i] = false
+				for _, timeSlot := range weekTimeSlots {
+					teacherDispersion[teacherID][timeSlot] = false
 				}
 			}
 			for _, timeSlot := range gene.TimeSlots {
@@ -997,8 +1154,9 @@ func (i *Individual) PrintSchedule(schedule *models.Schedule, subjects []*models
 		for _, gene := range chromosome.Genes {
 			for _, timeSlot := range gene.TimeSlots {
 				countMap[gradeAndClass]++
-				day := timeSlot / totalClassesPerDay
-				period := timeSlot % totalClassesPerDay
+				ts := schedule.GetTimeSlot(timeSlot)
+				day := ts.Day
+				period := ts.Period
 
 				subject, err := models.FindSubjectByID(SN.SubjectID, subjects)
 				if err != nil {
@@ -1053,19 +1211,46 @@ func (i *Individual) PrintSchedule(schedule *models.Schedule, subjects []*models
 	for _, gradeAndClass := range keys {
 
 		log.Printf("课程表(%s): 共%d节课\n", gradeAndClass, countMap[gradeAndClass])
-		fmt.Println("   |", strings.Join(getWeekdays(), " | "), "|")
-		fmt.Println("---+-------------------------------------------")
+		fmt.Println("               |", strings.Join(getWeekdays(), " | "), "|")
+		fmt.Println("---------------+-------------------------------------------")
 		for c := 0; c < totalClassesPerDay; c++ {
-			fmt.Printf("%-2d |", c+1)
+
+			// 节次和上下课时间, 以星期一的作息时间为准
+			startTime, endTime := schedule.GetPeriodTime(0, c)
+			if startTime != "" {
+				fmt.Printf("%-2d %s-%s |", c+1, startTime, endTime)
+			} else {
+				fmt.Printf("%-14d |", c+1)
+			}
+
 			for d := 0; d < numWorkdays; d++ {
 				class, ok := scheduleMap[gradeAndClass][d][c]
 				if !ok {
 					class = ""
 				}
+
+				// 不存在的时间段
+				if !schedule.IsTimeSlotAvailable(schedule.GetTimeSlotIndex(d, c)) {
+					class = "×"
+				}
+
+				// 当天的上课时间和星期一不同时, 标记出当天的上课时间
+				dayStartTime, _ := schedule.GetPeriodTime(d, c)
+				if class != "" && dayStartTime != startTime {
+					class = fmt.Sprintf("%s@%s", class, dayStartTime)
+				}
 				fmt.Printf(" %-16s |", class)
 			}
 			fmt.Println()
-			fmt.Println("---+-------------------------------------------")
+
+			// 课间休息
+			if bellSchedule := schedule.GetBellSchedule(0); bellSchedule != nil {
+				if breakTime := bellSchedule.FindBreakTime(c + 1); breakTime != nil {
+					fmt.Printf("---------------+---- %s %s-%s ----\n", breakTime.Name, breakTime.StartTime, breakTime.EndTime)
+					continue
+				}
+			}
+			fmt.Println("---------------+-------------------------------------------")
 		}
 		fmt.Println()
 	}
@@ -1168,7 +1353,7 @@ func getDifference(usageMap map[string][]string, usageMap1 map[string][]string,
 		sort.Slice(resultMap[key], func(i, j int) bool {
 			ts1 := utils.ParseTimeSlotStr(resultMap[key][i])
 			ts2 := utils.ParseTimeSlotStr(resultMap[key][j])
-			return ts1[0] < ts2[0] || (ts1[0] == ts2[0] && ts1[1] < ts2[1])
+			return utils.LessTimeSlots(ts1, ts2)
 		})
 	}
 	return resultMap
@@ -1188,7 +1373,7 @@ func getTimeSlotsFromGenes(genesMap map[string][]*Gene) map[string][]string {
 		sort.Slice(timeSlotMap[key], func(i, j int) bool {
 			ts1 := utils.ParseTimeSlotStr(timeSlotMap[key][i])
 			ts2 := utils.ParseTimeSlotStr(timeSlotMap[key][j])
-			return ts1[0] < ts2[0] || (ts1[0] == ts2[0] && ts1[1] < ts2[1])
+			return utils.LessTimeSlots(ts1, ts2)
 		})
 	}
 	return timeSlotMap
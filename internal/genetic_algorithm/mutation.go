@@ -29,12 +29,13 @@ import (
 //	grades: 年级信息
 //	subjectVenueMap: 科目与教学场地
 //	constraintMap: 约束条件
+//	reschedule: 部分重排参数, 不是部分重排时为空
 //
 // 返回值:
 //
 //	返回 交叉后的个体、准备交叉次数、实际交叉次数、错误信息
 
-func Mutation(selected []*Individual, mutationRate float64, schedule *models.Schedule, teachingTasks []*models.TeachingTask, subjects []*models.Subject, teachers []*models.Teacher, grades []*models.Grade, venueMap map[string][]int, constraintMap map[string]interface{}) ([]*Individual, int, int, error) {
+func Mutation(selected []*Individual, mutationRate float64, schedule *models.Schedule, teachingTasks []*models.TeachingTask, subjects []*models.Subject, teachers []*models.Teacher, grades []*models.Grade, venueMap map[string][]int, constraintMap map[string]interface{}, reschedule *Reschedule) ([]*Individual, int, int, error) {
 
 	prepared := 0
 	executed := 0
@@ -44,16 +45,15 @@ func Mutation(selected []*Individual, mutationRate float64, schedule *models.Sch
 			prepared++
 
 			// 变异的个体
-			// 随机选择染色体和基因索引进行突变
-			chromosomeIndex := rand.Intn(len(selected[i].Chromosomes))
-			geneIndex := rand.Intn(len(selected[i].Chromosomes[chromosomeIndex].Genes))
-
-			// 获取要突变的染色体和基因
-			chromosome := selected[i].Chromosomes[chromosomeIndex]
-			gene := chromosome.Genes[geneIndex]
+			// 随机选择未锁定的染色体和基因进行突变
+			chromosome, gene := randomUnlockedGene(selected[i])
+			if gene == nil {
+				log.Printf("mutation failed. err: all genes are locked\n")
+				continue
+			}
 
 			// 基因变异和校验
-			err := mutationAndValidate(selected[i], chromosome, gene, schedule, teachingTasks, subjects, teachers, venueMap, constraintMap)
+			err := mutationAndValidate(selected[i], chromosome, gene, schedule, teachingTasks, subjects, teachers, venueMap, constraintMap, reschedule)
 			if err != nil {
 				log.Printf("mutation failed. err: %v\n", err)
 			} else {
@@ -67,16 +67,16 @@ func Mutation(selected []*Individual, mutationRate float64, schedule *models.Sch
 }
 
 // mutationAndValidate 可行性验证 用于验证染色体上的基因在进行基因变异更换时是否符合基因的约束条件
-func mutationAndValidate(individual *Individual, chromosome *Chromosome, gene *Gene, schedule *models.Schedule, teachingTasks []*models.TeachingTask, subjects []*models.Subject, teachers []*models.Teacher, venueMap map[string][]int, constraintMap map[string]interface{}) error {
+func mutationAndValidate(individual *Individual, chromosome *Chromosome, gene *Gene, schedule *models.Schedule, teachingTasks []*models.TeachingTask, subjects []*models.Subject, teachers []*models.Teacher, venueMap map[string][]int, constraintMap map[string]interface{}, reschedule *Reschedule) error {
 
-	err := mutationGene(individual, chromosome, gene, schedule, teachingTasks, subjects, teachers, venueMap, constraintMap)
+	err := mutationGene(individual, chromosome, gene, schedule, teachingTasks, subjects, teachers, venueMap, constraintMap, reschedule)
 
 	// 校验的过程...
 	return err
 }
 
 // 基因变异
-func mutationGene(individual *Individual, chromosome *Chromosome, gene *Gene, schedule *models.Schedule, teachingTasks []*models.TeachingTask, subjects []*models.Subject, teachers []*models.Teacher, venueMap map[string][]int, constraintMap map[string]interface{}) error {
+func mutationGene(individual *Individual, chromosome *Chromosome, gene *Gene, schedule *models.Schedule, teachingTasks []*models.TeachingTask, subjects []*models.Subject, teachers []*models.Teacher, venueMap map[string][]int, constraintMap map[string]interface{}, reschedule *Reschedule) error {
 
 	constr1 := constraintMap["Class"].([]*constraints.Class)
 	constr2 := constraintMap["Teacher"].([]*constraints.Teacher)
@@ -103,7 +103,7 @@ func mutationGene(individual *Individual, chromosome *Chromosome, gene *Gene, sc
 	}
 
 	// 修复个体时间段冲突
-	_, err = individual.resolveConflicts(schedule, teachers, constr1, constr2)
+	_, err = individual.resolveConflicts(schedule, teachers, constraintMap)
 	if err != nil {
 		return err
 	}
@@ -117,7 +117,7 @@ func mutationGene(individual *Individual, chromosome *Chromosome, gene *Gene, sc
 		return err
 	}
 
-	newFitness, err := individual.evaluateFitness(classMatrix, schedule, subjects, teachers, constraintMap)
+	newFitness, err := individual.evaluateFitness(classMatrix, schedule, subjects, teachers, constraintMap, reschedule)
 	if err != nil {
 		return err
 	}
@@ -179,10 +179,28 @@ func findRandomScheduleForGene(individual *Individual, chromosome *Chromosome, g
 	// 即是班级可用的时间段,又是教师可用的时间段
 	if isConnected {
 		timeSlotStrs = lo.Intersect(classConnected[classKey], teacherConnected[teacherIDStr])
+
+		// 连堂课的节数需要和基因相同
+		timeSlotStrs = lo.Filter(timeSlotStrs, func(str string, _ int) bool {
+			return len(utils.ParseTimeSlotStr(str)) == len(gene.TimeSlots)
+		})
 	} else {
 		timeSlotStrs = lo.Intersect(classNormal[classKey], teacherNormal[teacherIDStr])
 	}
 
+	// 教学班占用的行政班也需要可用
+	for _, key := range gene.GetClassKeys() {
+		if key != classKey {
+			timeSlotStrs = lo.Intersect(timeSlotStrs, concatTimeSlotStrs(classConnected[key], classNormal[key]))
+		}
+	}
+
+	// 协同上课的教师也需要可用
+	for _, coTeacherID := range gene.CoTeacherIDs {
+		coTeacherIDStr := cast.ToString(coTeacherID)
+		timeSlotStrs = lo.Intersect(timeSlotStrs, concatTimeSlotStrs(teacherConnected[coTeacherIDStr], teacherNormal[coTeacherIDStr]))
+	}
+
 	// 随机从可用时间段中取一个
 	timeSlotStrVal, err := randomSample(timeSlotStrs)
 	if err != nil {
@@ -193,6 +211,35 @@ func findRandomScheduleForGene(individual *Individual, chromosome *Chromosome, g
 	return teacherID, venueID, timeSlotStr, nil
 }
 
+// 合并连堂课和普通课的可用时间段
+// 返回新的切片, 不能直接append到map中的切片上, 否则会修改其他班级或教师的可用时间段
+func concatTimeSlotStrs(connected, normal []string) []string {
+	return append(append([]string{}, connected...), normal...)
+}
+
+// 随机获取个体中未锁定的基因, 以及基因所在的染色体
+// 部分重排时, 锁定的基因不能变异
+func randomUnlockedGene(individual *Individual) (*Chromosome, *Gene) {
+
+	var chromosomes []*Chromosome
+	var genes []*Gene
+	for _, chromosome := range individual.Chromosomes {
+		for _, gene := range chromosome.Genes {
+			if !gene.Locked {
+				chromosomes = append(chromosomes, chromosome)
+				genes = append(genes, gene)
+			}
+		}
+	}
+
+	if len(genes) == 0 {
+		return nil, nil
+	}
+
+	index := rand.Intn(len(genes))
+	return chromosomes[index], genes[index]
+}
+
 // 随机获取基因中未使用的教师ID
 func randomIdleTeacherID(chromosome *Chromosome, gene *Gene, teachers []*models.Teacher) (int, error) {
 
@@ -206,7 +253,8 @@ func randomIdleTeacherID(chromosome *Chromosome, gene *Gene, teachers []*models.
 	classID := SN.ClassID
 
 	teacherID := 0
-	teacherIDs := models.ClassTeacherIDs(gradeID, classID, subjectID, teachers)
+	// 协同上课的教师, 不作为上课教师
+	teacherIDs := lo.Without(models.ClassTeacherIDs(gradeID, classID, subjectID, teachers), gene.CoTeacherIDs...)
 	unusedTeacherIDs := make([]int, 0)
 
 	// 找到闲置的老师
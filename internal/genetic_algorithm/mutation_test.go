@@ -0,0 +1,28 @@
+package genetic_algorithm
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 合并可用时间段时不能修改map中原有的切片
+func TestConcatTimeSlotStrs(t *testing.T) {
+
+	connected := make([]string, 1, 4)
+	connected[0] = "0_1"
+	teacherConnected := map[string][]string{"1": connected, "2": connected[:1]}
+
+	got := concatTimeSlotStrs(teacherConnected["1"], []string{"5"})
+	if !reflect.DeepEqual(got, []string{"0_1", "5"}) {
+		t.Errorf("got %v", got)
+	}
+
+	// 再次合并不会覆盖上一次的结果
+	concatTimeSlotStrs(teacherConnected["2"], []string{"6"})
+	if !reflect.DeepEqual(got, []string{"0_1", "5"}) || len(teacherConnected["1"]) != 1 || cap(teacherConnected["1"]) != 4 {
+		t.Errorf("slice changed: %v, %v", got, teacherConnected["1"])
+	}
+	if extended := teacherConnected["1"][:2]; extended[1] != "" {
+		t.Errorf("backing array changed: %v", extended)
+	}
+}
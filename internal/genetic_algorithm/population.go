@@ -9,19 +9,29 @@ import (
 	"fmt"
 	"log"
 	"sort"
+
+	"github.com/samber/lo"
 )
 
 // 初始化种群
-func InitPopulation(populationSize int, schedule *models.Schedule, teachingTasks []*models.TeachingTask, subjects []*models.Subject, teachers []*models.Teacher, subjectVenueMap map[string][]int, constraints map[string]interface{}) ([]*Individual, error) {
+func InitPopulation(populationSize int, schedule *models.Schedule, teachingTasks []*models.TeachingTask, subjects []*models.Subject, teachers []*models.Teacher, subjectVenueMap map[string][]int, constraints map[string]interface{}, reschedule *Reschedule) ([]*Individual, error) {
 
 	population := make([]*Individual, populationSize)
 	errChan := make(chan error, populationSize)
 
+	// 有已发布的课表时, 第一个个体以已发布的课表为基础生成, 减少课表的变化
+	seedEntries := getSeedTimetableEntries(teachingTasks, reschedule)
+
 	for i := 0; i < populationSize; i++ {
 		go func(i int) {
 			log.Printf("Initializing individual %d\n", i+1)
 
-			individual, err := createIndividual(schedule, teachingTasks, subjects, teachers, subjectVenueMap, constraints)
+			var entries []*models.TimetableEntry
+			if i == 0 {
+				entries = seedEntries
+			}
+
+			individual, err := createIndividual(schedule, teachingTasks, subjects, teachers, subjectVenueMap, constraints, reschedule, entries)
 			if err != nil {
 				errChan <- err
 				return
@@ -194,7 +204,8 @@ func CheckConflicts(population []*Individual) bool {
 // ============================================
 
 // 创建个体
-func createIndividual(schedule *models.Schedule, teachingTasks []*models.TeachingTask, subjects []*models.Subject, teachers []*models.Teacher, subjectVenueMap map[string][]int, constraints map[string]interface{}) (*Individual, error) {
+// seedEntries 生成个体时先占用的课, 为空时随机生成个体
+func createIndividual(schedule *models.Schedule, teachingTasks []*models.TeachingTask, subjects []*models.Subject, teachers []*models.Teacher, subjectVenueMap map[string][]int, constraints map[string]interface{}, reschedule *Reschedule, seedEntries []*models.TimetableEntry) (*Individual, error) {
 	allocated := false
 	classMatrix, err := types.NewClassMatrix(schedule, teachingTasks, subjects, teachers, subjectVenueMap)
 	if err != nil {
@@ -207,6 +218,22 @@ func createIndividual(schedule *models.Schedule, teachingTasks []*models.Teachin
 			return nil, err
 		}
 
+		// 部分重排时, 先锁定已排的课
+		if reschedule != nil {
+			if err := classMatrix.Lock(reschedule.LockedEntries); err != nil {
+				return nil, err
+			}
+		}
+
+		// 先占用已发布课表中的课, 无法占用时(如: 教师, 教室发生变化)随机生成个体
+		if len(seedEntries) > 0 {
+			if err := classMatrix.Occupy(seedEntries); err != nil {
+				log.Printf("occupy seed timetable entries failed. retry: %d, err: %s\n", retry, err)
+				seedEntries = nil
+				continue
+			}
+		}
+
 		calcFixedScores(classMatrix, subjects, teachers, schedule, teachingTasks, constraints)
 		calcDynamicScores(classMatrix, schedule, teachingTasks, constraints)
 
@@ -225,7 +252,30 @@ func createIndividual(schedule *models.Schedule, teachingTasks []*models.Teachin
 		return nil, fmt.Errorf("create individual failed. because allocate class matrix failed")
 	}
 
-	return newIndividual(classMatrix, schedule, subjects, teachers, constraints)
+	return newIndividual(classMatrix, schedule, subjects, teachers, constraints, reschedule)
+}
+
+// 获取生成个体时先占用的已发布课表中的课
+// 已锁定的课不包括在内, 课时数和教学计划不一致的课班(如: 修改了课时数)重新分配
+func getSeedTimetableEntries(teachingTasks []*models.TeachingTask, reschedule *Reschedule) []*models.TimetableEntry {
+
+	if reschedule == nil {
+		return nil
+	}
+	baseEntries := reschedule.BaseTimetable
+	lockedEntries := reschedule.LockedEntries
+
+	// 课班在已发布课表中的课时数
+	// key: 课班, value: 天_节次
+	snTimeSlots := make(map[string][]string)
+	for _, entry := range baseEntries {
+		snTimeSlots[entry.ClassSN()] = lo.Uniq(append(snTimeSlots[entry.ClassSN()], fmt.Sprintf("%d_%d", entry.Weekday, entry.Period)))
+	}
+
+	return lo.Filter(lo.Without(baseEntries, lockedEntries...), func(entry *models.TimetableEntry, _ int) bool {
+		numClasses := models.GetNumClassesPerWeek(entry.GradeID, entry.ClassID, entry.SubjectID, teachingTasks)
+		return len(snTimeSlots[entry.ClassSN()]) == numClasses
+	})
 }
 
 // 计算固定得分
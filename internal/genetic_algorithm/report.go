@@ -0,0 +1,128 @@
+// report.go
+package genetic_algorithm
+
+import (
+	"course_scheduler/config"
+	"course_scheduler/internal/base"
+	"course_scheduler/internal/constraints"
+	"course_scheduler/internal/models"
+	"sort"
+)
+
+// 排课质量报告
+// 记录最佳个体的适应度, 未满足的约束条件, 分散度, 学生课程冲突, 以及遗传算法的执行情况
+type ScheduleReport struct {
+	Fitness                int                    `json:"fitness"`                  // 适应度
+	SubjectDispersionScore float64                `json:"subject_dispersion_score"` // 科目分散度
+	TeacherDispersionScore float64                `json:"teacher_dispersion_score"` // 教师分散度
+	NumGenerations         int                    `json:"num_generations"`          // 执行的代数
+	BestGen                int                    `json:"best_gen"`                 // 最佳个体所在的代数
+	TerminationReason      string                 `json:"termination_reason"`       // 终止原因
+	RuntimeMs              int64                  `json:"runtime_ms"`               // 运行时间(毫秒)
+	NumHardViolations      int                    `json:"num_hard_violations"`      // 未满足的硬约束条件数量
+	NumSoftViolations      int                    `json:"num_soft_violations"`      // 未满足的软约束条件数量
+	Violations             []*GeneViolation       `json:"violations"`               // 有未满足约束条件的基因
+	NumStudentClashes      int                    `json:"num_student_clashes"`      // 学生课程冲突数量, 没有学生选课信息时为0
+	StudentClashes         []StudentClash         `json:"student_clashes"`          // 学生课程冲突
+	StudentDailyOverloads  []StudentDailyOverload `json:"student_daily_overloads"`  // 每天课时数超过限制的学生
+
+	TimetableDiff    *TimetableDiffSummary `json:"timetable_diff,omitempty"`    // 与已发布课表相比的变化汇总, 没有已发布课表时为空
+	TimetableChanges []*TimetableChange    `json:"timetable_changes,omitempty"` // 与已发布课表相比的每节课的变化, 没有已发布课表时为空
+}
+
+// 一个基因未满足的约束条件
+type GeneViolation struct {
+	ClassSN   string   `json:"class_sn"`   // 课班 科目_年级_班级
+	TeacherID int      `json:"teacher_id"` // 教师id
+	VenueID   int      `json:"venue_id"`   // 教室id
+	TimeSlots []int    `json:"time_slots"` // 时间段
+	Hard      []string `json:"hard"`       // 未满足的硬约束条件(惩罚分为 math.MaxInt32, 如: 禁排)
+	Soft      []string `json:"soft"`       // 未满足的软约束条件
+}
+
+// 生成排课质量报告
+// 需要在遗传算法执行完成, 并且设置了monitor.TotalTime之后调用
+// enrollments 学生选课信息, 用于检查学生课程冲突和每天的课时数, 可以为空
+// maxDailyLessons 学生每天最多课时数
+// periodConsistencies 科目固定节次约束, 计算科目分散度时不处罚受约束的课班排在相同节次
+func (i *Individual) Report(schedule *models.Schedule, enrollments []*models.StudentEnrollment, maxDailyLessons int, periodConsistencies []*constraints.SubjectPeriodConsistency, monitor *base.Monitor) (*ScheduleReport, error) {
+
+	subjectDispersionScore, err := i.calcSubjectDispersionScore(schedule, true, config.SubjectPeriodLimitThreshold, periodConsistencies)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ScheduleReport{
+		Fitness:                i.Fitness,
+		SubjectDispersionScore: subjectDispersionScore,
+		TeacherDispersionScore: i.calcTeacherDispersionScore(schedule),
+		NumGenerations:         monitor.NumGenerations,
+		BestGen:                monitor.BestGen,
+		TerminationReason:      monitor.TerminationReason,
+		RuntimeMs:              monitor.TotalTime.Milliseconds(),
+		Violations:             []*GeneViolation{},
+		StudentClashes:         []StudentClash{},
+		StudentDailyOverloads:  []StudentDailyOverload{},
+	}
+
+	// 学生冲突只在适应度中处罚, 不能保证完全消除, 需要在报告中提示
+	if clashes := i.StudentClashes(enrollments); len(clashes) > 0 {
+		report.NumStudentClashes = len(clashes)
+		report.StudentClashes = clashes
+	}
+	if overloads := i.StudentDailyOverloads(schedule, enrollments, maxDailyLessons); len(overloads) > 0 {
+		report.StudentDailyOverloads = overloads
+	}
+
+	for _, chromosome := range i.Chromosomes {
+		for _, gene := range chromosome.Genes {
+
+			if len(gene.FailedConstraints) == 0 {
+				continue
+			}
+
+			violation := &GeneViolation{
+				ClassSN:   gene.ClassSN,
+				TeacherID: gene.TeacherID,
+				VenueID:   gene.VenueID,
+				TimeSlots: gene.TimeSlots,
+				Hard:      append([]string{}, gene.HardFailedConstraints...),
+				Soft:      softConstraints(gene.FailedConstraints, gene.HardFailedConstraints),
+			}
+			report.NumHardViolations += len(violation.Hard)
+			report.NumSoftViolations += len(violation.Soft)
+			report.Violations = append(report.Violations, violation)
+		}
+	}
+
+	// 按照课班, 时间段排序, 相同的排课结果生成的报告相同
+	sort.Slice(report.Violations, func(a, b int) bool {
+		va, vb := report.Violations[a], report.Violations[b]
+		if va.ClassSN != vb.ClassSN {
+			return va.ClassSN < vb.ClassSN
+		}
+		return va.TimeSlots[0] < vb.TimeSlots[0]
+	})
+
+	return report, nil
+}
+
+// 未满足的软约束条件, 从未满足的约束条件中去掉硬约束条件
+// 不同的约束可能使用相同的规则名称, 每个硬约束条件只去掉一次
+func softConstraints(failed, hard []string) []string {
+
+	hardCount := make(map[string]int)
+	for _, name := range hard {
+		hardCount[name]++
+	}
+
+	soft := []string{}
+	for _, name := range failed {
+		if hardCount[name] > 0 {
+			hardCount[name]--
+			continue
+		}
+		soft = append(soft, name)
+	}
+	return soft
+}
@@ -177,8 +177,11 @@ func getIndivid() *Individual {
 
 func TestResolveConflicts(t *testing.T) {
 
-	configFilePath := "/Users/apple/Documents/work/my/course_scheduler/testdata/test1.yaml"
-	input, _ := base.LoadTestData(configFilePath)
+	configFilePath := "../../testdata/test1.yaml"
+	input, err := base.LoadTestData(configFilePath)
+	if err != nil {
+		t.Fatalf("load test data failed. %s", err)
+	}
 	constraintMap := input.Constraints()
 	schedule := input.Schedule
 	constr1 := constraintMap["Class"].([]*constraints.Class)
@@ -275,13 +278,16 @@ func TestResolveConflicts(t *testing.T) {
 	i.rejectConflictGenes(teacherConnectedConflict, classConnectedConflict)
 	i.rejectConflictGenes(teacherNormalConflict, classNormalConflict)
 
+	// 教师跨教学楼上课检查
+	isTravelValid := i.travelValidFunc(schedule, teachers, constraintMap)
+
 	// 修复班级连堂课,普通课冲突
-	count1, err1 := i.resolveClassConflict(classConnectedConflict, classConnected, teacherConnected)
-	count2, err2 := i.resolveClassConflict(classNormalConflict, classNormal, teacherNormal)
+	count1, err1 := i.resolveClassConflict(classConnectedConflict, classConnected, teacherConnected, isTravelValid)
+	count2, err2 := i.resolveClassConflict(classNormalConflict, classNormal, teacherNormal, isTravelValid)
 
 	// 修复教师连堂课,普通课冲突
-	count3, err3 := i.resolveTeacherConflict(teacherConnectedConflict, teacherConnected, classConnected)
-	count4, err4 := i.resolveTeacherConflict(teacherNormalConflict, teacherNormal, classNormal)
+	count3, err3 := i.resolveTeacherConflict(teacherConnectedConflict, teacherConnected, classConnected, isTravelValid)
+	count4, err4 := i.resolveTeacherConflict(teacherNormalConflict, teacherNormal, classNormal, isTravelValid)
 	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
 		fmt.Printf("resolve conflicts failed. err1: %v, err2: %v, err3: %v, err4: %v\n", err1, err2, err3, err4)
 	}
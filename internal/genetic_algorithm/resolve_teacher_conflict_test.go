@@ -0,0 +1,155 @@
+package genetic_algorithm
+
+import (
+	"course_scheduler/internal/constraints"
+	"course_scheduler/internal/models"
+	"testing"
+)
+
+// 教师在两个班级同一时间段上课, 只有教师冲突, 没有班级冲突
+func TestResolveTeacherConflicts(t *testing.T) {
+
+	schedule := &models.Schedule{Name: "默认", NumWorkdays: 5, NumForenoonClasses: 4, NumAfternoonClasses: 4}
+	teachers := []*models.Teacher{{TeacherID: 1}}
+	constraintMap := map[string]interface{}{
+		"Class":   []*constraints.Class{},
+		"Teacher": []*constraints.Teacher{},
+	}
+
+	individual := &Individual{
+		Chromosomes: []*Chromosome{
+			{ClassSN: "1_9_1", Genes: []*Gene{{ClassSN: "1_9_1", TeacherID: 1, VenueID: 901, TimeSlots: []int{0}}}},
+			{ClassSN: "1_9_2", Genes: []*Gene{{ClassSN: "1_9_2", TeacherID: 1, VenueID: 902, TimeSlots: []int{0}}}},
+		},
+	}
+
+	count, err := individual.resolveConflicts(schedule, teachers, constraintMap)
+	if err != nil {
+		t.Fatalf("resolve conflicts failed. %s", err)
+	}
+	if count != 1 {
+		t.Errorf("got %d resolved conflicts, want 1", count)
+	}
+
+	ts1 := individual.Chromosomes[0].Genes[0].TimeSlots[0]
+	ts2 := individual.Chromosomes[1].Genes[0].TimeSlots[0]
+	if ts1 == ts2 {
+		t.Errorf("teacher still has two lessons at time slot %d", ts1)
+	}
+}
+
+// 两校区的作息时间: 第1节, 第2节之间10分钟, 第2节, 第3节之间30分钟大课间
+func newTravelConstraintMap(limit string) map[string]interface{} {
+	return map[string]interface{}{
+		"Class":         []*constraints.Class{},
+		"Teacher":       []*constraints.Teacher{},
+		"TeacherTravel": []*constraints.TeacherTravel{{Limit: limit}},
+		"Venue": []*models.Venue{
+			{VenueID: 901, Name: "东校区901", Building: "东校区"},
+			{VenueID: 902, Name: "西校区902", Building: "西校区"},
+		},
+		"TravelTime": []*models.TravelTime{{FromBuilding: "东校区", ToBuilding: "西校区", Minutes: 20}},
+	}
+}
+
+func newTravelSchedule(numForenoonClasses int) *models.Schedule {
+	return &models.Schedule{
+		Name:               "两校区",
+		NumWorkdays:        1,
+		NumForenoonClasses: numForenoonClasses,
+		BellSchedules: []*models.BellSchedule{
+			{
+				Weekday: 0,
+				Periods: []*models.PeriodTime{
+					{Period: 1, StartTime: "08:00", EndTime: "08:45"},
+					{Period: 2, StartTime: "08:55", EndTime: "09:40"},
+					{Period: 3, StartTime: "10:10", EndTime: "10:55"},
+				},
+			},
+		},
+	}
+}
+
+func TestTravelValidFunc(t *testing.T) {
+
+	schedule := newTravelSchedule(3)
+	teachers := []*models.Teacher{{TeacherID: 1}, {TeacherID: 2}}
+
+	// 教师1第1节在东校区上课
+	east := &Gene{ClassSN: "1_9_1", TeacherID: 1, VenueID: 901, TimeSlots: []int{0}}
+	west := &Gene{ClassSN: "1_9_2", TeacherID: 2, VenueID: 902, TimeSlots: []int{2}}
+	individual := &Individual{
+		Chromosomes: []*Chromosome{
+			{ClassSN: "1_9_1", Genes: []*Gene{east}},
+			{ClassSN: "1_9_2", Genes: []*Gene{west}},
+		},
+	}
+
+	isTravelValid := individual.travelValidFunc(schedule, teachers, newTravelConstraintMap("not"))
+	if !isTravelValid(west, "1") {
+		t.Errorf("other teacher: got invalid")
+	}
+
+	// 协同上课的教师也需要检查
+	west.CoTeacherIDs = []int{1}
+	if isTravelValid(west, "1") {
+		t.Errorf("co-teacher gap too short: got valid")
+	}
+	if !isTravelValid(west, "2") {
+		t.Errorf("co-teacher long break: got invalid")
+	}
+
+	// 只检查限制类型为禁止的教师
+	isTravelValid = individual.travelValidFunc(schedule, teachers, newTravelConstraintMap("avoid"))
+	if !isTravelValid(west, "1") {
+		t.Errorf("avoid: got invalid")
+	}
+}
+
+// 修复冲突时不能把课程移动到来不及换校区的时间段
+func TestResolveConflictsTeacherTravel(t *testing.T) {
+
+	// 每天只有2节课, 冲突的课程只能移动到第2节
+	schedule := newTravelSchedule(2)
+	teachers := []*models.Teacher{{TeacherID: 1}}
+
+	tests := []struct {
+		name    string
+		limit   string
+		wantErr bool
+	}{
+		// 没有可以移动的时间段, 修复失败
+		{"forbidden", "not", true},
+		{"avoid", "avoid", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			individual := &Individual{
+				Chromosomes: []*Chromosome{
+					{ClassSN: "1_9_1", Genes: []*Gene{{ClassSN: "1_9_1", TeacherID: 1, VenueID: 901, TimeSlots: []int{0}}}},
+					{ClassSN: "1_9_2", Genes: []*Gene{{ClassSN: "1_9_2", TeacherID: 1, VenueID: 902, TimeSlots: []int{0}}}},
+				},
+			}
+
+			count, err := individual.resolveConflicts(schedule, teachers, newTravelConstraintMap(tt.limit))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want err %v", err, tt.wantErr)
+			}
+
+			moved := 0
+			for _, chromosome := range individual.Chromosomes {
+				if chromosome.Genes[0].TimeSlots[0] == 1 {
+					moved++
+				}
+			}
+			if tt.wantErr && (count != 0 || moved != 0) {
+				t.Errorf("got %d resolved conflicts, %d lessons moved to time slot 1, want 0", count, moved)
+			}
+			if !tt.wantErr && (count != 1 || moved != 1) {
+				t.Errorf("got %d resolved conflicts, %d lessons moved to time slot 1, want 1", count, moved)
+			}
+		})
+	}
+}
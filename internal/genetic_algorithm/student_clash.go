@@ -0,0 +1,141 @@
+// student_clash.go
+package genetic_algorithm
+
+import (
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/types"
+	"fmt"
+	"sort"
+)
+
+// 学生课程冲突
+// 同一个学生在同一个时间段有多节课, 如: 同时选了化学A班和生物A班, 两个教学班排在同一节
+type StudentClash struct {
+	StudentID int      `json:"student_id"` // 学生id
+	TimeSlot  int      `json:"time_slot"`  // 冲突的时间段
+	ClassSNs  []string `json:"class_sns"`  // 冲突的课班
+}
+
+// 学生每天课时过多
+type StudentDailyOverload struct {
+	StudentID int `json:"student_id"` // 学生id
+	Day       int `json:"day"`        // 天, 从0开始
+	Count     int `json:"count"`      // 当天的课时数
+}
+
+// 检查学生课程冲突
+func (i *Individual) StudentClashes(enrollments []*models.StudentEnrollment) []StudentClash {
+
+	var clashes []StudentClash
+	if len(enrollments) == 0 {
+		return clashes
+	}
+
+	classGenes := i.genesByClassKey()
+	for _, enrollment := range enrollments {
+
+		// key: timeSlot, val: 该时间段学生要上的课班
+		timeSlotSNs := make(map[int][]string)
+		for _, key := range enrollment.ClassKeys() {
+			for _, gene := range classGenes[key] {
+				for _, timeSlot := range gene.TimeSlots {
+					timeSlotSNs[timeSlot] = append(timeSlotSNs[timeSlot], gene.ClassSN)
+				}
+			}
+		}
+
+		for timeSlot, classSNs := range timeSlotSNs {
+			if len(classSNs) > 1 {
+				clashes = append(clashes, StudentClash{StudentID: enrollment.StudentID, TimeSlot: timeSlot, ClassSNs: classSNs})
+			}
+		}
+	}
+
+	sort.Slice(clashes, func(a, b int) bool {
+		if clashes[a].StudentID != clashes[b].StudentID {
+			return clashes[a].StudentID < clashes[b].StudentID
+		}
+		return clashes[a].TimeSlot < clashes[b].TimeSlot
+	})
+	return clashes
+}
+
+// 检查学生每天的课时数是否超过maxDailyLessons
+func (i *Individual) StudentDailyOverloads(schedule *models.Schedule, enrollments []*models.StudentEnrollment, maxDailyLessons int) []StudentDailyOverload {
+
+	var overloads []StudentDailyOverload
+	if len(enrollments) == 0 || maxDailyLessons <= 0 {
+		return overloads
+	}
+
+	classGenes := i.genesByClassKey()
+	for _, enrollment := range enrollments {
+
+		// 同一时间段的冲突课程只计算一次
+		dayTimeSlots := make(map[int]map[int]bool)
+		for _, key := range enrollment.ClassKeys() {
+			for _, gene := range classGenes[key] {
+				for _, timeSlot := range gene.TimeSlots {
+					day := schedule.GetTimeSlot(timeSlot).Day
+					if _, ok := dayTimeSlots[day]; !ok {
+						dayTimeSlots[day] = make(map[int]bool)
+					}
+					dayTimeSlots[day][timeSlot] = true
+				}
+			}
+		}
+
+		for day, timeSlots := range dayTimeSlots {
+			if len(timeSlots) > maxDailyLessons {
+				overloads = append(overloads, StudentDailyOverload{StudentID: enrollment.StudentID, Day: day, Count: len(timeSlots)})
+			}
+		}
+	}
+
+	sort.Slice(overloads, func(a, b int) bool {
+		if overloads[a].StudentID != overloads[b].StudentID {
+			return overloads[a].StudentID < overloads[b].StudentID
+		}
+		return overloads[a].Day < overloads[b].Day
+	})
+	return overloads
+}
+
+// 打印学生课程冲突和课时过多的报告
+func (i *Individual) PrintStudentReport(schedule *models.Schedule, enrollments []*models.StudentEnrollment, maxDailyLessons int) {
+
+	if len(enrollments) == 0 {
+		return
+	}
+
+	clashes := i.StudentClashes(enrollments)
+	fmt.Printf("学生课程冲突: %d\n", len(clashes))
+	for _, clash := range clashes {
+		ts := schedule.GetTimeSlot(clash.TimeSlot)
+		fmt.Printf("StudentID: %d\tWeekday: %d\tPeriod: %d\tClassSNs: %v\n", clash.StudentID, ts.Weekday(), ts.Period+1, clash.ClassSNs)
+	}
+
+	overloads := i.StudentDailyOverloads(schedule, enrollments, maxDailyLessons)
+	fmt.Printf("学生每天课时超过%d节: %d\n", maxDailyLessons, len(overloads))
+	for _, overload := range overloads {
+		fmt.Printf("StudentID: %d\tWeekday: %d\tCount: %d\n", overload.StudentID, overload.Day+1, overload.Count)
+	}
+}
+
+// 按照课班所在的班级对基因分组, key: 年级_班级
+// 教学班占用的行政班不计算在内, 行政班的学生不一定在该教学班上课
+func (i *Individual) genesByClassKey() map[string][]*Gene {
+
+	classGenes := make(map[string][]*Gene)
+	for _, chromosome := range i.Chromosomes {
+		for _, gene := range chromosome.Genes {
+			SN, err := types.ParseSN(gene.ClassSN)
+			if err != nil {
+				continue
+			}
+			key := fmt.Sprintf("%d_%d", SN.GradeID, SN.ClassID)
+			classGenes[key] = append(classGenes[key], gene)
+		}
+	}
+	return classGenes
+}
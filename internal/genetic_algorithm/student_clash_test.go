@@ -0,0 +1,75 @@
+package genetic_algorithm
+
+import (
+	"course_scheduler/internal/base"
+	"course_scheduler/internal/models"
+	"testing"
+)
+
+func TestStudentClashes(t *testing.T) {
+
+	// 化学A班(101)和生物A班(102)都排在时间段4
+	individual := &Individual{
+		Chromosomes: []*Chromosome{
+			{ClassSN: "7_11_101", Genes: []*Gene{{ClassSN: "7_11_101", TeacherID: 1, TimeSlots: []int{4}}}},
+			{ClassSN: "8_11_102", Genes: []*Gene{{ClassSN: "8_11_102", TeacherID: 2, TimeSlots: []int{4}}}},
+			{ClassSN: "1_11_1", Genes: []*Gene{{ClassSN: "1_11_1", TeacherID: 3, TimeSlots: []int{5}}}},
+		},
+	}
+
+	enrollments := []*models.StudentEnrollment{
+		{StudentID: 1001, GradeID: 11, ClassID: 1, TeachingGroupIDs: []int{101, 102}},
+		{StudentID: 1002, GradeID: 11, ClassID: 1, TeachingGroupIDs: []int{101}},
+	}
+
+	clashes := individual.StudentClashes(enrollments)
+	if len(clashes) != 1 {
+		t.Fatalf("expected 1 clash, got %d: %v", len(clashes), clashes)
+	}
+
+	if clashes[0].StudentID != 1001 || clashes[0].TimeSlot != 4 || len(clashes[0].ClassSNs) != 2 {
+		t.Errorf("unexpected clash: %+v", clashes[0])
+	}
+}
+
+// 学生课程冲突记录在排课质量报告中
+func TestReportStudentClashes(t *testing.T) {
+
+	schedule := &models.Schedule{Name: "默认", NumWorkdays: 5, NumForenoonClasses: 4, NumAfternoonClasses: 4}
+	individual := &Individual{
+		Chromosomes: []*Chromosome{
+			{ClassSN: "7_11_101", Genes: []*Gene{{ClassSN: "7_11_101", TeacherID: 1, TimeSlots: []int{4}}}},
+			{ClassSN: "8_11_102", Genes: []*Gene{{ClassSN: "8_11_102", TeacherID: 2, TimeSlots: []int{4}}}},
+			{ClassSN: "1_11_1", Genes: []*Gene{{ClassSN: "1_11_1", TeacherID: 3, TimeSlots: []int{5}}}},
+		},
+	}
+	enrollments := []*models.StudentEnrollment{
+		{StudentID: 1001, GradeID: 11, ClassID: 1, TeachingGroupIDs: []int{101, 102}},
+	}
+
+	// 星期一有2节课(冲突的2节算1节), 超过每天最多1节
+	report, err := individual.Report(schedule, enrollments, 1, nil, base.NewMonitor())
+	if err != nil {
+		t.Fatalf("report failed. %s", err)
+	}
+	if report.NumStudentClashes != 1 || len(report.StudentClashes) != 1 || report.StudentClashes[0].StudentID != 1001 {
+		t.Errorf("student clashes: %d, %+v", report.NumStudentClashes, report.StudentClashes)
+	}
+	if len(report.StudentDailyOverloads) != 1 || report.StudentDailyOverloads[0] != (StudentDailyOverload{StudentID: 1001, Day: 0, Count: 2}) {
+		t.Errorf("student daily overloads: %+v", report.StudentDailyOverloads)
+	}
+
+	report, err = individual.Report(schedule, enrollments, 2, nil, base.NewMonitor())
+	if err != nil || len(report.StudentDailyOverloads) != 0 {
+		t.Errorf("student daily overloads within limit: %+v, err %v", report.StudentDailyOverloads, err)
+	}
+
+	// 没有学生选课信息时没有冲突
+	report, err = individual.Report(schedule, nil, 1, nil, base.NewMonitor())
+	if err != nil {
+		t.Fatalf("report failed. %s", err)
+	}
+	if report.NumStudentClashes != 0 || report.StudentClashes == nil || report.StudentDailyOverloads == nil {
+		t.Errorf("student clashes without enrollments: %d, %+v", report.NumStudentClashes, report.StudentClashes)
+	}
+}
@@ -0,0 +1,49 @@
+package genetic_algorithm
+
+import (
+	"course_scheduler/internal/constraints"
+	"course_scheduler/internal/models"
+	"math"
+	"testing"
+)
+
+// 受科目固定节次约束的课班排在相同节次时不处罚
+func TestSubjectDispersionScorePeriodConsistency(t *testing.T) {
+
+	schedule := &models.Schedule{Name: "默认", NumWorkdays: 5, NumForenoonClasses: 4, NumAfternoonClasses: 4}
+
+	// 英语每天都排在第1节
+	var genes []*Gene
+	for day := 0; day < 5; day++ {
+		genes = append(genes, &Gene{ClassSN: "3_1_1", TeacherID: 1, VenueID: 101, TimeSlots: []int{day * 8}})
+	}
+	individual := &Individual{Chromosomes: []*Chromosome{{ClassSN: "3_1_1", Genes: genes}}}
+
+	score := func(consistencies []*constraints.SubjectPeriodConsistency) float64 {
+		s, err := individual.calcSubjectDispersionScore(schedule, true, 2, consistencies)
+		if err != nil {
+			t.Fatalf("calc subject dispersion score failed. %s", err)
+		}
+		return s
+	}
+
+	punished := score(nil)
+	tests := []struct {
+		name          string
+		consistencies []*constraints.SubjectPeriodConsistency
+		want          float64
+	}{
+		{"subject", []*constraints.SubjectPeriodConsistency{{SubjectID: 3}}, punished + 0.09},
+		{"class", []*constraints.SubjectPeriodConsistency{{GradeID: 1, ClassID: 1, SubjectID: 3}}, punished + 0.09},
+		{"other subject", []*constraints.SubjectPeriodConsistency{{SubjectID: 1}}, punished},
+		{"other class", []*constraints.SubjectPeriodConsistency{{GradeID: 1, ClassID: 2, SubjectID: 3}}, punished},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := score(tt.consistencies); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("got %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
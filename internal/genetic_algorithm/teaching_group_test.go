@@ -0,0 +1,49 @@
+package genetic_algorithm
+
+import (
+	"course_scheduler/internal/constraints"
+	"course_scheduler/internal/models"
+	"testing"
+)
+
+// 教学班上课时占用学生来源的行政班, 和行政班的课程冲突
+func TestTeachingGroupConflicts(t *testing.T) {
+
+	schedule := &models.Schedule{Name: "默认", NumWorkdays: 5, NumForenoonClasses: 4, NumAfternoonClasses: 4}
+	teachers := []*models.Teacher{{TeacherID: 1}, {TeacherID: 2}}
+	constraintMap := map[string]interface{}{
+		"Class":   []*constraints.Class{},
+		"Teacher": []*constraints.Teacher{},
+	}
+
+	// 化学A班(103)的学生来自1班和2班, 和2班的语文都排在时间段0
+	individual := &Individual{
+		Chromosomes: []*Chromosome{
+			{ClassSN: "8_11_103", Genes: []*Gene{{ClassSN: "8_11_103", TeacherID: 1, VenueID: 901, OccupiedClassIDs: []int{1, 2}, TimeSlots: []int{0}}}},
+			{ClassSN: "1_11_2", Genes: []*Gene{{ClassSN: "1_11_2", TeacherID: 2, VenueID: 902, TimeSlots: []int{0}}}},
+		},
+	}
+
+	hasConflicts, conflicts := individual.HasTimeSlotConflicts()
+	if !hasConflicts {
+		t.Fatalf("want class conflict")
+	}
+	if len(conflicts) != 1 || conflicts[0] != "class(11_2)_timeSlot(0)" {
+		t.Errorf("conflicts: %v", conflicts)
+	}
+
+	if _, err := individual.resolveConflicts(schedule, teachers, constraintMap); err != nil {
+		t.Fatalf("resolve conflicts failed. %s", err)
+	}
+	if hasConflicts, conflicts := individual.HasTimeSlotConflicts(); hasConflicts {
+		t.Errorf("conflicts after resolve: %v", conflicts)
+	}
+
+	// 不占用行政班时没有冲突
+	individual.Chromosomes[0].Genes[0].OccupiedClassIDs = nil
+	individual.Chromosomes[0].Genes[0].TimeSlots = []int{0}
+	individual.Chromosomes[1].Genes[0].TimeSlots = []int{0}
+	if hasConflicts, conflicts := individual.HasTimeSlotConflicts(); hasConflicts {
+		t.Errorf("unexpected conflicts: %v", conflicts)
+	}
+}
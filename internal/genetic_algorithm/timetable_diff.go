@@ -0,0 +1,247 @@
+// timetable_diff.go
+package genetic_algorithm
+
+import (
+	"course_scheduler/internal/base"
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/types"
+	"fmt"
+	"sort"
+
+	"github.com/samber/lo"
+)
+
+// 课表变化
+// 部分重排后, 与已发布课表相比的变化
+type TimetableChange struct {
+	Type   string                 `json:"type"`   // 变化类型 moved: 移动, added: 新增, removed: 删除
+	Before *models.TimetableEntry `json:"before"` // 变化前的课, 新增时为空
+	After  *models.TimetableEntry `json:"after"`  // 变化后的课, 删除时为空
+}
+
+// 课表变化汇总
+type TimetableDiffSummary struct {
+	Moved        int            `json:"moved"`         // 移动的课时数
+	Added        int            `json:"added"`         // 新增的课时数
+	Removed      int            `json:"removed"`       // 删除的课时数
+	ClassMoved   map[string]int `json:"class_moved"`   // 各班级移动的课时数, key: 年级_班级
+	TeacherMoved map[int]int    `json:"teacher_moved"` // 各教师移动的课时数, key: 教师id
+}
+
+// 部分重排参数
+// 锁定的课和已发布的课表不是约束条件, 不生成规则, 单独传给遗传算法
+type Reschedule struct {
+	LockedEntries    []*models.TimetableEntry // 锁定的课, 用于初始化种群时直接占用矩阵元素
+	BaseTimetable    []*models.TimetableEntry // 已发布的课表, 用于生成第一个个体和处罚与已发布课表不同的基因
+	DisruptionWeight int                      // 与已发布课表不同的基因的处罚分
+}
+
+// 获取部分重排参数
+func NewReschedule(input *base.ScheduleInput) *Reschedule {
+	return &Reschedule{
+		LockedEntries:    models.GetLockedTimetableEntries(input.BaseTimetable, input.RescheduleScope),
+		BaseTimetable:    input.BaseTimetable,
+		DisruptionWeight: input.GetDisruptionWeight(),
+	}
+}
+
+// 生成字符串
+func (c *TimetableChange) String() string {
+	return fmt.Sprintf("Type: %s, Before: {%s}, After: {%s}", c.Type, c.Before, c.After)
+}
+
+// 将个体转换为课表, 每节课一条, 协同上课的教师每人一条
+func (i *Individual) TimetableEntries(schedule *models.Schedule) ([]*models.TimetableEntry, error) {
+
+	var entries []*models.TimetableEntry
+	for _, chromosome := range i.Chromosomes {
+		for _, gene := range chromosome.Genes {
+
+			SN, err := types.ParseSN(gene.ClassSN)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, timeSlot := range gene.TimeSlots {
+				ts := schedule.GetTimeSlot(timeSlot)
+				for _, teacherID := range gene.GetTeacherIDs() {
+					entries = append(entries, &models.TimetableEntry{
+						SubjectID: SN.SubjectID,
+						GradeID:   SN.GradeID,
+						ClassID:   SN.ClassID,
+						TeacherID: teacherID,
+						VenueID:   gene.VenueID,
+						Weekday:   ts.Day,
+						Period:    ts.Period,
+						Locked:    gene.Locked,
+					})
+				}
+			}
+		}
+	}
+	return entries, nil
+}
+
+// 与已发布的课表比较, 获取最少的课表变化
+// 1. 完全相同的课不算变化
+// 2. 同一课班删除的课和新增的课配对为移动, 优先配对相同教师的课
+// 3. 无法配对的课为删除或者新增
+func (i *Individual) DiffTimetable(schedule *models.Schedule, baseEntries []*models.TimetableEntry) ([]*TimetableChange, error) {
+
+	entries, err := i.TimetableEntries(schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	// 完全相同的课
+	matched := make(map[int]bool)
+	var removed []*models.TimetableEntry
+	for _, base := range baseEntries {
+		index := findTimetableEntry(entries, matched, func(entry *models.TimetableEntry) bool {
+			return entry.Equal(base)
+		})
+		if index < 0 {
+			removed = append(removed, base)
+		} else {
+			matched[index] = true
+		}
+	}
+
+	// 删除的课和新增的课配对为移动
+	var changes []*TimetableChange
+	var unpaired []*models.TimetableEntry
+	for _, base := range removed {
+		index := findTimetableEntry(entries, matched, func(entry *models.TimetableEntry) bool {
+			return entry.ClassSN() == base.ClassSN() && entry.TeacherID == base.TeacherID
+		})
+		if index < 0 {
+			index = findTimetableEntry(entries, matched, func(entry *models.TimetableEntry) bool {
+				return entry.ClassSN() == base.ClassSN()
+			})
+		}
+
+		if index < 0 {
+			unpaired = append(unpaired, base)
+			continue
+		}
+
+		matched[index] = true
+		changes = append(changes, &TimetableChange{Type: "moved", Before: base, After: entries[index]})
+	}
+
+	for _, base := range unpaired {
+		changes = append(changes, &TimetableChange{Type: "removed", Before: base})
+	}
+
+	for index, entry := range entries {
+		if !matched[index] {
+			changes = append(changes, &TimetableChange{Type: "added", After: entry})
+		}
+	}
+
+	return changes, nil
+}
+
+// 统计课表变化, 移动的课按照班级和教师汇总
+// 移动时更换了教师, 原教师和新教师都计算在内
+func SummarizeTimetableChanges(changes []*TimetableChange) *TimetableDiffSummary {
+
+	summary := &TimetableDiffSummary{
+		ClassMoved:   make(map[string]int),
+		TeacherMoved: make(map[int]int),
+	}
+
+	for _, change := range changes {
+		switch change.Type {
+		case "moved":
+			summary.Moved++
+			summary.ClassMoved[fmt.Sprintf("%d_%d", change.Before.GradeID, change.Before.ClassID)]++
+			summary.TeacherMoved[change.Before.TeacherID]++
+			if change.After.TeacherID != change.Before.TeacherID {
+				summary.TeacherMoved[change.After.TeacherID]++
+			}
+		case "added":
+			summary.Added++
+		case "removed":
+			summary.Removed++
+		}
+	}
+	return summary
+}
+
+// 统计与已发布课表不同的基因数量
+// 基因的任意一个时间段, 在已发布课表中没有相同教师, 相同教室的课, 即为不同
+// 已发布课表中没有的课班(如: 新增的课班)不计算在内
+func (i *Individual) CountDisruptedGenes(schedule *models.Schedule, baseEntries []*models.TimetableEntry) int {
+
+	// key: 课班_教师_教室_时间段
+	baseKeys := make(map[string]bool)
+	baseSNs := make(map[string]bool)
+	for _, entry := range baseEntries {
+		timeSlot := schedule.GetTimeSlotIndex(entry.Weekday, entry.Period)
+		baseKeys[fmt.Sprintf("%s_%d_%d_%d", entry.ClassSN(), entry.TeacherID, entry.VenueID, timeSlot)] = true
+		baseSNs[entry.ClassSN()] = true
+	}
+
+	count := 0
+	for _, chromosome := range i.Chromosomes {
+		for _, gene := range chromosome.Genes {
+
+			if !baseSNs[gene.ClassSN] {
+				continue
+			}
+
+			for _, timeSlot := range gene.TimeSlots {
+				if !baseKeys[fmt.Sprintf("%s_%d_%d_%d", gene.ClassSN, gene.TeacherID, gene.VenueID, timeSlot)] {
+					count++
+					break
+				}
+			}
+		}
+	}
+	return count
+}
+
+// 打印与已发布课表相比的变化
+func (i *Individual) PrintTimetableDiff(schedule *models.Schedule, baseEntries []*models.TimetableEntry) {
+
+	if len(baseEntries) == 0 {
+		return
+	}
+
+	changes, err := i.DiffTimetable(schedule, baseEntries)
+	if err != nil {
+		fmt.Printf("diff timetable failed. %s\n", err)
+		return
+	}
+
+	fmt.Printf("课表变化: %d\n", len(changes))
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+
+	summary := SummarizeTimetableChanges(changes)
+	fmt.Printf("移动: %d, 新增: %d, 删除: %d\n", summary.Moved, summary.Added, summary.Removed)
+
+	classKeys := lo.Keys(summary.ClassMoved)
+	sort.Strings(classKeys)
+	for _, classKey := range classKeys {
+		fmt.Printf("Class: %s\tMoved: %d\n", classKey, summary.ClassMoved[classKey])
+	}
+
+	teacherIDs := lo.Keys(summary.TeacherMoved)
+	sort.Ints(teacherIDs)
+	for _, teacherID := range teacherIDs {
+		fmt.Printf("TeacherID: %d\tMoved: %d\n", teacherID, summary.TeacherMoved[teacherID])
+	}
+}
+
+// 查找第一个未匹配, 并且满足条件的课
+func findTimetableEntry(entries []*models.TimetableEntry, matched map[int]bool, fn func(entry *models.TimetableEntry) bool) int {
+	for index, entry := range entries {
+		if !matched[index] && fn(entry) {
+			return index
+		}
+	}
+	return -1
+}
@@ -0,0 +1,98 @@
+package genetic_algorithm
+
+import (
+	"course_scheduler/config"
+	"course_scheduler/internal/base"
+	"course_scheduler/internal/models"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDiffTimetable(t *testing.T) {
+
+	schedule := &models.Schedule{
+		Name:                "默认",
+		NumWorkdays:         5,
+		NumDaysOff:          2,
+		NumForenoonClasses:  4,
+		NumAfternoonClasses: 2,
+	}
+
+	// 语文锁定不动, 数学从星期一第1节移到星期二第2节
+	individual := &Individual{
+		Chromosomes: []*Chromosome{
+			{ClassSN: "1_9_1", Genes: []*Gene{{ClassSN: "1_9_1", TeacherID: 1, VenueID: 901, TimeSlots: []int{1}, Locked: true}}},
+			{ClassSN: "2_9_1", Genes: []*Gene{{ClassSN: "2_9_1", TeacherID: 8, VenueID: 901, TimeSlots: []int{7}}}},
+		},
+	}
+
+	baseEntries := []*models.TimetableEntry{
+		{SubjectID: 1, GradeID: 9, ClassID: 1, TeacherID: 1, VenueID: 901, Weekday: 0, Period: 1, Locked: true},
+		{SubjectID: 2, GradeID: 9, ClassID: 1, TeacherID: 8, VenueID: 901, Weekday: 0, Period: 0},
+	}
+
+	changes, err := individual.DiffTimetable(schedule, baseEntries)
+	if err != nil {
+		t.Fatalf("diff timetable failed. %s", err)
+	}
+
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d: %v", len(changes), changes)
+	}
+
+	change := changes[0]
+	if change.Type != "moved" || change.Before.Period != 0 || change.After.Weekday != 1 || change.After.Period != 1 {
+		t.Errorf("unexpected change: %s", change)
+	}
+
+	// 只有数学的基因与已发布课表不同
+	if count := individual.CountDisruptedGenes(schedule, baseEntries); count != 1 {
+		t.Errorf("expected 1 disrupted gene, got %d", count)
+	}
+
+	data, err := json.Marshal(change)
+	if err != nil || !strings.HasPrefix(string(data), `{"type":"moved","before":{"subject_id":2`) {
+		t.Errorf("unexpected change json: %s, err %v", data, err)
+	}
+
+	summary := SummarizeTimetableChanges(changes)
+	if summary.Moved != 1 || summary.ClassMoved["9_1"] != 1 || summary.TeacherMoved[8] != 1 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+}
+
+// 锁定的课不作为生成第一个个体时占用的课, 课时数变化的课班重新分配
+func TestNewReschedule(t *testing.T) {
+
+	input := &base.ScheduleInput{
+		BaseTimetable: []*models.TimetableEntry{
+			{SubjectID: 1, GradeID: 9, ClassID: 1, TeacherID: 1, VenueID: 901, Weekday: 0, Period: 1, Locked: true},
+			{SubjectID: 2, GradeID: 9, ClassID: 1, TeacherID: 8, VenueID: 901, Weekday: 0, Period: 0},
+			{SubjectID: 3, GradeID: 9, ClassID: 1, TeacherID: 5, VenueID: 901, Weekday: 1, Period: 0},
+		},
+	}
+	teachingTasks := []*models.TeachingTask{
+		{SubjectID: 1, GradeID: 9, ClassID: 1, NumClassesPerWeek: 1},
+		{SubjectID: 2, GradeID: 9, ClassID: 1, NumClassesPerWeek: 1},
+		{SubjectID: 3, GradeID: 9, ClassID: 1, NumClassesPerWeek: 2},
+	}
+
+	reschedule := NewReschedule(input)
+	if len(reschedule.LockedEntries) != 1 || reschedule.LockedEntries[0].SubjectID != 1 {
+		t.Errorf("unexpected locked entries: %v", reschedule.LockedEntries)
+	}
+	if reschedule.DisruptionWeight != config.DisruptionWeight {
+		t.Errorf("expected default disruption weight %d, got %d", config.DisruptionWeight, reschedule.DisruptionWeight)
+	}
+
+	seedEntries := getSeedTimetableEntries(teachingTasks, reschedule)
+	if len(seedEntries) != 1 || seedEntries[0].SubjectID != 2 {
+		t.Errorf("unexpected seed entries: %v", seedEntries)
+	}
+
+	// 不是部分重排时, 没有先占用的课
+	if seedEntries := getSeedTimetableEntries(teachingTasks, nil); len(seedEntries) != 0 {
+		t.Errorf("expected no seed entries, got %v", seedEntries)
+	}
+}
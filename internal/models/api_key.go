@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// API密钥, 每个密钥属于一个学校(租户)
+// 只保存密钥的SHA-256, 明文只在创建时返回一次
+type APIKey struct {
+	KeyID     uint64     `gorm:"primaryKey;autoIncrement;column:key_id" json:"key_id"`
+	SchoolID  uint64     `gorm:"type:bigint unsigned;not null;column:school_id" json:"school_id"`
+	Name      string     `gorm:"type:varchar(255);not null;default:'';column:name" json:"name"`
+	KeyPrefix string     `gorm:"type:varchar(16);not null;column:key_prefix" json:"key_prefix"` // 明文密钥的前几位, 用于识别密钥
+	KeyHash   string     `gorm:"type:char(64);not null;uniqueIndex;column:key_hash" json:"-"`
+	RevokedAt *time.Time `gorm:"type:timestamp;null;column:revoked_at" json:"revoked_at"`
+	CreatedAt time.Time  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;column:created_at" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;column:updated_at" json:"updated_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_key"
+}
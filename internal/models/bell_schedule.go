@@ -0,0 +1,131 @@
+// bell_schedule.go
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// 作息时间表
+// 每节课的上下课时间, 以及课间休息时间
+// weekday为0的作息时间表是默认作息时间表, 对每天都生效
+// weekday不为0的作息时间表, 只对当天生效, 如: 星期五下午提前放学, weekday 从1开始, 不能超过每周上课天数
+
+// | 周几 | 节次   | 名称  | 上课时间 | 下课时间 |
+// | ---- | ------ | ----- | -------- | -------- |
+// | 每天 | 第1节  | 早读  | 07:30    | 08:00    |
+// | 每天 | 第2节  | 第1节 | 08:10    | 08:55    |
+// | 周五 | 第8节  | 第7节 | 14:30    | 15:10    |
+
+// | 周几 | 名称   | 在第几节课之后 | 开始时间 | 结束时间 |
+// | ---- | ------ | -------------- | -------- | -------- |
+// | 每天 | 大课间 | 第3节          | 09:45    | 10:15    |
+// | 每天 | 午休   | 第5节          | 11:50    | 14:00    |
+type BellSchedule struct {
+	Weekday int           `json:"weekday" mapstructure:"weekday"` // 周几，可选项为"0: 每天"、"1: 星期一"、"2: 星期二"、"3: 星期三"、"4: 星期四"、"5: 星期五", 与 weekday_classes 相同
+	Periods []*PeriodTime `json:"periods" mapstructure:"periods"` // 每节课的上下课时间
+	Breaks  []*BreakTime  `json:"breaks" mapstructure:"breaks"`   // 课间休息时间
+}
+
+// 节次时间
+type PeriodTime struct {
+	Period    int    `json:"period" mapstructure:"period"`         // 节次(period 从1开始)
+	Name      string `json:"name" mapstructure:"name"`             // 名称, 如: 早读, 第1节
+	StartTime string `json:"start_time" mapstructure:"start_time"` // 上课时间, 如: 08:00
+	EndTime   string `json:"end_time" mapstructure:"end_time"`     // 下课时间, 如: 08:45
+}
+
+// 课间休息时间
+type BreakTime struct {
+	Name        string `json:"name" mapstructure:"name"`                 // 名称, 如: 大课间, 午休
+	AfterPeriod int    `json:"after_period" mapstructure:"after_period"` // 在第几节课之后(从1开始)
+	StartTime   string `json:"start_time" mapstructure:"start_time"`     // 开始时间, 如: 09:45
+	EndTime     string `json:"end_time" mapstructure:"end_time"`         // 结束时间, 如: 10:15
+}
+
+// 作息时间的格式
+const clockLayout = "15:04"
+
+// 检查作息时间表
+// totalClassesPerDay 每天节数, numWorkdays 每周上课天数
+func (b *BellSchedule) Check(totalClassesPerDay, numWorkdays int) error {
+
+	if b.Weekday < 0 || b.Weekday > numWorkdays {
+		return fmt.Errorf("invalid bell schedule weekday %d, must be in range [0, %d]", b.Weekday, numWorkdays)
+	}
+
+	var lastEnd time.Time
+	for i, p := range b.Periods {
+
+		if p.Period <= 0 || p.Period > totalClassesPerDay {
+			return fmt.Errorf("invalid bell schedule period %d, must be in range [1, %d]", p.Period, totalClassesPerDay)
+		}
+
+		start, end, err := parseClockRange(p.StartTime, p.EndTime)
+		if err != nil {
+			return fmt.Errorf("invalid bell schedule period %d, %s", p.Period, err)
+		}
+
+		// 节次需要按照时间先后顺序排列, 且不能重叠
+		if i > 0 && (p.Period <= b.Periods[i-1].Period || start.Before(lastEnd)) {
+			return fmt.Errorf("invalid bell schedule period %d, periods must be in order and not overlap", p.Period)
+		}
+		lastEnd = end
+	}
+
+	for _, br := range b.Breaks {
+		if br.Name == "" {
+			return fmt.Errorf("bell schedule break name cannot be empty")
+		}
+
+		if br.AfterPeriod < 0 || br.AfterPeriod > totalClassesPerDay {
+			return fmt.Errorf("invalid bell schedule break %s, after_period must be in range [0, %d]", br.Name, totalClassesPerDay)
+		}
+
+		if _, _, err := parseClockRange(br.StartTime, br.EndTime); err != nil {
+			return fmt.Errorf("invalid bell schedule break %s, %s", br.Name, err)
+		}
+	}
+	return nil
+}
+
+// 根据节次查找节次时间
+// period 从1开始
+func (b *BellSchedule) FindPeriodTime(period int) *PeriodTime {
+	for _, p := range b.Periods {
+		if p.Period == period {
+			return p
+		}
+	}
+	return nil
+}
+
+// 根据节次查找课间休息时间
+// 返回在第period节课之后的课间休息, period 从1开始
+func (b *BellSchedule) FindBreakTime(afterPeriod int) *BreakTime {
+	for _, br := range b.Breaks {
+		if br.AfterPeriod == afterPeriod {
+			return br
+		}
+	}
+	return nil
+}
+
+// 解析起止时间
+func parseClockRange(startTime, endTime string) (time.Time, time.Time, error) {
+
+	start, err := time.Parse(clockLayout, startTime)
+	if err != nil {
+		return start, start, fmt.Errorf("invalid start_time %q, must be HH:MM", startTime)
+	}
+
+	end, err := time.Parse(clockLayout, endTime)
+	if err != nil {
+		return start, end, fmt.Errorf("invalid end_time %q, must be HH:MM", endTime)
+	}
+
+	if !start.Before(end) {
+		return start, end, fmt.Errorf("start_time %s must be before end_time %s", startTime, endTime)
+	}
+	return start, end, nil
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"testing"
+)
+
+// 测试用的作息时间表: 每天4节课, 星期五第4节提前下课
+func newTestBellSchedules() []*BellSchedule {
+	return []*BellSchedule{
+		{
+			Weekday: 0,
+			Periods: []*PeriodTime{
+				{Period: 1, StartTime: "08:00", EndTime: "08:45"},
+				{Period: 2, StartTime: "08:55", EndTime: "09:40"},
+				{Period: 3, StartTime: "10:10", EndTime: "10:55"},
+				{Period: 4, StartTime: "11:05", EndTime: "11:50"},
+			},
+			Breaks: []*BreakTime{{Name: "大课间", AfterPeriod: 2, StartTime: "09:40", EndTime: "10:10"}},
+		},
+		{
+			Weekday: 5,
+			Periods: []*PeriodTime{{Period: 4, StartTime: "11:05", EndTime: "11:30"}},
+		},
+	}
+}
+
+func TestBellScheduleCheck(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		bell    *BellSchedule
+		wantErr bool
+	}{
+		{"default", newTestBellSchedules()[0], false},
+		{"weekday", newTestBellSchedules()[1], false},
+		{"weekday out of range", &BellSchedule{Weekday: 6}, true},
+		{"negative weekday", &BellSchedule{Weekday: -1}, true},
+		{"period out of range", &BellSchedule{Periods: []*PeriodTime{{Period: 5, StartTime: "08:00", EndTime: "08:45"}}}, true},
+		{"invalid time", &BellSchedule{Periods: []*PeriodTime{{Period: 1, StartTime: "8点", EndTime: "08:45"}}}, true},
+		{"end before start", &BellSchedule{Periods: []*PeriodTime{{Period: 1, StartTime: "08:45", EndTime: "08:00"}}}, true},
+		{"overlap", &BellSchedule{Periods: []*PeriodTime{
+			{Period: 1, StartTime: "08:00", EndTime: "08:45"},
+			{Period: 2, StartTime: "08:40", EndTime: "09:20"},
+		}}, true},
+		{"break without name", &BellSchedule{Breaks: []*BreakTime{{AfterPeriod: 1, StartTime: "08:45", EndTime: "08:55"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 每天4节课, 每周5天
+			err := tt.bell.Check(4, 5)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSchedulePeriodTime(t *testing.T) {
+
+	schedule := &Schedule{Name: "test", NumWorkdays: 5, NumForenoonClasses: 4, BellSchedules: newTestBellSchedules()}
+	if err := schedule.Check(); err != nil {
+		t.Fatalf("check schedule failed. %s", err)
+	}
+
+	tests := []struct {
+		day, period int
+		start, end  string
+	}{
+		{0, 0, "08:00", "08:45"},
+		{0, 3, "11:05", "11:50"},
+		// 星期五第4节使用当天的作息时间表, 其他节次使用默认作息时间表
+		{4, 3, "11:05", "11:30"},
+		{4, 0, "08:00", "08:45"},
+	}
+
+	for _, tt := range tests {
+		start, end := schedule.GetPeriodTime(tt.day, tt.period)
+		if start != tt.start || end != tt.end {
+			t.Errorf("day %d period %d: got %s-%s, want %s-%s", tt.day, tt.period, start, end, tt.start, tt.end)
+		}
+	}
+
+	// 第2节和第3节之间是大课间
+	if gap, ok := schedule.GetGapMinutes(0, 1); !ok || gap != 30 {
+		t.Errorf("gap after period 2: got %d, want 30", gap)
+	}
+
+	// 第4节之后没有配置作息时间
+	if _, ok := schedule.GetGapMinutes(0, 3); ok {
+		t.Errorf("gap after period 4: want not configured")
+	}
+
+	// 重复的作息时间表
+	schedule.BellSchedules = append(schedule.BellSchedules, &BellSchedule{Weekday: 5})
+	if err := schedule.Check(); err == nil {
+		t.Errorf("duplicate weekday: want error")
+	}
+}
+
+// 当天的作息时间表和默认作息时间表合并后, 节次的时间不能重叠
+func TestScheduleCheckMergedBellSchedule(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		periods []*PeriodTime
+		wantErr bool
+	}{
+		{"earlier dismissal", []*PeriodTime{{Period: 4, StartTime: "11:05", EndTime: "11:30"}}, false},
+		{"shorter break", []*PeriodTime{{Period: 3, StartTime: "09:50", EndTime: "10:35"}}, false},
+		{"overlaps previous default period", []*PeriodTime{{Period: 2, StartTime: "08:30", EndTime: "09:15"}}, true},
+		{"overlaps next default period", []*PeriodTime{{Period: 3, StartTime: "10:30", EndTime: "11:15"}}, true},
+		{"after next default period", []*PeriodTime{{Period: 1, StartTime: "09:00", EndTime: "09:45"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bellSchedules := newTestBellSchedules()
+			bellSchedules[1].Periods = tt.periods
+
+			schedule := &Schedule{Name: "test", NumWorkdays: 5, NumForenoonClasses: 4, BellSchedules: bellSchedules}
+			err := schedule.Check()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
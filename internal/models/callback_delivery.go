@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// 排课任务完成回调的一次发送记录
+type CallbackDelivery struct {
+	DeliveryID uint64    `gorm:"primaryKey;autoIncrement;column:delivery_id" json:"delivery_id"`
+	TaskID     uint64    `gorm:"type:bigint unsigned;not null;column:task_id" json:"task_id"`
+	Attempt    int       `gorm:"type:int unsigned;not null;column:attempt" json:"attempt"`
+	URL        string    `gorm:"type:varchar(1024);not null;column:url" json:"url"`
+	TaskStatus string    `gorm:"type:varchar(16);not null;column:task_status" json:"task_status"`
+	StatusCode int       `gorm:"type:int;not null;default:0;column:status_code" json:"status_code"` // 回调地址返回的HTTP状态码, 请求失败时为0
+	Success    bool      `gorm:"type:tinyint(1);not null;default:0;column:success" json:"success"`
+	ErrorMsg   string    `gorm:"type:text;not null;column:error_message" json:"error_message"`
+	DurationMs int64     `gorm:"type:bigint;not null;default:0;column:duration_ms" json:"duration_ms"`
+	CreatedAt  time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;column:created_at" json:"created_at"`
+}
+
+func (CallbackDelivery) TableName() string {
+	return "callback_delivery"
+}
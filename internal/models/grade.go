@@ -1,9 +1,22 @@
 // grade.go
 package models
 
+import "fmt"
+
 type Grade struct {
 	SchoolID int     `json:"school_id" mapstructure:"school_id"`
 	GradeID  int     `json:"grade_id" mapstructure:"grade_id"`
 	Name     string  `json:"name" mapstructure:"name"`
 	Classes  []Class `json:"classes" mapstructure:"classes"`
 }
+
+// 根据年级id查找年级
+func FindGradeByID(gradeID int, grades []*Grade) (*Grade, error) {
+
+	for _, grade := range grades {
+		if grade.GradeID == gradeID {
+			return grade, nil
+		}
+	}
+	return nil, fmt.Errorf("grade not found")
+}
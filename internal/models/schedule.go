@@ -4,6 +4,7 @@ package models
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/samber/lo"
 )
@@ -21,6 +22,37 @@ type Schedule struct {
 	// NumNoonClasses           int    `json:"num_noon_classes" mapstructure:"num_noon_classes"`                       // 中午 几节课, 默认: 4节
 	NumAfternoonClasses int `json:"num_afternoon_classes" mapstructure:"num_afternoon_classes"` // 下午 几节课, 默认: 4节
 	NumNightClasses     int `json:"num_night_classes" mapstructure:"num_night_classes"`         // 晚自习 几节课, 默认: 0节
+
+	WeekdayClasses []*WeekdayClasses `json:"weekday_classes" mapstructure:"weekday_classes"` // 按周几设置各时间区间的节数, 可以为空
+	BellSchedules  []*BellSchedule   `json:"bell_schedules" mapstructure:"bell_schedules"`   // 作息时间表, 可以为空
+}
+
+// 按周几设置各时间区间的节数
+// 如: 星期三下午只有2节课, 星期五没有晚自习
+// 各时间区间的节数不能超过课表方案的默认节数
+type WeekdayClasses struct {
+	Weekday                  int `json:"weekday" mapstructure:"weekday"`                                         // 周几，可选项为"1: 星期一"、"2: 星期二"、"3: 星期三"、"4: 星期四"、"5: 星期五"
+	NumMorningReadingClasses int `json:"num_morning_reading_classes" mapstructure:"num_morning_reading_classes"` // 早读 几节课
+	NumForenoonClasses       int `json:"num_forenoon_classes" mapstructure:"num_forenoon_classes"`               // 上午 几节课
+	NumAfternoonClasses      int `json:"num_afternoon_classes" mapstructure:"num_afternoon_classes"`             // 下午 几节课
+	NumNightClasses          int `json:"num_night_classes" mapstructure:"num_night_classes"`                     // 晚自习 几节课
+}
+
+// 获取时间区间的节数
+func (w *WeekdayClasses) getNumClasses(segment string) int {
+
+	switch segment {
+	case "morning_reading":
+		return w.NumMorningReadingClasses
+	case "forenoon":
+		return w.NumForenoonClasses
+	case "afternoon":
+		return w.NumAfternoonClasses
+	case "night":
+		return w.NumNightClasses
+	default:
+		return 0
+	}
 }
 
 func (s *Schedule) Check() error {
@@ -57,6 +89,76 @@ func (s *Schedule) Check() error {
 		return fmt.Errorf("invalid schedule, the sum of morning reading, forenoon, afternoon and night classes, must be positive")
 	}
 
+	classesWeekdays := make(map[int]bool)
+	for _, weekdayClasses := range s.WeekdayClasses {
+
+		if weekdayClasses.Weekday <= 0 || weekdayClasses.Weekday > s.NumWorkdays {
+			return fmt.Errorf("invalid weekday_classes weekday %d, must be in range [1, %d]", weekdayClasses.Weekday, s.NumWorkdays)
+		}
+
+		if classesWeekdays[weekdayClasses.Weekday] {
+			return fmt.Errorf("duplicate weekday_classes for weekday %d", weekdayClasses.Weekday)
+		}
+		classesWeekdays[weekdayClasses.Weekday] = true
+
+		for _, segment := range Segments {
+			numClasses := weekdayClasses.getNumClasses(segment)
+			if numClasses < 0 || numClasses > s.getNumClasses(segment) {
+				return fmt.Errorf("invalid weekday_classes weekday %d, %s classes must be in range [0, %d]", weekdayClasses.Weekday, segment, s.getNumClasses(segment))
+			}
+		}
+	}
+
+	weekdays := make(map[int]bool)
+	for _, bellSchedule := range s.BellSchedules {
+
+		if weekdays[bellSchedule.Weekday] {
+			return fmt.Errorf("duplicate bell schedule for weekday %d", bellSchedule.Weekday)
+		}
+		weekdays[bellSchedule.Weekday] = true
+
+		if err := bellSchedule.Check(s.GetTotalClassesPerDay(), s.NumWorkdays); err != nil {
+			return err
+		}
+	}
+
+	// 当天的作息时间表没有配置的节次使用默认作息时间表, 合并后的节次也不能重叠
+	for _, bellSchedule := range s.BellSchedules {
+		if bellSchedule.Weekday == 0 {
+			continue
+		}
+		if err := s.checkDayPeriodTimes(bellSchedule.Weekday - 1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 检查某天合并默认作息时间表之后的节次时间, 需要按照时间先后顺序排列, 且不能重叠
+// day 从0开始
+func (s *Schedule) checkDayPeriodTimes(day int) error {
+
+	var lastEnd time.Time
+	lastPeriod := 0
+	for period := 0; period < s.GetTotalClassesPerDay(); period++ {
+
+		startTime, endTime := s.GetPeriodTime(day, period)
+		if startTime == "" {
+			continue
+		}
+
+		start, end, err := parseClockRange(startTime, endTime)
+		if err != nil {
+			return fmt.Errorf("invalid bell schedule weekday %d period %d, %s", day+1, period+1, err)
+		}
+
+		if lastPeriod > 0 && start.Before(lastEnd) {
+			return fmt.Errorf("invalid bell schedule weekday %d, period %d overlaps period %d after merging with the default bell schedule", day+1, period+1, lastPeriod)
+		}
+		lastEnd = end
+		lastPeriod = period + 1
+	}
 	return nil
 }
 
@@ -81,6 +183,15 @@ func (s *Schedule) GetPeriodWithRange(r string) (int, int) {
 		{"night", s.NumNightClasses},
 	}
 
+	// 全天(不含晚自习), 从早读开始到下午结束
+	if r == "all_day" {
+		endPeriod := s.NumMorningReadingClasses + s.NumForenoonClasses + s.NumAfternoonClasses - 1
+		if endPeriod < 0 {
+			return -1, -1
+		}
+		return 0, endPeriod
+	}
+
 	totalClasses := 0
 	startPeriod, endPeriod := -1, -1
 
@@ -97,15 +208,17 @@ func (s *Schedule) GetPeriodWithRange(r string) (int, int) {
 }
 
 // 生成一周课程时间段
+// 不存在的时间段(如: 星期三下午只有2节课, 第3,4节)不包含在内
 func (s *Schedule) GenWeekTimeSlots() []int {
 
-	// // 每天总课时
-	// dayTotalClasses := s.GetTotalClassesPerDay()
-	// // 每周总课时
-	// weekTotalClasses := s.NumWorkdays * dayTotalClasses
-	totalClassesPerWeek := s.TotalClassesPerWeek()
+	// 每天总课时
+	dayTotalClasses := s.GetTotalClassesPerDay()
+	// 每周总课时
+	weekTotalClasses := s.NumWorkdays * dayTotalClasses
 
-	timeSlots := lo.Range(totalClassesPerWeek)
+	timeSlots := lo.Filter(lo.Range(weekTotalClasses), func(timeSlot int, _ int) bool {
+		return s.IsTimeSlotAvailable(timeSlot)
+	})
 
 	return timeSlots
 }
@@ -113,10 +226,69 @@ func (s *Schedule) GenWeekTimeSlots() []int {
 // 每周总课时数
 // TODO: 这个名字要统计下
 func (s *Schedule) TotalClassesPerWeek() int {
+	return len(s.GenWeekTimeSlots())
+}
 
-	// 每天总课时
-	dayTotalClasses := s.GetTotalClassesPerDay()
-	// 每周总课时
-	totalClassesPerWeek := s.NumWorkdays * dayTotalClasses
-	return totalClassesPerWeek
+// 获取某天的作息时间表
+// day 从0开始, 当天没有单独的作息时间表时, 使用默认作息时间表, 都没有时返回nil
+func (s *Schedule) GetBellSchedule(day int) *BellSchedule {
+
+	for _, bellSchedule := range s.BellSchedules {
+		if bellSchedule.Weekday == day+1 {
+			return bellSchedule
+		}
+	}
+	return s.getDefaultBellSchedule()
+}
+
+// 获取默认作息时间表
+func (s *Schedule) getDefaultBellSchedule() *BellSchedule {
+
+	for _, bellSchedule := range s.BellSchedules {
+		if bellSchedule.Weekday == 0 {
+			return bellSchedule
+		}
+	}
+	return nil
+}
+
+// 获取某天某节课的上下课时间
+// day, period 从0开始, 没有配置作息时间时返回空字符串
+func (s *Schedule) GetPeriodTime(day, period int) (string, string) {
+
+	bellSchedule := s.GetBellSchedule(day)
+	if bellSchedule == nil {
+		return "", ""
+	}
+
+	// 当天的作息时间表没有配置该节次时, 使用默认作息时间表
+	periodTime := bellSchedule.FindPeriodTime(period + 1)
+	if periodTime == nil && bellSchedule.Weekday != 0 {
+		if defaultBellSchedule := s.getDefaultBellSchedule(); defaultBellSchedule != nil {
+			periodTime = defaultBellSchedule.FindPeriodTime(period + 1)
+		}
+	}
+
+	if periodTime == nil {
+		return "", ""
+	}
+	return periodTime.StartTime, periodTime.EndTime
+}
+
+// 获取某天第period节课下课到下一节课上课的间隔分钟数
+// day, period 从0开始, 没有配置这两节课的作息时间时, 第二个返回值为false
+func (s *Schedule) GetGapMinutes(day, period int) (int, bool) {
+
+	_, endTime := s.GetPeriodTime(day, period)
+	startTime, _ := s.GetPeriodTime(day, period+1)
+	if endTime == "" || startTime == "" {
+		return 0, false
+	}
+
+	end, err1 := time.Parse(clockLayout, endTime)
+	start, err2 := time.Parse(clockLayout, startTime)
+	if err1 != nil || err2 != nil || start.Before(end) {
+		return 0, false
+	}
+	return int(start.Sub(end).Minutes()), true
 }
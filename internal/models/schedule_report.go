@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// 排课质量报告, 每个排课任务一条
+// Report 为JSON格式的完整报告, 包含每个基因未满足的约束条件
+type ScheduleReport struct {
+	TaskID            uint64    `gorm:"primaryKey;type:bigint unsigned;not null;column:task_id" json:"task_id"`
+	Fitness           int       `gorm:"type:int;not null;column:fitness" json:"fitness"`
+	NumHardViolations int       `gorm:"type:int;not null;default:0;column:num_hard_violations" json:"num_hard_violations"`
+	NumSoftViolations int       `gorm:"type:int;not null;default:0;column:num_soft_violations" json:"num_soft_violations"`
+	TerminationReason string    `gorm:"type:varchar(32);not null;column:termination_reason" json:"termination_reason"`
+	Report            string    `gorm:"type:mediumtext;not null;column:report" json:"report"`
+	CreatedAt         time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;column:created_at" json:"created_at"`
+	UpdatedAt         time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;column:updated_at" json:"updated_at"`
+}
+
+func (ScheduleReport) TableName() string {
+	return "schedule_report"
+}
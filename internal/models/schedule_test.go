@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestScheduleGetPeriodWithRange(t *testing.T) {
+
+	// 每天早读1节, 上午4节, 下午3节, 晚自习2节
+	schedule := &Schedule{Name: "test", NumWorkdays: 5, NumMorningReadingClasses: 1, NumForenoonClasses: 4, NumAfternoonClasses: 3, NumNightClasses: 2}
+
+	tests := []struct {
+		r          string
+		start, end int
+	}{
+		{"morning_reading", 0, 0},
+		{"forenoon", 1, 4},
+		{"afternoon", 5, 7},
+		{"night", 8, 9},
+		// 全天不含晚自习
+		{"all_day", 0, 7},
+		{"noon", -1, -1},
+	}
+
+	for _, tt := range tests {
+		start, end := schedule.GetPeriodWithRange(tt.r)
+		if start != tt.start || end != tt.end {
+			t.Errorf("%s: got [%d, %d], want [%d, %d]", tt.r, start, end, tt.start, tt.end)
+		}
+	}
+
+	// 没有早读时, 全天从上午开始
+	schedule.NumMorningReadingClasses = 0
+	if start, end := schedule.GetPeriodWithRange("all_day"); start != 0 || end != 6 {
+		t.Errorf("all_day without morning reading: got [%d, %d], want [0, 6]", start, end)
+	}
+	if start, end := schedule.GetPeriodWithRange("morning_reading"); start != -1 || end != -1 {
+		t.Errorf("morning_reading: got [%d, %d], want [-1, -1]", start, end)
+	}
+}
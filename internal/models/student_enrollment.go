@@ -0,0 +1,33 @@
+// student_enrollment.go
+package models
+
+import "fmt"
+
+// 学生选课信息
+// 走班后, 同一个行政班的学生会去不同的教学班上课, 需要按学生检查课程是否冲突
+//
+// | 学生id | 年级 | 行政班 | 教学班           |
+// | ------ | ---- | ------ | ---------------- |
+// | 1001   | 高二 | 1班    | 物理A班, 化学A班 |
+// | 1002   | 高二 | 1班    | 历史A班, 化学A班 |
+type StudentEnrollment struct {
+	StudentID        int   `json:"student_id" mapstructure:"student_id"`                 // 学生id
+	GradeID          int   `json:"grade_id" mapstructure:"grade_id"`                     // 年级id
+	ClassID          int   `json:"class_id" mapstructure:"class_id"`                     // 行政班id, 可以为空
+	TeachingGroupIDs []int `json:"teaching_group_ids" mapstructure:"teaching_group_ids"` // 教学班id
+}
+
+// 学生上课的班级key列表, key: 年级_班级
+// 包括学生所在的行政班和选择的教学班
+func (e *StudentEnrollment) ClassKeys() []string {
+
+	var keys []string
+	if e.ClassID > 0 {
+		keys = append(keys, fmt.Sprintf("%d_%d", e.GradeID, e.ClassID))
+	}
+
+	for _, id := range e.TeachingGroupIDs {
+		keys = append(keys, fmt.Sprintf("%d_%d", e.GradeID, id))
+	}
+	return keys
+}
@@ -3,25 +3,49 @@ package models
 
 import (
 	"time"
+)
+
+// 排课任务状态
+const (
+	TaskStatusPending   = "pending"   // 等待执行
+	TaskStatusRunning   = "running"   // 执行中
+	TaskStatusSuccess   = "success"   // 执行成功
+	TaskStatusFailed    = "failed"    // 执行失败
+	TaskStatusCancelled = "cancelled" // 已取消
+)
 
-	"gorm.io/gorm"
+// 排课任务完成回调状态, 没有设置回调地址时为空
+const (
+	CallbackStatusPending   = "pending"   // 等待回调
+	CallbackStatusDelivered = "delivered" // 回调成功
+	CallbackStatusFailed    = "failed"    // 多次回调失败, 不再重试
 )
 
+// 排课任务
 type Task struct {
-	TaskID    uint64    `gorm:"primaryKey;autoIncrement;column:task_id" json:"task_id"`
-	TaskData  string    `gorm:"type:text;not null;column:task_data" json:"task_data"`
-	Status    string    `gorm:"type:enum('pending','running','success','failed');not null;column:status" json:"status"`
-	Progress  int8      `gorm:"type:tinyint unsigned;not null;default:0;column:progress" json:"progress"`
-	CreatedAt time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;column:created_at" json:"created_at"`
-	UpdatedAt time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;column:updated_at" json:"updated_at"`
+	TaskID     uint64     `gorm:"primaryKey;autoIncrement;column:task_id" json:"task_id"`
+	SchoolID   uint64     `gorm:"type:bigint unsigned;not null;default:0;column:school_id" json:"school_id"` // 创建任务的学校(租户)
+	TaskData   string     `gorm:"type:text;not null;column:task_data" json:"task_data,omitempty"`
+	Status     string     `gorm:"type:enum('pending','running','success','failed','cancelled');not null;column:status" json:"status"`
+	Progress   int8       `gorm:"type:tinyint unsigned;not null;default:0;column:progress" json:"progress"`
+	Attempts   int        `gorm:"type:int unsigned;not null;default:0;column:attempts" json:"attempts"`
+	StartedAt  *time.Time `gorm:"type:timestamp;null;column:started_at" json:"started_at"`
+	FinishedAt *time.Time `gorm:"type:timestamp;null;column:finished_at" json:"finished_at"`
+	// 任务结束(成功, 失败, 取消)时回调的地址和签名密钥, 密钥不返回给客户端
+	CallbackURL      string     `gorm:"type:varchar(1024);not null;default:'';column:callback_url" json:"callback_url,omitempty"`
+	CallbackSecret   string     `gorm:"type:varchar(255);not null;default:'';column:callback_secret" json:"-"`
+	CallbackStatus   string     `gorm:"type:enum('','pending','delivered','failed');not null;default:'';column:callback_status" json:"callback_status,omitempty"`
+	CallbackAttempts int        `gorm:"type:int unsigned;not null;default:0;column:callback_attempts" json:"callback_attempts,omitempty"`
+	CallbackNextAt   *time.Time `gorm:"type:timestamp;null;column:callback_next_at" json:"-"`
+	// 幂等键和规范化后排课数据的SHA-256, 同一个学校的幂等键不能重复, 没有幂等键时为空
+	IdempotencyKey *string `gorm:"type:varchar(255);null;column:idempotency_key" json:"idempotency_key,omitempty"`
+	InputHash      string  `gorm:"type:char(64);not null;default:'';column:input_hash" json:"input_hash,omitempty"`
+	// 复用了这个任务的排课结果, 没有复用时为0
+	SourceTaskID uint64    `gorm:"type:bigint unsigned;not null;default:0;column:source_task_id" json:"source_task_id,omitempty"`
+	CreatedAt    time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;column:created_at" json:"created_at"`
+	UpdatedAt    time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;column:updated_at" json:"updated_at"`
 }
 
 func (Task) TableName() string {
 	return "task"
 }
-
-// 高并发情况下，创建任务的频率很难达到每纳秒一个。因此，使用时间戳生成 ID 在实际应用中是可行
-func (t *Task) BeforeCreate(tx *gorm.DB) (err error) {
-	t.TaskID = uint64(time.Now().UnixNano())
-	return
-}
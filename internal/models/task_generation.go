@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// 排课任务每一代遗传的监控数据, 用于推送排课进度
+// 数据来自 base.Monitor, 任务重新执行时删除之前的数据
+type TaskGeneration struct {
+	TaskID                uint64    `gorm:"primaryKey;type:bigint unsigned;not null;column:task_id" json:"task_id"`
+	Generation            int       `gorm:"primaryKey;type:int;not null;column:generation" json:"generation"`
+	BestFitness           int       `gorm:"type:int;not null;column:best_fitness" json:"best_fitness"`
+	AvgFitness            float64   `gorm:"type:double;not null;column:avg_fitness" json:"avg_fitness"`
+	WorstFitness          int       `gorm:"type:int;not null;column:worst_fitness" json:"worst_fitness"`
+	GenWithoutImprovement int       `gorm:"type:int;not null;column:gen_without_improvement" json:"gen_without_improvement"`
+	Progress              int8      `gorm:"type:tinyint;not null;column:progress" json:"progress"`
+	ElapsedMs             int64     `gorm:"type:bigint;not null;column:elapsed_ms" json:"elapsed_ms"`
+	EtaMs                 int64     `gorm:"type:bigint;not null;column:eta_ms" json:"eta_ms"`
+	CreatedAt             time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;column:created_at" json:"created_at"`
+}
+
+func (TaskGeneration) TableName() string {
+	return "task_generation"
+}
@@ -1,14 +1,80 @@
 package models
 
-var (
-	teacherGroup = []TeacherGroup{
-		{TeacherGroupID: 1, Name: "语文组"},
-		{TeacherGroupID: 2, Name: "数学组"},
-		{TeacherGroupID: 3, Name: "行政领导"},
-	}
+import (
+	"fmt"
+
+	"github.com/samber/lo"
 )
 
+// 教师分组
+// 如: 语文组, 数学组, 行政领导
+// 分组成员可以在教师信息的teacher_group_ids中指定, 也可以在分组的teacher_ids中指定
 type TeacherGroup struct {
-	TeacherGroupID int    // 分组id
-	Name           string // 分组名称
+	TeacherGroupID int    `json:"teacher_group_id" mapstructure:"teacher_group_id"` // 分组id
+	Name           string `json:"name" mapstructure:"name"`                         // 分组名称
+	TeacherIDs     []int  `json:"teacher_ids" mapstructure:"teacher_ids"`           // 分组成员教师id
+}
+
+// 读取教师分组信息
+func GetTeacherGroupsFromDB() []*TeacherGroup {
+
+	teacherGroups := []*TeacherGroup{
+		// {TeacherGroupID: 1, Name: "语文组"},
+		// {TeacherGroupID: 2, Name: "数学组"},
+		// {TeacherGroupID: 3, Name: "行政领导"},
+	}
+	return teacherGroups
+}
+
+// 根据教师分组id查找教师分组
+func FindTeacherGroupByID(teacherGroupID int, teacherGroups []*TeacherGroup) (*TeacherGroup, error) {
+
+	for _, group := range teacherGroups {
+		if group.TeacherGroupID == teacherGroupID {
+			return group, nil
+		}
+	}
+	return nil, fmt.Errorf("teacher group not found")
+}
+
+// 将教师分组中的成员信息合并到教师的teacher_group_ids中
+// 合并后, 只需要通过教师的TeacherGroupIDs即可判断教师是否属于某个分组
+func ApplyTeacherGroups(teachers []*Teacher, teacherGroups []*TeacherGroup) error {
+
+	for _, group := range teacherGroups {
+		for _, teacherID := range group.TeacherIDs {
+
+			teacher, err := FindTeacherByID(teacherID, teachers)
+			if err != nil {
+				return fmt.Errorf("teacher group %d member %d: %s", group.TeacherGroupID, teacherID, err)
+			}
+
+			if !lo.Contains(teacher.TeacherGroupIDs, group.TeacherGroupID) {
+				teacher.TeacherGroupIDs = append(teacher.TeacherGroupIDs, group.TeacherGroupID)
+			}
+		}
+	}
+	return nil
+}
+
+// 教师分组内的所有教师id
+func GroupTeacherIDs(teacherGroupID int, teachers []*Teacher) []int {
+
+	var teacherIDs []int
+	for _, teacher := range teachers {
+		if lo.Contains(teacher.TeacherGroupIDs, teacherGroupID) {
+			teacherIDs = append(teacherIDs, teacher.TeacherID)
+		}
+	}
+	return teacherIDs
+}
+
+// 判断教师是否属于某个分组
+func IsTeacherInGroup(teacherID, teacherGroupID int, teachers []*Teacher) bool {
+
+	teacher, err := FindTeacherByID(teacherID, teachers)
+	if err != nil {
+		return false
+	}
+	return lo.Contains(teacher.TeacherGroupIDs, teacherGroupID)
 }
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyTeacherGroups(t *testing.T) {
+
+	teachers := []*Teacher{
+		{TeacherID: 1, TeacherGroupIDs: []int{10}},
+		{TeacherID: 2},
+	}
+	teacherGroups := []*TeacherGroup{
+		{TeacherGroupID: 10, Name: "语文组", TeacherIDs: []int{1, 2}},
+		{TeacherGroupID: 20, Name: "行政组", TeacherIDs: []int{2}},
+	}
+
+	if err := ApplyTeacherGroups(teachers, teacherGroups); err != nil {
+		t.Fatalf("apply teacher groups failed. %s", err)
+	}
+
+	// 已经在教师信息中指定的分组不重复
+	if !reflect.DeepEqual(teachers[0].TeacherGroupIDs, []int{10}) || !reflect.DeepEqual(teachers[1].TeacherGroupIDs, []int{10, 20}) {
+		t.Errorf("teacher group ids: %v, %v", teachers[0].TeacherGroupIDs, teachers[1].TeacherGroupIDs)
+	}
+
+	if ids := GroupTeacherIDs(10, teachers); !reflect.DeepEqual(ids, []int{1, 2}) {
+		t.Errorf("group 10 teachers: %v", ids)
+	}
+
+	if !IsTeacherInGroup(2, 20, teachers) || IsTeacherInGroup(1, 20, teachers) || IsTeacherInGroup(3, 10, teachers) {
+		t.Errorf("is teacher in group: unexpected result")
+	}
+
+	// 分组成员不存在
+	err := ApplyTeacherGroups(teachers, []*TeacherGroup{{TeacherGroupID: 30, TeacherIDs: []int{3}}})
+	if err == nil {
+		t.Errorf("unknown member: want error")
+	}
+}
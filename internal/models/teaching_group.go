@@ -0,0 +1,85 @@
+// teaching_group.go
+package models
+
+import (
+	"fmt"
+
+	"github.com/samber/lo"
+)
+
+// 教学班(走班)
+// 新高考选科走班, 学生从多个行政班中重新组成教学班上课, 如: 物理A班, 历史B班
+// 教学班id在教学任务, 教师任课班级, 教学场地中作为班级id使用, 不能和行政班id重复
+//
+// | 年级   | 教学班id | 名称    | 学生来源行政班 | 走班时段 |
+// | ------ | -------- | ------- | -------------- | -------- |
+// | 高二   | 101      | 物理A班 | 1班, 2班       | 选考1    |
+// | 高二   | 102      | 历史A班 | 1班, 2班       | 选考1    |
+// | 高二   | 103      | 化学A班 | 3班, 4班       |          |
+type TeachingGroup struct {
+	TeachingGroupID int    `json:"teaching_group_id" mapstructure:"teaching_group_id"` // 教学班id
+	GradeID         int    `json:"grade_id" mapstructure:"grade_id"`                   // 年级id
+	Name            string `json:"name" mapstructure:"name"`                           // 教学班名称
+	ClassIDs        []int  `json:"class_ids" mapstructure:"class_ids"`                 // 学生来源的行政班id
+	ElectiveBlockID int    `json:"elective_block_id" mapstructure:"elective_block_id"` // 走班时段id, 可以为空
+}
+
+// 走班时段
+// 同一个走班时段内的多个教学班同时上课, 学生来源的行政班在这些时间段不能排其他课
+// 不属于任何走班时段的教学班, 上课时会占用学生来源的所有行政班
+type ElectiveBlock struct {
+	ElectiveBlockID int    `json:"elective_block_id" mapstructure:"elective_block_id"` // 走班时段id
+	GradeID         int    `json:"grade_id" mapstructure:"grade_id"`                   // 年级id
+	Name            string `json:"name" mapstructure:"name"`                           // 名称, 如: 选考1
+	TimeSlots       []int  `json:"time_slots" mapstructure:"time_slots"`               // 走班时段的时间段
+}
+
+// 根据教学班id查找教学班
+func FindTeachingGroupByID(gradeID, teachingGroupID int, teachingGroups []*TeachingGroup) (*TeachingGroup, error) {
+
+	for _, group := range teachingGroups {
+		if group.GradeID == gradeID && group.TeachingGroupID == teachingGroupID {
+			return group, nil
+		}
+	}
+	return nil, fmt.Errorf("teaching group not found")
+}
+
+// 根据走班时段id查找走班时段
+func FindElectiveBlockByID(electiveBlockID int, electiveBlocks []*ElectiveBlock) (*ElectiveBlock, error) {
+
+	for _, block := range electiveBlocks {
+		if block.ElectiveBlockID == electiveBlockID {
+			return block, nil
+		}
+	}
+	return nil, fmt.Errorf("elective block not found")
+}
+
+// 走班时段内的所有行政班id
+func (b *ElectiveBlock) ClassIDs(teachingGroups []*TeachingGroup) []int {
+
+	var classIDs []int
+	for _, group := range teachingGroups {
+		if group.GradeID == b.GradeID && group.ElectiveBlockID == b.ElectiveBlockID {
+			classIDs = append(classIDs, group.ClassIDs...)
+		}
+	}
+	return lo.Uniq(classIDs)
+}
+
+// 将教学班信息合并到教学任务中
+// 合并后, 只需要通过教学任务即可判断课程会占用哪些行政班
+func ApplyTeachingGroups(teachingTasks []*TeachingTask, teachingGroups []*TeachingGroup) {
+
+	for _, task := range teachingTasks {
+
+		group, err := FindTeachingGroupByID(task.GradeID, task.ClassID, teachingGroups)
+		if err != nil {
+			continue
+		}
+
+		task.HomeClassIDs = group.ClassIDs
+		task.ElectiveBlockID = group.ElectiveBlockID
+	}
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyTeachingGroups(t *testing.T) {
+
+	// 物理A班(101)和历史A班(102)在走班时段1, 化学A班(103)不属于走班时段
+	teachingGroups := []*TeachingGroup{
+		{TeachingGroupID: 101, GradeID: 11, Name: "物理A班", ClassIDs: []int{1, 2}, ElectiveBlockID: 1},
+		{TeachingGroupID: 102, GradeID: 11, Name: "历史A班", ClassIDs: []int{2, 3}, ElectiveBlockID: 1},
+		{TeachingGroupID: 103, GradeID: 11, Name: "化学A班", ClassIDs: []int{3, 4}},
+	}
+	teachingTasks := []*TeachingTask{
+		{SubjectID: 7, GradeID: 11, ClassID: 101},
+		{SubjectID: 9, GradeID: 11, ClassID: 102},
+		{SubjectID: 8, GradeID: 11, ClassID: 103},
+		{SubjectID: 1, GradeID: 11, ClassID: 1},
+	}
+
+	ApplyTeachingGroups(teachingTasks, teachingGroups)
+
+	if !reflect.DeepEqual(teachingTasks[0].HomeClassIDs, []int{1, 2}) || teachingTasks[0].ElectiveBlockID != 1 {
+		t.Errorf("teaching group 101: %+v", teachingTasks[0])
+	}
+	if teachingTasks[3].HomeClassIDs != nil || teachingTasks[3].ElectiveBlockID != 0 {
+		t.Errorf("class 1: %+v", teachingTasks[3])
+	}
+
+	// 走班时段内的教学班不占用行政班, 其他教学班占用学生来源的所有行政班
+	if ids := GetOccupiedClassIDs(11, 101, 7, teachingTasks); ids != nil {
+		t.Errorf("teaching group 101 occupied: %v", ids)
+	}
+	if ids := GetOccupiedClassIDs(11, 103, 8, teachingTasks); !reflect.DeepEqual(ids, []int{3, 4}) {
+		t.Errorf("teaching group 103 occupied: %v", ids)
+	}
+	if ids := GetOccupiedClassIDs(11, 1, 1, teachingTasks); ids != nil {
+		t.Errorf("class 1 occupied: %v", ids)
+	}
+
+	block := &ElectiveBlock{ElectiveBlockID: 1, GradeID: 11, Name: "选考1", TimeSlots: []int{0, 1}}
+	if ids := block.ClassIDs(teachingGroups); !reflect.DeepEqual(ids, []int{1, 2, 3}) {
+		t.Errorf("elective block class ids: %v", ids)
+	}
+}
@@ -10,12 +10,16 @@ type TeachingTask struct {
 	ClassID                    int    `json:"class_id" mapstructure:"class_id"`                                                 // 班级id
 	SubjectID                  int    `json:"subject_id" mapstructure:"subject_id"`                                             // 科目id
 	TeacherID                  int    `json:"teacher_id" mapstructure:"teacher_id"`                                             // 教师id
+	CoTeacherIDs               []int  `json:"co_teacher_ids,omitempty" mapstructure:"co_teacher_ids,omitempty"`                 // 协同上课的教师id, 需要和上课教师同时上课, 如: 体育分男女生上课, 实验课助教, 双语课
 	NumClassesPerWeek          int    `json:"num_classes_per_week" mapstructure:"num_classes_per_week"`                         // 每周几节课
-	NumConnectedClassesPerWeek int    `json:"num_connected_classes_per_week" mapstructure:"num_connected_classes_per_week"`     // 每周几次连堂课 1连堂课=2节课
+	NumConnectedClassesPerWeek int    `json:"num_connected_classes_per_week" mapstructure:"num_connected_classes_per_week"`     // 每周几次连堂课 1连堂课=connected_length节课
+	ConnectedLength            int    `json:"connected_length,omitempty" mapstructure:"connected_length,omitempty"`             // 每次连堂课的节数, 默认为2, 三连堂为3
 	WeekType                   string `json:"week_type,omitempty" mapstructure:"week_type,omitempty"`                           // 单双周类型: single 表示单周，double 表示双周, 默认为空, 不做设置
 	SubjectIDForWeek           int    `json:"subject_id_for_week,omitempty" mapstructure:"subject_id_for_week,omitempty"`       // 单双周轮换科目
 	SubjectIDOnDiffDay         int    `json:"subject_id_on_diff_day,omitempty" mapstructure:"subject_id_on_diff_day,omitempty"` // 不同天上课科目id TODO: 这个和科目互斥有重复?
 	CourseType                 string `json:"course_type" mapstructure:"course_type"`                                           // 课程类型: class_specific 表示班级特殊课, grade_shared 表示年级统一课
+	HomeClassIDs               []int  `json:"home_class_ids,omitempty" mapstructure:"home_class_ids,omitempty"`                 // 教学班学生来源的行政班id, 由教学班信息生成
+	ElectiveBlockID            int    `json:"elective_block_id,omitempty" mapstructure:"elective_block_id,omitempty"`           // 教学班所在的走班时段id, 由教学班信息生成
 }
 
 // NewTeachingTask 创建一个教学任务
@@ -65,6 +69,56 @@ func GetNumConnectedClassesPerWeek(gradeID, classID, subjectID int, teachingTask
 	return count
 }
 
+// 获取每次连堂课的节数
+// 没有设置时, 默认为2节
+func (t *TeachingTask) GetConnectedLength() int {
+	if t.ConnectedLength < 2 {
+		return 2
+	}
+	return t.ConnectedLength
+}
+
+// 获取一个科目每次连堂课的节数
+func GetConnectedLength(gradeID, classID, subjectID int, teachingTask []*TeachingTask) int {
+
+	for _, task := range teachingTask {
+
+		if task.GradeID == gradeID && task.ClassID == classID && task.SubjectID == subjectID {
+			return task.GetConnectedLength()
+		}
+	}
+	return 2
+}
+
+// 获取一个科目协同上课的教师
+func GetCoTeacherIDs(gradeID, classID, subjectID int, teachingTask []*TeachingTask) []int {
+
+	for _, task := range teachingTask {
+
+		if task.GradeID == gradeID && task.ClassID == classID && task.SubjectID == subjectID {
+			return task.CoTeacherIDs
+		}
+	}
+	return nil
+}
+
+// 获取课程上课时会占用的其他班级
+// 不属于走班时段的教学班, 上课时会占用学生来源的所有行政班
+// 走班时段内的教学班, 行政班已经预留了走班时段, 不需要再占用
+func GetOccupiedClassIDs(gradeID, classID, subjectID int, teachingTask []*TeachingTask) []int {
+
+	for _, task := range teachingTask {
+
+		if task.GradeID == gradeID && task.ClassID == classID && task.SubjectID == subjectID {
+			if task.ElectiveBlockID > 0 {
+				return nil
+			}
+			return task.HomeClassIDs
+		}
+	}
+	return nil
+}
+
 // 获取一个年级,一个班级，一个科目的所有老师
 func GetTeacherIDs(gradeID, classID, subjectID int, teachingTask []*TeachingTask) []int {
 
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"course_scheduler/config"
+	"time"
+)
+
+// 租户, 一个学校一个租户
+// 排课任务, 排课结果, 排课质量报告按照学校隔离, 限制为0时使用 config 中的默认值
+type Tenant struct {
+	SchoolID        uint64    `gorm:"primaryKey;type:bigint unsigned;not null;column:school_id" json:"school_id"`
+	Name            string    `gorm:"type:varchar(255);not null;default:'';column:name" json:"name"`
+	MaxRunningTasks int       `gorm:"type:int unsigned;not null;default:0;column:max_running_tasks" json:"max_running_tasks"`    // 同时执行的排课任务数量
+	MaxPayloadBytes int64     `gorm:"type:bigint unsigned;not null;default:0;column:max_payload_bytes" json:"max_payload_bytes"` // 请求体的最大字节数
+	CreatedAt       time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;column:created_at" json:"created_at"`
+	UpdatedAt       time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;column:updated_at" json:"updated_at"`
+}
+
+func (Tenant) TableName() string {
+	return "tenant"
+}
+
+// 同时执行的排课任务数量限制
+func (t *Tenant) RunningLimit() int {
+	if t.MaxRunningTasks > 0 {
+		return t.MaxRunningTasks
+	}
+	return config.TenantMaxRunningTasks
+}
+
+// 请求体的最大字节数
+func (t *Tenant) PayloadLimit() int64 {
+	if t.MaxPayloadBytes > 0 {
+		return t.MaxPayloadBytes
+	}
+	return config.TenantMaxPayloadBytes
+}
@@ -0,0 +1,108 @@
+// time_slot.go
+package models
+
+// 时间段
+// 一周的时间段按照 天*每天节数+节次 编号, 每天节数是各时间区间默认节数之和
+// 某天的节数少于每天节数时(如: 星期三下午只有2节课), 多出来的时间段不存在, 不能排课
+type TimeSlot struct {
+	Index   int    // 时间段编号
+	Day     int    // 天, 从0开始
+	Period  int    // 节次, 从0开始
+	Segment string // 时间区间 早读: morning_reading, 上午: forenoon, 下午: afternoon, 晚自习: night
+}
+
+// 时间区间, 按照一天中的先后顺序
+var Segments = []string{"morning_reading", "forenoon", "afternoon", "night"}
+
+// 周几, 从1开始
+func (t TimeSlot) Weekday() int {
+	return t.Day + 1
+}
+
+// 判断两个时间段是否是同一天, 同一个时间区间内相邻的两节课
+func (t TimeSlot) IsAdjacent(other TimeSlot) bool {
+	return t.Day == other.Day && t.Segment == other.Segment && (t.Period+1 == other.Period || other.Period+1 == t.Period)
+}
+
+// 根据时间段编号获取时间段
+func (s *Schedule) GetTimeSlot(index int) TimeSlot {
+
+	totalClassesPerDay := s.GetTotalClassesPerDay()
+	day := index / totalClassesPerDay
+	period := index % totalClassesPerDay
+
+	return TimeSlot{
+		Index:   index,
+		Day:     day,
+		Period:  period,
+		Segment: s.GetSegment(period),
+	}
+}
+
+// 根据天和节次获取时间段编号
+// day, period 从0开始
+func (s *Schedule) GetTimeSlotIndex(day, period int) int {
+	return day*s.GetTotalClassesPerDay() + period
+}
+
+// 根据节次获取所在的时间区间
+// period 从0开始
+func (s *Schedule) GetSegment(period int) string {
+
+	for _, segment := range Segments {
+		startPeriod, endPeriod := s.GetPeriodWithRange(segment)
+		if startPeriod != -1 && period >= startPeriod && period <= endPeriod {
+			return segment
+		}
+	}
+	return ""
+}
+
+// 判断时间段是否存在
+// 超出工作日, 或者超出当天时间区间节数的时间段不存在
+func (s *Schedule) IsTimeSlotAvailable(index int) bool {
+
+	if index < 0 {
+		return false
+	}
+
+	timeSlot := s.GetTimeSlot(index)
+	if timeSlot.Day >= s.NumWorkdays || timeSlot.Segment == "" {
+		return false
+	}
+
+	// 节次在时间区间内的序号
+	startPeriod, _ := s.GetPeriodWithRange(timeSlot.Segment)
+	offset := timeSlot.Period - startPeriod
+	return offset < s.GetNumClassesOfDay(timeSlot.Day, timeSlot.Segment)
+}
+
+// 获取某天某个时间区间的节数
+// day 从0开始
+func (s *Schedule) GetNumClassesOfDay(day int, segment string) int {
+
+	numClasses := s.getNumClasses(segment)
+	for _, weekdayClasses := range s.WeekdayClasses {
+		if weekdayClasses.Weekday == day+1 {
+			numClasses = weekdayClasses.getNumClasses(segment)
+		}
+	}
+	return numClasses
+}
+
+// 获取时间区间的默认节数
+func (s *Schedule) getNumClasses(segment string) int {
+
+	switch segment {
+	case "morning_reading":
+		return s.NumMorningReadingClasses
+	case "forenoon":
+		return s.NumForenoonClasses
+	case "afternoon":
+		return s.NumAfternoonClasses
+	case "night":
+		return s.NumNightClasses
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,82 @@
+// timetable_entry.go
+package models
+
+import (
+	"fmt"
+
+	"github.com/samber/lo"
+)
+
+// 已发布课表中的一节课
+// 字段含义与排课结果(ScheduleResult)相同, 连堂课每节一条, 协同上课的教师每人一条
+//
+// | 科目 | 年级 | 班级 | 教师 | 教室 | 天 | 节次 | 锁定 |
+// | ---- | ---- | ---- | ---- | ---- | -- | ---- | ---- |
+// | 语文 | 一年 | 1班  | 张三 | 101  | 0  | 0    | 是   |
+type TimetableEntry struct {
+	SubjectID int  `json:"subject_id" mapstructure:"subject_id"` // 科目id
+	GradeID   int  `json:"grade_id" mapstructure:"grade_id"`     // 年级id
+	ClassID   int  `json:"class_id" mapstructure:"class_id"`     // 班级id
+	TeacherID int  `json:"teacher_id" mapstructure:"teacher_id"` // 教师id
+	VenueID   int  `json:"venue_id" mapstructure:"venue_id"`     // 教室id
+	Weekday   int  `json:"weekday" mapstructure:"weekday"`       // 天, 从0开始
+	Period    int  `json:"period" mapstructure:"period"`         // 节次, 从0开始
+	Locked    bool `json:"locked" mapstructure:"locked"`         // 是否锁定, 锁定的课重排时不能移动
+}
+
+// 部分重排的范围
+// 只重新安排范围内的课, 范围外的课全部锁定
+// 教师, 班级, 天都为空表示对所有课生效, 多个条件同时满足才在范围内
+//
+// | 教师 | 班级       | 天   | 描述                   |
+// | ---- | ---------- | ---- | ---------------------- |
+// | 张三 |            |      | 张三离职, 重排他的课   |
+// |      | 一年级1班  | 0, 1 | 重排1班星期一, 星期二  |
+type RescheduleScope struct {
+	TeacherIDs []int    `json:"teacher_ids" mapstructure:"teacher_ids"` // 教师id, 可以为空
+	ClassKeys  []string `json:"class_keys" mapstructure:"class_keys"`   // 班级key 年级id_班级id, 如: 1_1, 可以为空
+	Weekdays   []int    `json:"weekdays" mapstructure:"weekdays"`       // 天, 从0开始, 可以为空
+}
+
+// 生成字符串
+func (e *TimetableEntry) String() string {
+	return fmt.Sprintf("SubjectID: %d, GradeID: %d, ClassID: %d, TeacherID: %d, VenueID: %d, Weekday: %d, Period: %d, Locked: %v",
+		e.SubjectID, e.GradeID, e.ClassID, e.TeacherID, e.VenueID, e.Weekday, e.Period, e.Locked)
+}
+
+// 课班信息 科目_年级_班级
+func (e *TimetableEntry) ClassSN() string {
+	return fmt.Sprintf("%d_%d_%d", e.SubjectID, e.GradeID, e.ClassID)
+}
+
+// 判断两节课是否相同, 不比较锁定状态
+func (e *TimetableEntry) Equal(other *TimetableEntry) bool {
+	return e.SubjectID == other.SubjectID && e.GradeID == other.GradeID && e.ClassID == other.ClassID &&
+		e.TeacherID == other.TeacherID && e.VenueID == other.VenueID && e.Weekday == other.Weekday && e.Period == other.Period
+}
+
+// 判断课是否在重排范围内
+func (s *RescheduleScope) Contains(entry *TimetableEntry) bool {
+
+	if len(s.TeacherIDs) > 0 && !lo.Contains(s.TeacherIDs, entry.TeacherID) {
+		return false
+	}
+
+	classKey := fmt.Sprintf("%d_%d", entry.GradeID, entry.ClassID)
+	if len(s.ClassKeys) > 0 && !lo.Contains(s.ClassKeys, classKey) {
+		return false
+	}
+
+	if len(s.Weekdays) > 0 && !lo.Contains(s.Weekdays, entry.Weekday) {
+		return false
+	}
+	return true
+}
+
+// 获取重排时锁定的课
+// 标记为锁定的课, 以及不在重排范围内的课
+func GetLockedTimetableEntries(entries []*TimetableEntry, scope *RescheduleScope) []*TimetableEntry {
+	return lo.Filter(entries, func(entry *TimetableEntry, _ int) bool {
+		return entry.Locked || (scope != nil && !scope.Contains(entry))
+	})
+}
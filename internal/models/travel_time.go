@@ -0,0 +1,60 @@
+// travel_time.go
+package models
+
+// 教学楼之间的通行时间
+// 教师在两个教学楼连续上课时, 两节课之间的课间需要足够走过去
+//
+// | 教学楼A | 教学楼B | 通行时间(分钟) |
+// | ------- | ------- | -------------- |
+// | 东校区  | 西校区  | 20             |
+// | 主楼    | 实验楼  | 5              |
+type TravelTime struct {
+	FromBuilding string `json:"from_building" mapstructure:"from_building"` // 教学楼A
+	ToBuilding   string `json:"to_building" mapstructure:"to_building"`     // 教学楼B
+	Minutes      int    `json:"minutes" mapstructure:"minutes"`             // 通行时间(分钟), A到B和B到A相同
+}
+
+// 获取两个教学楼之间的通行时间(分钟)
+// 同一个教学楼, 或者没有设置通行时间时返回0
+func GetTravelMinutes(fromBuilding, toBuilding string, travelTimes []*TravelTime) int {
+
+	if fromBuilding == toBuilding {
+		return 0
+	}
+
+	for _, t := range travelTimes {
+		if (t.FromBuilding == fromBuilding && t.ToBuilding == toBuilding) || (t.FromBuilding == toBuilding && t.ToBuilding == fromBuilding) {
+			return t.Minutes
+		}
+	}
+	return 0
+}
+
+// 判断两节课之间的课间, 是否不够从一个教学楼走到另一个教学楼
+// 只检查同一天相邻节次的两节课, 连堂课取第一节和最后一节
+// 没有配置作息时间表(或者没有这两节课的作息时间)时不知道课间的长度, 不检查
+func IsTravelTooShort(schedule *Schedule, venues []*Venue, travelTimes []*TravelTime, venueID1 int, timeSlots1 []int, venueID2 int, timeSlots2 []int) bool {
+
+	if len(timeSlots1) == 0 || len(timeSlots2) == 0 {
+		return false
+	}
+
+	minutes := GetTravelMinutes(GetVenueBuilding(venueID1, venues), GetVenueBuilding(venueID2, venues), travelTimes)
+	if minutes <= 0 {
+		return false
+	}
+
+	first1, last1 := schedule.GetTimeSlot(timeSlots1[0]), schedule.GetTimeSlot(timeSlots1[len(timeSlots1)-1])
+	first2, last2 := schedule.GetTimeSlot(timeSlots2[0]), schedule.GetTimeSlot(timeSlots2[len(timeSlots2)-1])
+
+	if last1.Day == first2.Day && last1.Period+1 == first2.Period {
+		gap, ok := schedule.GetGapMinutes(last1.Day, last1.Period)
+		return ok && gap < minutes
+	}
+
+	if last2.Day == first1.Day && last2.Period+1 == first1.Period {
+		gap, ok := schedule.GetGapMinutes(last2.Day, last2.Period)
+		return ok && gap < minutes
+	}
+	return false
+}
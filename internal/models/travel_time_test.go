@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestIsTravelTooShort(t *testing.T) {
+
+	venues := []*Venue{
+		{VenueID: 1, Name: "东校区101", Building: "东校区"},
+		{VenueID: 2, Name: "西校区201", Building: "西校区"},
+	}
+	travelTimes := []*TravelTime{
+		{FromBuilding: "东校区", ToBuilding: "西校区", Minutes: 20},
+	}
+
+	// 作息时间表: 第1节, 第2节之间10分钟, 第2节, 第3节之间30分钟大课间
+	schedule := &Schedule{Name: "两校区", NumWorkdays: 5, NumForenoonClasses: 4, BellSchedules: newTestBellSchedules()}
+
+	tests := []struct {
+		name       string
+		schedule   *Schedule
+		venueID1   int
+		timeSlots1 []int
+		venueID2   int
+		timeSlots2 []int
+		want       bool
+	}{
+		{"gap too short", schedule, 1, []int{0}, 2, []int{1}, true},
+		{"reverse order", schedule, 2, []int{1}, 1, []int{0}, true},
+		{"long break", schedule, 2, []int{2}, 1, []int{1}, false},
+		{"same building", schedule, 1, []int{0}, 1, []int{1}, false},
+		{"not adjacent", schedule, 1, []int{0}, 2, []int{2}, false},
+		{"connected", schedule, 1, []int{2, 3}, 2, []int{1}, false},
+		// 没有作息时间表时不知道课间的长度, 不检查
+		{"without bell schedule", &Schedule{Name: "默认", NumWorkdays: 5, NumForenoonClasses: 4}, 1, []int{0}, 2, []int{1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTravelTooShort(tt.schedule, venues, travelTimes, tt.venueID1, tt.timeSlots1, tt.venueID2, tt.timeSlots2); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,9 +6,33 @@ import (
 
 // 教学场地
 type Venue struct {
+	VenueID  int    `json:"venue_id" mapstructure:"venue_id"` // 场地id
 	Name     string `json:"name" mapstructure:"name"`         // 场地名称
 	Type     string `json:"type" mapstructure:"type"`         // 场地类型 exclusive: 专用教学场所, shared: 共享教学场所
 	Capacity int    `json:"capacity" mapstructure:"capacity"` // 教学场所能容纳的, 最多上课班级, 专用教学场所: 为固定值1, 共享教学场所: 默认值为 0,表示不限制
+	Building string `json:"building" mapstructure:"building"` // 所在的教学楼或校区, 如: 东校区, 实验楼, 可以为空
+}
+
+// 根据场地id查找场地
+func FindVenueByID(venueID int, venues []*Venue) (*Venue, error) {
+
+	for _, venue := range venues {
+		if venue.VenueID == venueID {
+			return venue, nil
+		}
+	}
+	return nil, fmt.Errorf("venue %d not found", venueID)
+}
+
+// 获取场地所在的教学楼
+// 场地不存在或者没有设置教学楼时返回空字符串
+func GetVenueBuilding(venueID int, venues []*Venue) string {
+
+	venue, err := FindVenueByID(venueID, venues)
+	if err != nil {
+		return ""
+	}
+	return venue.Building
 }
 
 // 教室集合
@@ -0,0 +1,702 @@
+// gorm.go
+package storage
+
+import (
+	"course_scheduler/internal/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 基于GORM的存储, MySQL和SQLite共用
+type gormStorage struct {
+	db *gorm.DB
+}
+
+// 使用已经打开的数据库连接创建存储
+func NewGormStorage(db *gorm.DB) Storage {
+	return &gormStorage{db: db}
+}
+
+func (s *gormStorage) Tasks() TaskRepository {
+	return &gormTaskRepository{db: s.db}
+}
+
+func (s *gormStorage) Results() ScheduleResultRepository {
+	return &gormScheduleResultRepository{db: s.db}
+}
+
+func (s *gormStorage) ErrorLogs() ScheduleErrorLogRepository {
+	return &gormScheduleErrorLogRepository{db: s.db}
+}
+
+func (s *gormStorage) Reports() ScheduleReportRepository {
+	return &gormScheduleReportRepository{db: s.db}
+}
+
+func (s *gormStorage) Generations() TaskGenerationRepository {
+	return &gormTaskGenerationRepository{db: s.db}
+}
+
+func (s *gormStorage) Callbacks() CallbackRepository {
+	return &gormCallbackRepository{db: s.db}
+}
+
+func (s *gormStorage) Tenants() TenantRepository {
+	return &gormTenantRepository{db: s.db}
+}
+
+func (s *gormStorage) APIKeys() APIKeyRepository {
+	return &gormAPIKeyRepository{db: s.db}
+}
+
+// 关闭数据库连接
+func (s *gormStorage) Close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// 排课任务
+type gormTaskRepository struct {
+	db *gorm.DB
+}
+
+// 幂等键由唯一索引保证不重复, 并发创建时只有一个请求成功
+// 不同数据库的唯一索引错误不同, 新增失败后再查询幂等键是否已经存在
+func (r *gormTaskRepository) Create(task *models.Task) error {
+
+	err := r.db.Create(task).Error
+	if err != nil && task.IdempotencyKey != nil {
+		if _, getErr := r.GetByIdempotencyKey(task.SchoolID, *task.IdempotencyKey); getErr == nil {
+			return ErrDuplicateKey
+		}
+	}
+	return err
+}
+
+func (r *gormTaskRepository) CreateFromSource(task *models.Task, sourceTaskID uint64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+
+		var source models.Task
+		if err := tx.Select("task_id", "status").Where("task_id = ?", sourceTaskID).First(&source).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if source.Status != models.TaskStatusSuccess {
+			return ErrTaskStatus
+		}
+
+		now := time.Now()
+		task.Status = models.TaskStatusSuccess
+		task.Progress = 100
+		task.SourceTaskID = sourceTaskID
+		task.StartedAt = &now
+		task.FinishedAt = &now
+		if task.CallbackURL != "" {
+			task.CallbackStatus = models.CallbackStatusPending
+			task.CallbackNextAt = &now
+		}
+		if err := (&gormTaskRepository{db: tx}).Create(task); err != nil {
+			return err
+		}
+
+		var results []*models.ScheduleResult
+		if err := tx.Where("task_id = ?", sourceTaskID).Order("result_id").Find(&results).Error; err != nil {
+			return err
+		}
+		for _, result := range results {
+			result.ResultID = 0
+			result.TaskID = task.TaskID
+		}
+		if len(results) > 0 {
+			if err := tx.CreateInBatches(results, len(results)).Error; err != nil {
+				return err
+			}
+		}
+
+		var report models.ScheduleReport
+		err := tx.Where("task_id = ?", sourceTaskID).First(&report).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		report.TaskID = task.TaskID
+		return tx.Create(&report).Error
+	})
+}
+
+func (r *gormTaskRepository) GetByIdempotencyKey(schoolID uint64, key string) (*models.Task, error) {
+	var task models.Task
+	if err := r.db.Where("school_id = ? AND idempotency_key = ?", schoolID, key).First(&task).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (r *gormTaskRepository) FindReusable(schoolID uint64, inputHash string) (*models.Task, error) {
+	var task models.Task
+	err := r.db.Omit("task_data").
+		Where("school_id = ? AND input_hash = ? AND status = ?", schoolID, inputHash, models.TaskStatusSuccess).
+		Order("task_id DESC").First(&task).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (r *gormTaskRepository) Get(taskID uint64) (*models.Task, error) {
+	var task models.Task
+	if err := r.db.Where("task_id = ?", taskID).First(&task).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &task, nil
+}
+
+// 在事务中先锁定学校的租户行(SELECT ... FOR UPDATE), 再统计学校执行中的任务数量并使用条件更新领取
+// 同一个学校的领取串行执行, 多个程序同时领取时执行中的任务数量也不会超过限制
+// 租户行不存在时先创建默认的租户行, 否则没有可以锁定的行
+// SQLite不支持FOR UPDATE, 但是只有一个连接, 事务本身就是串行执行的
+func (r *gormTaskRepository) Claim(taskID uint64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+
+		var task models.Task
+		if err := tx.Select("task_id", "school_id", "status").Where("task_id = ?", taskID).First(&task).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if task.Status != models.TaskStatusPending {
+			return ErrTaskNotPending
+		}
+
+		// 学校id可能为0, 使用map创建, 避免主键的零值被当作自增
+		err := tx.Model(&models.Tenant{}).Clauses(clause.OnConflict{DoNothing: true}).Create(map[string]interface{}{"school_id": task.SchoolID}).Error
+		if err != nil {
+			return err
+		}
+
+		var tenant models.Tenant
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("school_id = ?", task.SchoolID).First(&tenant).Error; err != nil {
+			return err
+		}
+
+		var running int64
+		if err := tx.Model(&models.Task{}).Where("school_id = ? AND status = ?", task.SchoolID, models.TaskStatusRunning).Count(&running).Error; err != nil {
+			return err
+		}
+		if running >= int64(tenant.RunningLimit()) {
+			return ErrTenantBusy
+		}
+
+		result := tx.Model(&models.Task{}).
+			Where("task_id = ? AND status = ?", taskID, models.TaskStatusPending).
+			Updates(map[string]interface{}{
+				"status":      models.TaskStatusRunning,
+				"progress":    0,
+				"attempts":    gorm.Expr("attempts + 1"),
+				"started_at":  time.Now(),
+				"finished_at": nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// 任务刚被取消或者删除
+		if result.RowsAffected == 0 {
+			return ErrTaskNotPending
+		}
+		return nil
+	})
+}
+
+// 先查询等待执行的任务, 再逐个领取
+// 每个任务的条件更新只有一个程序能成功, 学校执行中的任务数量由租户行锁保证
+// 学校的执行中任务达到限制后, 跳过这个学校的其他任务, 领取其他学校的任务
+func (r *gormTaskRepository) ClaimPending(limit int) ([]*models.Task, error) {
+
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	var pending []*models.Task
+	if err := r.db.Select("task_id", "school_id").Where("status = ?", models.TaskStatusPending).Order("task_id").Find(&pending).Error; err != nil {
+		return nil, err
+	}
+
+	var tasks []*models.Task
+	busy := make(map[uint64]bool)
+	for _, task := range pending {
+		if len(tasks) >= limit {
+			break
+		}
+		if busy[task.SchoolID] {
+			continue
+		}
+
+		err := r.Claim(task.TaskID)
+		if errors.Is(err, ErrTenantBusy) {
+			busy[task.SchoolID] = true
+			continue
+		}
+		if errors.Is(err, ErrTaskNotPending) || errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return tasks, err
+		}
+
+		claimed, err := r.Get(task.TaskID)
+		if err != nil {
+			return tasks, err
+		}
+		tasks = append(tasks, claimed)
+	}
+	return tasks, nil
+}
+
+func (r *gormTaskRepository) UpdateProgress(taskID uint64, progress int8) error {
+	return r.db.Model(&models.Task{}).Where("task_id = ?", taskID).Update("progress", progress).Error
+}
+
+func (r *gormTaskRepository) Complete(taskID uint64, results []*models.ScheduleResult, report *models.ScheduleReport) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+
+		// 先更新任务状态, 任务已经取消时不写入排课结果
+		err := (&gormTaskRepository{db: tx}).transit(taskID, []string{models.TaskStatusRunning}, finishValues(map[string]interface{}{
+			"status":   models.TaskStatusSuccess,
+			"progress": 100,
+		}))
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("task_id = ?", taskID).Delete(&models.ScheduleResult{}).Error; err != nil {
+			return err
+		}
+
+		if len(results) > 0 {
+			if err := tx.CreateInBatches(results, len(results)).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("task_id = ?", taskID).Delete(&models.ScheduleReport{}).Error; err != nil {
+			return err
+		}
+
+		if report != nil {
+			report.TaskID = taskID
+			return tx.Create(report).Error
+		}
+		return nil
+	})
+}
+
+func (r *gormTaskRepository) Fail(taskID uint64, errorMsg string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+
+		err := (&gormTaskRepository{db: tx}).transit(taskID, []string{models.TaskStatusRunning}, finishValues(map[string]interface{}{
+			"status": models.TaskStatusFailed,
+		}))
+		if err != nil {
+			return err
+		}
+
+		errorLog := &models.ScheduleErrorLog{
+			TaskID:   taskID,
+			ErrorMsg: errorMsg,
+		}
+		return tx.Create(errorLog).Error
+	})
+}
+
+func (r *gormTaskRepository) Cancel(taskID uint64) error {
+	return r.transit(taskID, []string{models.TaskStatusPending, models.TaskStatusRunning}, finishValues(map[string]interface{}{
+		"status": models.TaskStatusCancelled,
+	}))
+}
+
+func (r *gormTaskRepository) Retry(taskID uint64) error {
+	return r.transit(taskID, []string{models.TaskStatusFailed, models.TaskStatusCancelled}, map[string]interface{}{
+		"status":           models.TaskStatusPending,
+		"progress":         0,
+		"callback_status":  "",
+		"callback_next_at": nil,
+	})
+}
+
+func (r *gormTaskRepository) Release(taskID uint64) error {
+	return r.transit(taskID, []string{models.TaskStatusRunning}, map[string]interface{}{
+		"status":   models.TaskStatusPending,
+		"progress": 0,
+	})
+}
+
+func (r *gormTaskRepository) Delete(taskID uint64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+
+		result := tx.Where("task_id = ? AND status <> ?", taskID, models.TaskStatusRunning).Delete(&models.Task{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			if _, err := (&gormTaskRepository{db: tx}).Get(taskID); err != nil {
+				return err
+			}
+			return ErrTaskStatus
+		}
+
+		if err := tx.Where("task_id = ?", taskID).Delete(&models.ScheduleResult{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("task_id = ?", taskID).Delete(&models.ScheduleErrorLog{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("task_id = ?", taskID).Delete(&models.ScheduleReport{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("task_id = ?", taskID).Delete(&models.TaskGeneration{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("task_id = ?", taskID).Delete(&models.CallbackDelivery{}).Error
+	})
+}
+
+func (r *gormTaskRepository) List(filter *TaskFilter) ([]*models.Task, int64, error) {
+
+	query := r.db.Model(&models.Task{})
+	if filter.SchoolID > 0 {
+		query = query.Where("school_id = ?", filter.SchoolID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 任务数据可能很大, 列表中不返回
+	query = query.Omit("task_data").Order("created_at DESC, task_id DESC").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var tasks []*models.Task
+	if err := query.Find(&tasks).Error; err != nil {
+		return nil, 0, err
+	}
+	return tasks, total, nil
+}
+
+// 任务结束时更新的字段, 设置了回调地址的任务等待回调
+func finishValues(values map[string]interface{}) map[string]interface{} {
+
+	now := time.Now()
+	values["finished_at"] = now
+	values["callback_status"] = gorm.Expr("CASE WHEN callback_url <> '' THEN ? ELSE '' END", models.CallbackStatusPending)
+	values["callback_attempts"] = 0
+	values["callback_next_at"] = now
+	return values
+}
+
+// 将状态为from之一的任务更新为values
+// 任务不存在时返回ErrNotFound, 状态不满足时返回ErrTaskStatus
+func (r *gormTaskRepository) transit(taskID uint64, from []string, values map[string]interface{}) error {
+
+	result := r.db.Model(&models.Task{}).Where("task_id = ? AND status IN ?", taskID, from).Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		if _, err := r.Get(taskID); err != nil {
+			return err
+		}
+		return ErrTaskStatus
+	}
+	return nil
+}
+
+func (r *gormTaskRepository) AssignOrphans(schoolID uint64) (int64, error) {
+	result := r.db.Model(&models.Task{}).Where("school_id = ?", 0).Update("school_id", schoolID)
+	return result.RowsAffected, result.Error
+}
+
+func (r *gormTaskRepository) RecoverStale(before time.Time) (int64, error) {
+	result := r.db.Model(&models.Task{}).
+		Where("status = ? AND updated_at < ?", models.TaskStatusRunning, before).
+		Updates(map[string]interface{}{"status": models.TaskStatusPending, "progress": 0})
+	return result.RowsAffected, result.Error
+}
+
+// 排课结果
+type gormScheduleResultRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormScheduleResultRepository) CreateBatch(results []*models.ScheduleResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(results, len(results)).Error
+}
+
+func (r *gormScheduleResultRepository) ListByTask(taskID uint64) ([]*models.ScheduleResult, error) {
+	var results []*models.ScheduleResult
+	if err := r.db.Where("task_id = ?", taskID).Order("result_id").Find(&results).Error; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *gormScheduleResultRepository) CountByTask(taskID uint64) (int64, error) {
+	var count int64
+	if err := r.db.Model(&models.ScheduleResult{}).Where("task_id = ?", taskID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *gormScheduleResultRepository) Query(filter *ScheduleResultFilter) ([]*models.ScheduleResult, error) {
+
+	query := r.db.Where("task_id = ?", filter.TaskID)
+	conditions := []struct {
+		column string
+		value  uint64
+	}{
+		{"grade_id", filter.GradeID},
+		{"class_id", filter.ClassID},
+		{"teacher_id", filter.TeacherID},
+		{"venue_id", filter.VenueID},
+		{"subject_id", filter.SubjectID},
+	}
+	for _, c := range conditions {
+		if c.value > 0 {
+			query = query.Where(c.column+" = ?", c.value)
+		}
+	}
+	if filter.Weekday != nil {
+		query = query.Where("weekday = ?", *filter.Weekday)
+	}
+
+	var results []*models.ScheduleResult
+	if err := query.Order("weekday, period, result_id").Find(&results).Error; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// 排课错误日志
+type gormScheduleErrorLogRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormScheduleErrorLogRepository) Create(errorLog *models.ScheduleErrorLog) error {
+	return r.db.Create(errorLog).Error
+}
+
+func (r *gormScheduleErrorLogRepository) ListByTask(taskID uint64) ([]*models.ScheduleErrorLog, error) {
+	var errorLogs []*models.ScheduleErrorLog
+	if err := r.db.Where("task_id = ?", taskID).Order("error_id").Find(&errorLogs).Error; err != nil {
+		return nil, err
+	}
+	return errorLogs, nil
+}
+
+// 排课质量报告
+type gormScheduleReportRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormScheduleReportRepository) GetByTask(taskID uint64) (*models.ScheduleReport, error) {
+	var report models.ScheduleReport
+	if err := r.db.Where("task_id = ?", taskID).First(&report).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &report, nil
+}
+
+// 排课任务每一代遗传的监控数据
+type gormTaskGenerationRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormTaskGenerationRepository) Create(generation *models.TaskGeneration) error {
+	return r.db.Create(generation).Error
+}
+
+func (r *gormTaskGenerationRepository) ListByTask(taskID uint64, afterGeneration int) ([]*models.TaskGeneration, error) {
+	var generations []*models.TaskGeneration
+	if err := r.db.Where("task_id = ? AND generation > ?", taskID, afterGeneration).Order("generation").Find(&generations).Error; err != nil {
+		return nil, err
+	}
+	return generations, nil
+}
+
+func (r *gormTaskGenerationRepository) DeleteByTask(taskID uint64) error {
+	return r.db.Where("task_id = ?", taskID).Delete(&models.TaskGeneration{}).Error
+}
+
+// 排课任务完成回调
+type gormCallbackRepository struct {
+	db *gorm.DB
+}
+
+// 先查询到期的任务, 再逐个使用条件更新下次回调时间领取, 与ClaimPending相同
+func (r *gormCallbackRepository) ClaimDue(now time.Time, lease time.Duration, limit int) ([]*models.Task, error) {
+
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	var due []*models.Task
+	err := r.db.Omit("task_data").
+		Where("callback_status = ? AND callback_next_at <= ?", models.CallbackStatusPending, now).
+		Order("callback_next_at").Limit(limit).Find(&due).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []*models.Task
+	for _, task := range due {
+		result := r.db.Model(&models.Task{}).
+			Where("task_id = ? AND callback_status = ? AND callback_next_at <= ?", task.TaskID, models.CallbackStatusPending, now).
+			Update("callback_next_at", now.Add(lease))
+		if result.Error != nil {
+			return tasks, result.Error
+		}
+		if result.RowsAffected == 1 {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+func (r *gormCallbackRepository) Record(delivery *models.CallbackDelivery, next *time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+
+		if err := tx.Create(delivery).Error; err != nil {
+			return err
+		}
+
+		values := map[string]interface{}{"callback_attempts": delivery.Attempt}
+		switch {
+		case delivery.Success:
+			values["callback_status"] = models.CallbackStatusDelivered
+			values["callback_next_at"] = nil
+		case next != nil:
+			values["callback_next_at"] = *next
+		default:
+			values["callback_status"] = models.CallbackStatusFailed
+			values["callback_next_at"] = nil
+		}
+
+		return tx.Model(&models.Task{}).
+			Where("task_id = ? AND callback_status = ?", delivery.TaskID, models.CallbackStatusPending).
+			Updates(values).Error
+	})
+}
+
+func (r *gormCallbackRepository) ListByTask(taskID uint64) ([]*models.CallbackDelivery, error) {
+	var deliveries []*models.CallbackDelivery
+	if err := r.db.Where("task_id = ?", taskID).Order("delivery_id").Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// 租户
+type gormTenantRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormTenantRepository) Get(schoolID uint64) (*models.Tenant, error) {
+	var tenant models.Tenant
+	if err := r.db.Where("school_id = ?", schoolID).First(&tenant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+func (r *gormTenantRepository) Save(tenant *models.Tenant) error {
+	return r.db.Save(tenant).Error
+}
+
+// API密钥
+type gormAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormAPIKeyRepository) Create(key *models.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *gormAPIKeyRepository) GetByHash(keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.Where("key_hash = ? AND revoked_at IS NULL", keyHash).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *gormAPIKeyRepository) Revoke(keyID uint64) error {
+	result := r.db.Model(&models.APIKey{}).Where("key_id = ?", keyID).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormAPIKeyRepository) List(schoolID uint64) ([]*models.APIKey, error) {
+	query := r.db.Order("key_id")
+	if schoolID > 0 {
+		query = query.Where("school_id = ?", schoolID)
+	}
+
+	var keys []*models.APIKey
+	if err := query.Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
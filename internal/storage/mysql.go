@@ -0,0 +1,24 @@
+// mysql.go
+package storage
+
+import (
+	"fmt"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// 打开MySQL存储
+// 数据表需要提前使用 docs/course_scheduler.sql 创建
+// 旧版本的数据库需要先执行 docs/course_scheduler_upgrade.sql 升级
+func NewMySQLStorage(dsn string) (Storage, error) {
+
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("open mysql failed. %s", err)
+	}
+	if err := checkSchema(db); err != nil {
+		return nil, fmt.Errorf("%s. run docs/course_scheduler_upgrade.sql", err)
+	}
+	return NewGormStorage(db), nil
+}
@@ -0,0 +1,55 @@
+// schema.go
+package storage
+
+import (
+	"course_scheduler/internal/models"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 存储使用的全部数据表
+var schemaModels = []interface{}{
+	&models.Task{},
+	&models.ScheduleResult{},
+	&models.ScheduleErrorLog{},
+	&models.ScheduleReport{},
+	&models.TaskGeneration{},
+	&models.CallbackDelivery{},
+	&models.Tenant{},
+	&models.APIKey{},
+}
+
+// 检查数据表和字段是否齐全
+// 旧版本创建的数据库缺少新增的表和字段, 直接使用会在第一次查询时报错
+func checkSchema(db *gorm.DB) error {
+
+	var missing []string
+	migrator := db.Migrator()
+	for _, model := range schemaModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return fmt.Errorf("parse model failed. %s", err)
+		}
+
+		table := stmt.Schema.Table
+		if !migrator.HasTable(table) {
+			missing = append(missing, table)
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			if !migrator.HasColumn(model, field.DBName) {
+				missing = append(missing, table+"."+field.DBName)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("database schema is outdated, missing %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+// sqlite.go
+package storage
+
+import (
+	"fmt"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// SQLite的数据表, 与 docs/course_scheduler.sql 中MySQL的数据表相同
+// SQLite不支持ENUM等类型, 所以不使用模型自动迁移
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS task (
+		task_id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		school_id INTEGER NOT NULL DEFAULT 0,
+		task_data TEXT NOT NULL,
+		status TEXT NOT NULL CHECK (status IN ('pending', 'running', 'success', 'failed', 'cancelled')),
+		progress INTEGER NOT NULL DEFAULT 0,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		started_at TIMESTAMP NULL,
+		finished_at TIMESTAMP NULL,
+		callback_url TEXT NOT NULL DEFAULT '',
+		callback_secret TEXT NOT NULL DEFAULT '',
+		callback_status TEXT NOT NULL DEFAULT '' CHECK (callback_status IN ('', 'pending', 'delivered', 'failed')),
+		callback_attempts INTEGER NOT NULL DEFAULT 0,
+		callback_next_at TIMESTAMP NULL,
+		idempotency_key TEXT NULL,
+		input_hash TEXT NOT NULL DEFAULT '',
+		source_task_id INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_task_status ON task (status)`,
+	`CREATE INDEX IF NOT EXISTS idx_task_school_status ON task (school_id, status)`,
+	`CREATE INDEX IF NOT EXISTS idx_task_created_at ON task (created_at)`,
+	`CREATE INDEX IF NOT EXISTS idx_task_callback ON task (callback_status, callback_next_at)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS uk_task_school_idempotency_key ON task (school_id, idempotency_key)`,
+	`CREATE INDEX IF NOT EXISTS idx_task_school_input_hash ON task (school_id, input_hash)`,
+	`CREATE TABLE IF NOT EXISTS callback_delivery (
+		delivery_id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		task_id INTEGER NOT NULL,
+		attempt INTEGER NOT NULL,
+		url TEXT NOT NULL,
+		task_status TEXT NOT NULL,
+		status_code INTEGER NOT NULL DEFAULT 0,
+		success INTEGER NOT NULL DEFAULT 0,
+		error_message TEXT NOT NULL,
+		duration_ms INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_callback_delivery_task_id ON callback_delivery (task_id)`,
+	`CREATE TABLE IF NOT EXISTS tenant (
+		school_id INTEGER NOT NULL PRIMARY KEY,
+		name TEXT NOT NULL DEFAULT '',
+		max_running_tasks INTEGER NOT NULL DEFAULT 0,
+		max_payload_bytes INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS api_key (
+		key_id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		school_id INTEGER NOT NULL,
+		name TEXT NOT NULL DEFAULT '',
+		key_prefix TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE,
+		revoked_at TIMESTAMP NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_api_key_school_id ON api_key (school_id)`,
+	`CREATE TABLE IF NOT EXISTS schedule_error_log (
+		error_id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		task_id INTEGER NOT NULL,
+		error_message TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_schedule_error_log_task_id ON schedule_error_log (task_id)`,
+	`CREATE TABLE IF NOT EXISTS schedule_result (
+		result_id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		task_id INTEGER NOT NULL,
+		subject_id INTEGER NOT NULL,
+		teacher_id INTEGER NOT NULL,
+		grade_id INTEGER NOT NULL,
+		class_id INTEGER NOT NULL,
+		venue_id INTEGER NOT NULL,
+		weekday INTEGER NOT NULL,
+		period INTEGER NOT NULL,
+		start_time TEXT NOT NULL,
+		end_time TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_schedule_result_task_id ON schedule_result (task_id)`,
+	`CREATE TABLE IF NOT EXISTS schedule_report (
+		task_id INTEGER NOT NULL PRIMARY KEY,
+		fitness INTEGER NOT NULL,
+		num_hard_violations INTEGER NOT NULL DEFAULT 0,
+		num_soft_violations INTEGER NOT NULL DEFAULT 0,
+		termination_reason TEXT NOT NULL,
+		report TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS task_generation (
+		task_id INTEGER NOT NULL,
+		generation INTEGER NOT NULL,
+		best_fitness INTEGER NOT NULL,
+		avg_fitness REAL NOT NULL,
+		worst_fitness INTEGER NOT NULL,
+		gen_without_improvement INTEGER NOT NULL,
+		progress INTEGER NOT NULL,
+		elapsed_ms INTEGER NOT NULL,
+		eta_ms INTEGER NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (task_id, generation)
+	)`,
+}
+
+// 打开SQLite存储, 数据表不存在时自动创建
+// dsn 为数据库文件路径, 如: course_scheduler.db
+func NewSQLiteStorage(dsn string) (Storage, error) {
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite failed. %s", err)
+	}
+
+	// SQLite同一时间只能有一个写连接
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	for _, stmt := range sqliteSchema {
+		if err := db.Exec(stmt).Error; err != nil {
+			return nil, fmt.Errorf("create sqlite schema failed. %s", err)
+		}
+	}
+	// 已有的数据表不会被重新创建, 旧版本的数据库文件需要删除后重建
+	if err := checkSchema(db); err != nil {
+		return nil, err
+	}
+	return NewGormStorage(db), nil
+}
@@ -0,0 +1,195 @@
+// storage.go
+package storage
+
+import (
+	"course_scheduler/internal/models"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 记录不存在
+var ErrNotFound = errors.New("record not found")
+
+// 排课任务不是等待执行状态, 已经被其他程序领取
+var ErrTaskNotPending = errors.New("task is not pending")
+
+// 学校同时执行的排课任务已经达到限制, 任务保持等待执行
+var ErrTenantBusy = errors.New("too many running tasks for the school")
+
+// 同一个学校已经有相同幂等键的排课任务
+var ErrDuplicateKey = errors.New("idempotency key already used")
+
+// 排课任务当前的状态不允许执行这个操作, 如: 取消已经完成的任务, 删除执行中的任务
+var ErrTaskStatus = errors.New("task status does not allow the operation")
+
+// 排课任务查询条件, 为空的条件不生效
+type TaskFilter struct {
+	SchoolID      uint64     // 学校(租户)id
+	Status        string     // 任务状态
+	CreatedAfter  *time.Time // 创建时间不早于
+	CreatedBefore *time.Time // 创建时间早于
+	Offset        int        // 分页偏移量
+	Limit         int        // 分页数量, 0表示不限制
+}
+
+// 排课任务存储
+type TaskRepository interface {
+	// 新增排课任务, 新增后task.TaskID为任务id, 任务id由数据库自增生成
+	// 同一个学校已经有相同幂等键的任务时返回ErrDuplicateKey
+	Create(task *models.Task) error
+	// 新增一个直接成功的排课任务, 在同一个事务中复制sourceTaskID的排课结果和排课质量报告
+	// 源任务不是成功状态时返回ErrTaskStatus
+	CreateFromSource(task *models.Task, sourceTaskID uint64) error
+	// 根据任务id获取排课任务, 不存在时返回ErrNotFound
+	Get(taskID uint64) (*models.Task, error)
+	// 根据学校和幂等键获取排课任务, 不存在时返回ErrNotFound
+	GetByIdempotencyKey(schoolID uint64, key string) (*models.Task, error)
+	// 获取学校最近一个排课数据相同的成功任务, 用于复用排课结果, 不存在时返回ErrNotFound
+	FindReusable(schoolID uint64, inputHash string) (*models.Task, error)
+	// 领取排课任务, 将等待执行的任务改为执行中, 任务不是等待执行状态时返回ErrTaskNotPending
+	// 学校执行中的任务达到租户的限制时返回ErrTenantBusy
+	Claim(taskID uint64) error
+	// 领取最多limit个等待执行的任务, 按照创建顺序领取, 多个程序同时领取时每个任务只会被领取一次
+	// 跳过执行中的任务已经达到租户限制的学校
+	ClaimPending(limit int) ([]*models.Task, error)
+	// 更新排课任务进度(0-100)
+	UpdateProgress(taskID uint64, progress int8) error
+	// 排课成功, 在同一个事务中写入排课结果和排课质量报告(可以为空), 并将任务状态改为success
+	// 任务之前写入的排课结果和报告会被删除, 重新执行的任务不会有重复的排课结果
+	// 任务不是执行中(如: 已经取消)时返回ErrTaskStatus, 不写入排课结果
+	Complete(taskID uint64, results []*models.ScheduleResult, report *models.ScheduleReport) error
+	// 排课失败, 在同一个事务中写入排课错误日志, 并将任务状态改为failed
+	// 任务不是执行中(如: 已经取消)时返回ErrTaskStatus, 不写入错误日志
+	Fail(taskID uint64, errorMsg string) error
+	// 取消等待执行或者执行中的任务, 其他状态返回ErrTaskStatus
+	// 执行中的任务由执行程序在下一代遗传结束时停止
+	Cancel(taskID uint64) error
+	// 重新执行失败或者已经取消的任务, 任务改回等待执行, 其他状态返回ErrTaskStatus
+	// 还没有发送的回调不再发送, 任务再次结束时重新回调
+	Retry(taskID uint64) error
+	// 执行程序停止时将执行中的任务改回等待执行, 由其他程序或者重启后重新执行, 其他状态返回ErrTaskStatus
+	Release(taskID uint64) error
+	// 删除任务以及任务的排课结果, 错误日志, 排课质量报告, 遗传代数据和回调记录, 执行中的任务返回ErrTaskStatus
+	Delete(taskID uint64) error
+	// 按照条件查询任务, 按照创建时间倒序, 返回任务(不包含任务数据)和满足条件的任务总数
+	List(filter *TaskFilter) ([]*models.Task, int64, error)
+	// 将租户隔离之前新增的任务(学校id为0)分配给学校, 返回分配的任务数量
+	AssignOrphans(schoolID uint64) (int64, error)
+	// 将before之前更新的执行中的任务改回等待执行, 返回恢复的任务数量
+	// 用于恢复程序崩溃时没有执行完的任务
+	RecoverStale(before time.Time) (int64, error)
+}
+
+// 排课结果查询条件, 除任务id外为空的条件不生效
+type ScheduleResultFilter struct {
+	TaskID    uint64 // 任务id
+	GradeID   uint64 // 年级id
+	ClassID   uint64 // 班级id
+	TeacherID uint64 // 教师id
+	VenueID   uint64 // 教室id
+	SubjectID uint64 // 科目id
+	Weekday   *int8  // 天, 从0开始
+}
+
+// 排课结果存储
+type ScheduleResultRepository interface {
+	// 批量新增排课结果
+	CreateBatch(results []*models.ScheduleResult) error
+	// 获取排课任务的排课结果
+	ListByTask(taskID uint64) ([]*models.ScheduleResult, error)
+	// 统计排课任务的排课结果数量
+	CountByTask(taskID uint64) (int64, error)
+	// 按照条件查询排课结果, 按照天, 节次排序
+	Query(filter *ScheduleResultFilter) ([]*models.ScheduleResult, error)
+}
+
+// 排课错误日志存储
+type ScheduleErrorLogRepository interface {
+	// 新增排课错误日志
+	Create(errorLog *models.ScheduleErrorLog) error
+	// 获取排课任务的错误日志
+	ListByTask(taskID uint64) ([]*models.ScheduleErrorLog, error)
+}
+
+// 排课质量报告存储
+// 报告在排课成功时由TaskRepository.Complete写入
+type ScheduleReportRepository interface {
+	// 获取排课任务的排课质量报告, 不存在时返回ErrNotFound
+	GetByTask(taskID uint64) (*models.ScheduleReport, error)
+}
+
+// 排课任务每一代遗传的监控数据存储
+type TaskGenerationRepository interface {
+	// 新增一代的监控数据
+	Create(generation *models.TaskGeneration) error
+	// 获取排课任务afterGeneration之后的监控数据, 按照代数排序
+	ListByTask(taskID uint64, afterGeneration int) ([]*models.TaskGeneration, error)
+	// 删除排课任务的监控数据, 任务重新执行前调用
+	DeleteByTask(taskID uint64) error
+}
+
+// 排课任务完成回调存储
+// 设置了回调地址的任务结束(成功, 失败, 取消)时, 回调状态改为pending, 由执行程序发送回调
+type CallbackRepository interface {
+	// 领取最多limit个到期需要回调的任务(不包含任务数据), 按照下次回调时间排序
+	// 领取后lease时间内不会再被领取, 多个程序同时领取时每个任务只会被领取一次
+	ClaimDue(now time.Time, lease time.Duration, limit int) ([]*models.Task, error)
+	// 在同一个事务中写入回调记录, 并更新任务的回调状态
+	// 回调成功时改为delivered, 失败时next为下次回调时间, next为空时改为failed不再重试
+	// 任务的回调状态已经不是pending(如: 任务已经重新执行)时只写入回调记录
+	Record(delivery *models.CallbackDelivery, next *time.Time) error
+	// 获取排课任务的回调记录
+	ListByTask(taskID uint64) ([]*models.CallbackDelivery, error)
+}
+
+// 租户存储
+type TenantRepository interface {
+	// 获取学校的租户设置, 不存在时返回ErrNotFound
+	Get(schoolID uint64) (*models.Tenant, error)
+	// 新增或者更新租户设置
+	Save(tenant *models.Tenant) error
+}
+
+// API密钥存储
+type APIKeyRepository interface {
+	// 新增API密钥, 新增后key.KeyID为密钥id
+	Create(key *models.APIKey) error
+	// 根据密钥的SHA-256获取没有吊销的API密钥, 不存在或者已经吊销时返回ErrNotFound
+	GetByHash(keyHash string) (*models.APIKey, error)
+	// 吊销API密钥, 不存在时返回ErrNotFound
+	Revoke(keyID uint64) error
+	// 获取学校的API密钥, schoolID为0时获取所有的密钥
+	List(schoolID uint64) ([]*models.APIKey, error)
+}
+
+// 存储
+// 汇总排课任务, 排课结果, 排课错误日志, 排课质量报告, 遗传代数据, 回调, 租户, API密钥的存储, 不同的数据库各有一个实现
+type Storage interface {
+	Tasks() TaskRepository
+	Results() ScheduleResultRepository
+	ErrorLogs() ScheduleErrorLogRepository
+	Reports() ScheduleReportRepository
+	Generations() TaskGenerationRepository
+	Callbacks() CallbackRepository
+	Tenants() TenantRepository
+	APIKeys() APIKeyRepository
+	Close() error
+}
+
+// 存储的数据库类型
+var Drivers = []string{"mysql", "sqlite"}
+
+// 根据数据库类型和连接信息打开存储
+// mysql: dsn 如: root:root@tcp(127.0.0.1:3306)/course_scheduler?charset=utf8mb4&parseTime=true&loc=Local
+// sqlite: dsn 为数据库文件路径, 如: course_scheduler.db, 内存数据库 file::memory:
+func Open(driver, dsn string) (Storage, error) {
+	switch driver {
+	case "mysql":
+		return NewMySQLStorage(dsn)
+	case "sqlite":
+		return NewSQLiteStorage(dsn)
+	default:
+		return nil, fmt.Errorf("invalid storage driver %q, must be one of %v", driver, Drivers)
+	}
+}
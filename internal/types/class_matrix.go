@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"sort"
 	"strings"
 
 	"github.com/samber/lo"
@@ -73,11 +74,16 @@ func (cm *ClassMatrix) Init() error {
 		gradeID := subjectClass.SN.GradeID
 		classID := subjectClass.SN.ClassID
 
-		teacherIDs := models.ClassTeacherIDs(gradeID, classID, subjectID, cm.Teachers)
+		// 协同上课的教师, 不作为上课教师
+		coTeacherIDs := models.GetCoTeacherIDs(gradeID, classID, subjectID, cm.TeachingTasks)
+		teacherIDs := lo.Without(models.ClassTeacherIDs(gradeID, classID, subjectID, cm.Teachers), coTeacherIDs...)
 		if len(teacherIDs) == 0 {
 			return fmt.Errorf("no teacher available for class subjectID: %d, gradeID: %d, classID: %d", subjectID, gradeID, classID)
 		}
 
+		// 教学班上课时会占用的行政班
+		occupiedClassIDs := models.GetOccupiedClassIDs(gradeID, classID, subjectID, cm.TeachingTasks)
+
 		venueIDs := models.ClassVenueIDs(gradeID, classID, subjectID, cm.SubjectVenueMap)
 		if len(venueIDs) == 0 {
 			return fmt.Errorf("no venue available for class subjectID: %d, gradeID: %d, classID: %d", subjectID, gradeID, classID)
@@ -104,6 +110,8 @@ func (cm *ClassMatrix) Init() error {
 
 					timeSlots := utils.ParseTimeSlotStr(connectedStr)
 					element := NewElement(sn, subjectClass.SubjectID, subjectClass.GradeID, subjectClass.ClassID, teacherID, venueID, timeSlots)
+					element.CoTeacherIDs = coTeacherIDs
+					element.OccupiedClassIDs = occupiedClassIDs
 					cm.Elements[sn][teacherID][venueID][connectedStr] = element
 				}
 
@@ -112,6 +120,8 @@ func (cm *ClassMatrix) Init() error {
 
 					timeSlots := utils.ParseTimeSlotStr(normalStr)
 					element := NewElement(sn, subjectClass.SubjectID, subjectClass.GradeID, subjectClass.ClassID, teacherID, venueID, timeSlots)
+					element.CoTeacherIDs = coTeacherIDs
+					element.OccupiedClassIDs = occupiedClassIDs
 					cm.Elements[sn][teacherID][venueID][normalStr] = element
 				}
 			}
@@ -145,11 +155,13 @@ func (cm *ClassMatrix) UpdateElementScore(schedule *models.Schedule, teachingTas
 
 	// 更新固定约束得分
 	element.Val.ScoreInfo.FixedFailed = fixedVal.ScoreInfo.FixedFailed
+	element.Val.ScoreInfo.FixedHardFailed = fixedVal.ScoreInfo.FixedHardFailed
 	element.Val.ScoreInfo.FixedPassed = fixedVal.ScoreInfo.FixedPassed
 	element.Val.ScoreInfo.FixedScore = fixedVal.ScoreInfo.FixedScore
 
 	// 更新动态约束得分
 	element.Val.ScoreInfo.DynamicFailed = dynamicVal.ScoreInfo.DynamicFailed
+	element.Val.ScoreInfo.DynamicHardFailed = dynamicVal.ScoreInfo.DynamicHardFailed
 	element.Val.ScoreInfo.DynamicPassed = dynamicVal.ScoreInfo.DynamicPassed
 	element.Val.ScoreInfo.DynamicScore = dynamicVal.ScoreInfo.DynamicScore
 
@@ -178,9 +190,10 @@ func (cm *ClassMatrix) Allocate(rules []*Rule) (int, error) {
 		subjectID := sc.SN.SubjectID
 		numConnectedClassesPerWeek := models.GetNumConnectedClassesPerWeek(gradeID, classID, subjectID, cm.TeachingTasks)
 
-		// 分配课时
+		// 分配课时, 已锁定或者已占用的课不再分配
 		connectedCount := numConnectedClassesPerWeek
-		for i := 0; i < connectedCount; i++ {
+		usedConnected, _ := cm.countUsed(sn)
+		for i := usedConnected; i < connectedCount; i++ {
 			if err := cm.allocateClass(sn, true, rules); err != nil {
 				return allocateCount, err
 			}
@@ -197,12 +210,14 @@ func (cm *ClassMatrix) Allocate(rules []*Rule) (int, error) {
 		subjectID := sc.SN.SubjectID
 		numClassesPerWeek := models.GetNumClassesPerWeek(gradeID, classID, subjectID, cm.TeachingTasks)
 		numConnectedClassesPerWeek := models.GetNumConnectedClassesPerWeek(gradeID, classID, subjectID, cm.TeachingTasks)
+		connectedLength := models.GetConnectedLength(gradeID, classID, subjectID, cm.TeachingTasks)
 
 		// 分配课时
-		normalCount := numClassesPerWeek - numConnectedClassesPerWeek*2
+		normalCount := numClassesPerWeek - numConnectedClassesPerWeek*connectedLength
 
 		// 然后在分配普通课
-		for i := 0; i < normalCount; i++ {
+		_, usedNormal := cm.countUsed(sn)
+		for i := usedNormal; i < normalCount; i++ {
 			if err := cm.allocateClass(sn, false, rules); err != nil {
 				return allocateCount, err
 			}
@@ -215,6 +230,130 @@ func (cm *ClassMatrix) Allocate(rules []*Rule) (int, error) {
 	return allocateCount, nil
 }
 
+// 锁定已排的课
+// 部分重排时, 锁定的课直接占用矩阵元素, 分配课时时不再分配, 之后也不能移动
+func (cm *ClassMatrix) Lock(entries []*models.TimetableEntry) error {
+	return cm.occupy(entries, true)
+}
+
+// 占用已排的课
+// 与锁定相同, 但是占用的课之后可以移动, 用于以已发布的课表为基础生成个体
+func (cm *ClassMatrix) Occupy(entries []*models.TimetableEntry) error {
+	return cm.occupy(entries, false)
+}
+
+// 占用已排的课对应的矩阵元素
+// 同一课班, 教师, 教室连续的节次, 优先占用为连堂课
+// 协同上课的教师不作为矩阵的教师, 只占用上课教师的课
+func (cm *ClassMatrix) occupy(entries []*models.TimetableEntry, locked bool) error {
+
+	// key: 课班_教师_教室, value: 时间段
+	type occupyKey struct {
+		sn        string
+		teacherID int
+		venueID   int
+	}
+	occupyTimeSlots := make(map[occupyKey][]int)
+	var keys []occupyKey
+
+	for _, entry := range entries {
+
+		sn := entry.ClassSN()
+		if _, ok := cm.Elements[sn]; !ok {
+			return fmt.Errorf("occupied lesson class not found. %s", entry)
+		}
+
+		coTeacherIDs := models.GetCoTeacherIDs(entry.GradeID, entry.ClassID, entry.SubjectID, cm.TeachingTasks)
+		if lo.Contains(coTeacherIDs, entry.TeacherID) {
+			continue
+		}
+
+		key := occupyKey{sn: sn, teacherID: entry.TeacherID, venueID: entry.VenueID}
+		if _, ok := occupyTimeSlots[key]; !ok {
+			keys = append(keys, key)
+		}
+		timeSlot := cm.Schedule.GetTimeSlotIndex(entry.Weekday, entry.Period)
+		occupyTimeSlots[key] = lo.Uniq(append(occupyTimeSlots[key], timeSlot))
+	}
+
+	for _, key := range keys {
+
+		SN, _ := ParseSN(key.sn)
+		numConnected := models.GetNumConnectedClassesPerWeek(SN.GradeID, SN.ClassID, SN.SubjectID, cm.TeachingTasks)
+		connectedLength := models.GetConnectedLength(SN.GradeID, SN.ClassID, SN.SubjectID, cm.TeachingTasks)
+
+		timeSlots := occupyTimeSlots[key]
+		sort.Ints(timeSlots)
+
+		for i := 0; i < len(timeSlots); {
+
+			// 连续的节次, 并且还需要连堂课时, 锁定为连堂课
+			length := 1
+			usedConnected, _ := cm.countUsed(key.sn)
+			if usedConnected < numConnected && i+connectedLength <= len(timeSlots) {
+				str := utils.TimeSlotsToStr(timeSlots[i : i+connectedLength])
+				if _, ok := cm.Elements[key.sn][key.teacherID][key.venueID][str]; ok {
+					length = connectedLength
+				}
+			}
+
+			if err := cm.occupyElement(key.sn, key.teacherID, key.venueID, timeSlots[i:i+length], locked); err != nil {
+				return err
+			}
+			i += length
+		}
+
+		// 占用的普通课不能超过教学计划的课时
+		numClasses := models.GetNumClassesPerWeek(SN.GradeID, SN.ClassID, SN.SubjectID, cm.TeachingTasks)
+		_, usedNormal := cm.countUsed(key.sn)
+		if usedNormal > numClasses-numConnected*connectedLength {
+			return fmt.Errorf("occupied lessons exceed teaching task. sn: %s, normal count: %d", key.sn, usedNormal)
+		}
+	}
+
+	return nil
+}
+
+// 占用一个矩阵元素
+func (cm *ClassMatrix) occupyElement(sn string, teacherID, venueID int, timeSlots []int, locked bool) error {
+
+	str := utils.TimeSlotsToStr(timeSlots)
+	element, ok := cm.Elements[sn][teacherID][venueID][str]
+	if !ok {
+		return fmt.Errorf("occupied lesson not available. sn: %s, teacherID: %d, venueID: %d, timeSlots: %s", sn, teacherID, venueID, str)
+	}
+
+	if cm.isTimeSlotsUsed(element.GetClassKeys(), element.GetTeacherIDs(), element.TimeSlots) {
+		return fmt.Errorf("occupied lessons conflict. sn: %s, teacherID: %d, venueID: %d, timeSlots: %s", sn, teacherID, venueID, str)
+	}
+
+	element.Val.Used = 1
+	element.Locked = locked
+	return nil
+}
+
+// 统计课班已占用的连堂课, 普通课数量
+func (cm *ClassMatrix) countUsed(sn string) (int, int) {
+
+	connected, normal := 0, 0
+	for _, teacherMap := range cm.Elements[sn] {
+		for _, venueMap := range teacherMap {
+			for _, element := range venueMap {
+				if element.Val.Used == 0 {
+					continue
+				}
+
+				if element.IsConnected {
+					connected++
+				} else {
+					normal++
+				}
+			}
+		}
+	}
+	return connected, normal
+}
+
 func (cm *ClassMatrix) allocateClass(sn string, isConnected bool, rules []*Rule) error {
 
 	teacherID, venueID, timeSlotStr, score, err := cm.findBestTimeSlot(sn, isConnected)
@@ -329,18 +468,14 @@ func (cm *ClassMatrix) findBestTimeSlot(sn string, isConnected bool) (int, int,
 
 					// 检查当前元素的时间段,同年级同班级 是否有排课
 					// 或者相同教师,在该时间段 是否有排课
-					isUsed, err := cm.isTimeSlotsUsed(element.GradeID, element.ClassID, element.TeacherID, element.TimeSlots)
-					if err != nil {
-						return -1, -1, "", -1, err
-					}
-
+					isUsed := cm.isTimeSlotsUsed(element.GetClassKeys(), element.GetTeacherIDs(), element.TimeSlots)
 					if isUsed {
 						continue
 					}
 
 					// 连堂课,普通课判断
 					timeSlots := utils.ParseTimeSlotStr(timeSlotStrKey)
-					if (isConnected && len(timeSlots) != 2) || (!isConnected && len(timeSlots) != 1) {
+					if (isConnected && len(timeSlots) < 2) || (!isConnected && len(timeSlots) != 1) {
 						continue
 					}
 
@@ -366,32 +501,34 @@ func (cm *ClassMatrix) findBestTimeSlot(sn string, isConnected bool) (int, int,
 }
 
 // 辅助函数：检查时间段是否已被使用
-func (cm *ClassMatrix) isTimeSlotsUsed(gradeID int, classID int, teacherID int, timeSlots []int) (bool, error) {
-
-	for sn, classMap := range cm.Elements {
-
-		SN, err := ParseSN(sn)
-		if err != nil {
-			return false, err
-		}
+// classKeys 课程占用的所有班级, 包括教学班占用的行政班
+// teacherIDs 上课的所有教师, 包括协同上课的教师
+func (cm *ClassMatrix) isTimeSlotsUsed(classKeys []string, teacherIDs []int, timeSlots []int) bool {
 
-		for teacherIDKey, teacherMap := range classMap {
+	for _, classMap := range cm.Elements {
+		for _, teacherMap := range classMap {
 			for _, venueMap := range teacherMap {
 				for _, element := range venueMap {
 
-					if element.Val.Used == 1 && ((SN.GradeID == gradeID && SN.ClassID == classID) || teacherIDKey == teacherID) {
+					if element.Val.Used == 0 {
+						continue
+					}
+
+					isClassUsed := len(lo.Intersect(element.GetClassKeys(), classKeys)) > 0
+					isTeacherUsed := len(lo.Intersect(element.GetTeacherIDs(), teacherIDs)) > 0
+					if isClassUsed || isTeacherUsed {
 
 						intersect := lo.Intersect(element.TimeSlots, timeSlots)
 						isContain := len(intersect) > 0
 						if isContain {
-							return true, nil
+							return true
 						}
 					}
 				}
 			}
 		}
 	}
-	return false, nil
+	return false
 }
 
 // 计算固定约束条件得分
@@ -422,10 +559,12 @@ func (cm *ClassMatrix) calcElementScore(schedule *models.Schedule, teachingTasks
 		elementVal.ScoreInfo.FixedPassed = []string{}
 		elementVal.ScoreInfo.FixedFailed = []string{}
 		elementVal.ScoreInfo.FixedSkipped = []string{}
+		elementVal.ScoreInfo.FixedHardFailed = []string{}
 	} else {
 		elementVal.ScoreInfo.DynamicPassed = []string{}
 		elementVal.ScoreInfo.DynamicFailed = []string{}
 		elementVal.ScoreInfo.DynamicSkipped = []string{}
+		elementVal.ScoreInfo.DynamicHardFailed = []string{}
 	}
 
 	for _, rule := range rules {
@@ -446,6 +585,15 @@ func (cm *ClassMatrix) calcElementScore(schedule *models.Schedule, teachingTasks
 						} else {
 							elementVal.ScoreInfo.DynamicFailed = append(elementVal.ScoreInfo.DynamicFailed, rule.Name)
 						}
+
+						// 惩罚分为 math.MaxInt32 的约束是硬约束(如: 禁排)
+						if rule.Penalty == math.MaxInt32 {
+							if scoreType == "fixed" {
+								elementVal.ScoreInfo.FixedHardFailed = append(elementVal.ScoreInfo.FixedHardFailed, rule.Name)
+							} else {
+								elementVal.ScoreInfo.DynamicHardFailed = append(elementVal.ScoreInfo.DynamicHardFailed, rule.Name)
+							}
+						}
 					}
 				} else {
 
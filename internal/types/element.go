@@ -4,20 +4,23 @@ import "course_scheduler/internal/models"
 
 // 课班适应性矩阵中的一个元素
 type Element struct {
-	ClassSN     string // 科目_年级_班级
-	SubjectID   int    // 科目
-	GradeID     int    // 年级
-	ClassID     int    // 班级
-	TeacherID   int    // 教师
-	VenueID     int    // 教室
-	TimeSlots   []int  // 连堂课: 时间段1,时间段2, 普通课：时间段1
-	IsConnected bool   // 是否是连堂课
-	Val         Val    // 分数
+	ClassSN          string // 科目_年级_班级
+	SubjectID        int    // 科目
+	GradeID          int    // 年级
+	ClassID          int    // 班级
+	TeacherID        int    // 教师
+	CoTeacherIDs     []int  // 协同上课的教师
+	OccupiedClassIDs []int  // 教学班上课时会占用的行政班
+	VenueID          int    // 教室
+	TimeSlots        []int  // 连堂课: 时间段1,时间段2(三连堂: 时间段1,时间段2,时间段3), 普通课：时间段1
+	IsConnected      bool   // 是否是连堂课
+	Locked           bool   // 是否锁定, 部分重排时锁定的课不能移动
+	Val              Val    // 分数
 }
 
 func NewElement(classSN string, subjectID, gradeID, classID, teacherID, venueID int, timeSlots []int) *Element {
 
-	isConnected := len(timeSlots) == 2
+	isConnected := len(timeSlots) > 1
 	return &Element{
 		ClassSN:     classSN,
 		SubjectID:   subjectID,
@@ -52,6 +55,16 @@ func (e *Element) GetTeacherID() int {
 	return e.TeacherID
 }
 
+// 获取上课的所有教师, 包括协同上课的教师
+func (e *Element) GetTeacherIDs() []int {
+	return append([]int{e.TeacherID}, e.CoTeacherIDs...)
+}
+
+// 获取课程占用的班级key列表
+func (e *Element) GetClassKeys() []string {
+	return ClassKeys(e.GradeID, e.ClassID, e.OccupiedClassIDs)
+}
+
 func (e *Element) GetVenueID() int {
 	return e.VenueID
 }
@@ -79,6 +92,16 @@ func (e *Element) GetFailedConstraints() []string {
 
 }
 
+// 获取未满足的硬约束条件, 是未满足的约束条件的子集
+func (e *Element) GetHardFailedConstraints() []string {
+
+	fixedHardFailed := e.Val.ScoreInfo.FixedHardFailed
+	dynamicHardFailed := e.Val.ScoreInfo.DynamicHardFailed
+
+	hardFailedConstraints := append(append([]string{}, fixedHardFailed...), dynamicHardFailed...)
+	return hardFailedConstraints
+}
+
 func (e *Element) GetSkippedConstraints() []string {
 
 	fixedSkipped := e.Val.ScoreInfo.FixedSkipped
@@ -91,13 +114,12 @@ func (e *Element) GetSkippedConstraints() []string {
 // 当前元素排课信息的节次
 func GetElementPeriods(element Element, schedule *models.Schedule) []int {
 
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
 	currTimeSlots := element.TimeSlots
 
 	// 当前元素,课程所在的节次
 	var currPeriods []int
 	for _, currTimeSlot := range currTimeSlots {
-		currPeriod := currTimeSlot % totalClassesPerDay
+		currPeriod := schedule.GetTimeSlot(currTimeSlot).Period
 		currPeriods = append(currPeriods, currPeriod)
 	}
 
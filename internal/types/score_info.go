@@ -12,4 +12,7 @@ type ScoreInfo struct {
 	DynamicPassed  []string // 满足的动态约束条件
 	DynamicFailed  []string // 未满足的动态约束条件
 	DynamicSkipped []string // 跳过的动态约束条件
+
+	FixedHardFailed   []string // 未满足的固定硬约束条件(惩罚分为 math.MaxInt32)
+	DynamicHardFailed []string // 未满足的动态硬约束条件(惩罚分为 math.MaxInt32)
 }
@@ -45,3 +45,14 @@ func ParseSN(sn string) (*SN, error) {
 		ClassID:   classID,
 	}, nil
 }
+
+// 课程占用的班级key列表, key: 年级_班级
+// 教学班的课程, 除了教学班本身, 还会占用occupiedClassIDs中的行政班
+func ClassKeys(gradeID, classID int, occupiedClassIDs []int) []string {
+
+	keys := []string{fmt.Sprintf("%d_%d", gradeID, classID)}
+	for _, id := range occupiedClassIDs {
+		keys = append(keys, fmt.Sprintf("%d_%d", gradeID, id))
+	}
+	return keys
+}
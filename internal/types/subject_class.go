@@ -81,7 +81,9 @@ func getConnectedTimeSlots(schedule *models.Schedule, teachingTasks []*models.Te
 	connectedCount := models.GetNumConnectedClassesPerWeek(gradeID, classID, subjectID, teachingTasks)
 	if connectedCount > 0 {
 
-		timeSlotStrs = utils.GetAllConnectedTimeSlots(schedule)
+		// 每次连堂课的节数
+		connectedLength := models.GetConnectedLength(gradeID, classID, subjectID, teachingTasks)
+		timeSlotStrs = utils.GetAllConnectedTimeSlotsWithLength(schedule, connectedLength)
 	}
 	return timeSlotStrs
 }
@@ -103,8 +105,9 @@ func getNormalTimeSlots(schedule *models.Schedule, teachingTasks []*models.Teach
 
 	// 课班(科目班级)每周周连堂课次数
 	connectedCount := models.GetNumConnectedClassesPerWeek(gradeID, classID, subjectID, teachingTasks)
+	connectedLength := models.GetConnectedLength(gradeID, classID, subjectID, teachingTasks)
 
-	normalCount := total - connectedCount*2
+	normalCount := total - connectedCount*connectedLength
 	if normalCount > 0 {
 		timeSlotStrs = utils.GetAllNormalTimeSlots(schedule)
 	}
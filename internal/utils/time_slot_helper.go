@@ -38,8 +38,6 @@ func TimeSlotsToStr(timeSlot []int) string {
 // 从availableSlots获取一个可用的连堂课时间
 func GetConnectedTimeSlots(schedule *models.Schedule, availableSlots []int) (int, int) {
 
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
-
 	// 找出所有的连堂课时间段
 	pairs := make([][]int, 0)
 	for i := 0; i < len(availableSlots)-1; i++ {
@@ -48,23 +46,17 @@ func GetConnectedTimeSlots(schedule *models.Schedule, availableSlots []int) (int
 		}
 	}
 
-	// 上午的时间段
-	forenoonStartPeriod, forenoonEndPeriod := schedule.GetPeriodWithRange("forenoon")
-
-	// 下午的时间段
-	afternoonStartPeriod, afternoonEndPeriod := schedule.GetPeriodWithRange("afternoon")
+	// 设置连堂课的时间是上午和下午
+	segments := []string{"forenoon", "afternoon"}
 
 	// 遍历所有的连堂课时间段，找出一个可用的
+	// 两节课需要在同一天的同一个时间区间内, 不能跨越上午和下午, 早读和上午等时间区间
 	for _, pair := range pairs {
 
-		day1 := pair[0] / totalClassesPerDay
-		day2 := pair[1] / totalClassesPerDay
-
-		period0 := pair[0] % totalClassesPerDay
-		period1 := pair[1] % totalClassesPerDay
+		timeSlot0 := schedule.GetTimeSlot(pair[0])
+		timeSlot1 := schedule.GetTimeSlot(pair[1])
 
-		if day1 == day2 && (period0 >= forenoonStartPeriod && period1 <= forenoonEndPeriod) ||
-			(period0 >= afternoonStartPeriod && period1 <= afternoonEndPeriod) {
+		if timeSlot0.IsAdjacent(timeSlot1) && lo.Contains(segments, timeSlot0.Segment) {
 			return pair[0], pair[1]
 		}
 	}
@@ -80,7 +72,7 @@ func GetConnectedTimeSlotStrs(timeSlotStrs []string) []string {
 	for _, str := range timeSlotStrs {
 
 		parts := strings.Split(str, "_")
-		if len(parts) == 2 {
+		if len(parts) > 1 {
 			strs = append(strs, str)
 		}
 	}
@@ -89,28 +81,41 @@ func GetConnectedTimeSlotStrs(timeSlotStrs []string) []string {
 
 // 全部的连堂课时间段
 func GetAllConnectedTimeSlots(schedule *models.Schedule) []string {
+	return GetAllConnectedTimeSlotsWithLength(schedule, 2)
+}
+
+// 全部的指定节数的连堂课时间段
+// 如: 三连堂 length为3, 返回 "t_t+1_t+2"
+// 连堂课的每节课都需要在同一天的同一个时间区间内
+func GetAllConnectedTimeSlotsWithLength(schedule *models.Schedule, length int) []string {
 
 	var timeSlotStrs []string
 
 	timeSlots := schedule.GenWeekTimeSlots()
-	totalClassesPerDay := schedule.GetTotalClassesPerDay()
 
 	// 设置连堂课的时间是上午和下午
-	amStart, amEnd := schedule.GetPeriodWithRange("forenoon")
-	pmStart, pmEnd := schedule.GetPeriodWithRange("afternoon")
+	segments := []string{"forenoon", "afternoon"}
 
 	for _, timeSlot := range timeSlots {
 
-		if !lo.Contains(timeSlots, timeSlot+1) {
+		if !lo.Contains(segments, schedule.GetTimeSlot(timeSlot).Segment) {
 			continue
 		}
 
-		day1, day2 := timeSlot/totalClassesPerDay, (timeSlot+1)/totalClassesPerDay
-		period1, period2 := timeSlot%totalClassesPerDay, (timeSlot+1)%totalClassesPerDay
+		block := []int{timeSlot}
+		for len(block) < length {
+
+			next := block[len(block)-1] + 1
 
-		if day1 == day2 && ((period1 >= amStart && period1 <= amEnd && period2 >= amStart && period2 <= amEnd) ||
-			(period1 >= pmStart && period1 <= pmEnd && period2 >= pmStart && period2 <= pmEnd)) {
-			timeSlotStrs = append(timeSlotStrs, fmt.Sprintf("%d_%d", timeSlot, timeSlot+1))
+			// 不存在的时间段不能排连堂课
+			if !lo.Contains(timeSlots, next) || !schedule.GetTimeSlot(next-1).IsAdjacent(schedule.GetTimeSlot(next)) {
+				break
+			}
+			block = append(block, next)
+		}
+
+		if len(block) == length {
+			timeSlotStrs = append(timeSlotStrs, TimeSlotsToStr(block))
 		}
 	}
 
@@ -127,3 +132,15 @@ func GetAllNormalTimeSlots(schedule *models.Schedule) []string {
 	}
 	return timeSlotStrs
 }
+
+// 比较两个时间段列表的先后顺序
+// 依次比较每个时间段, 前面的时间段相同时, 节数少的排在前面
+func LessTimeSlots(ts1, ts2 []int) bool {
+
+	for k := 0; k < len(ts1) && k < len(ts2); k++ {
+		if ts1[k] != ts2[k] {
+			return ts1[k] < ts2[k]
+		}
+	}
+	return len(ts1) < len(ts2)
+}
@@ -0,0 +1,289 @@
+// callback.go
+package worker
+
+import (
+	"bytes"
+	"course_scheduler/config"
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/storage"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// 回调请求中的签名头
+// 值为 sha256=<十六进制的HMAC-SHA256>, 使用任务的回调密钥对 "<时间戳>.<请求体>" 签名, 没有设置密钥时不发送
+const SignatureHeader = "X-Signature-256"
+
+// 回调请求中的时间戳头, 值为发送时的Unix时间(秒), 包含在签名中
+// 接收方拒绝时间戳和当前时间相差超过5分钟的请求, 防止截获的回调被重放
+const TimestampHeader = "X-Signature-Timestamp"
+
+// 回调地址不能是回环, 内网, 链路本地(包括云服务器的元数据地址 169.254.169.254), 未指定和组播地址
+var ErrForbiddenCallbackHost = errors.New("callback host resolves to a private or reserved address")
+
+// 运营商级NAT和本网络地址, net.IP 没有对应的判断方法
+var reservedNetworks = []*net.IPNet{
+	{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)},
+	{IP: net.IPv4(0, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+}
+
+// 排课任务完成回调的请求体
+type CallbackPayload struct {
+	TaskID       string           `json:"task_id"`
+	Status       string           `json:"status"`
+	Progress     int8             `json:"progress"`
+	Attempts     int              `json:"attempts"`
+	FinishedAt   *time.Time       `json:"finished_at"`
+	ErrorMessage string           `json:"error_message,omitempty"` // 失败时的错误信息
+	Summary      *CallbackSummary `json:"summary,omitempty"`       // 成功时的排课结果汇总
+}
+
+// 排课结果汇总
+type CallbackSummary struct {
+	NumResults        int    `json:"num_results"`         // 排课结果数量
+	Fitness           int    `json:"fitness"`             // 适应度
+	NumHardViolations int    `json:"num_hard_violations"` // 未满足的硬约束条件数量
+	NumSoftViolations int    `json:"num_soft_violations"` // 未满足的软约束条件数量
+	TerminationReason string `json:"termination_reason"`  // 终止原因
+}
+
+// 排课任务完成回调的发送程序
+// 回调失败时按照指数退避重试, 每次发送都写入回调记录
+type CallbackSender struct {
+	store       storage.Storage
+	client      *http.Client
+	backoff     time.Duration // 第一次重试的间隔, 之后每次翻倍
+	maxBackoff  time.Duration // 重试的最大间隔
+	maxAttempts int           // 最多回调次数
+}
+
+func NewCallbackSender(store storage.Storage, backoff, maxBackoff time.Duration, maxAttempts int) *CallbackSender {
+	return &CallbackSender{
+		store:       store,
+		client:      newCallbackClient(false),
+		backoff:     backoff,
+		maxBackoff:  maxBackoff,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// 创建回调的HTTP客户端
+// 连接时检查解析后的IP地址, 防止创建任务后域名被解析到内网地址; 不跟随重定向, 3xx按照回调失败处理
+func newCallbackClient(allowPrivate bool) *http.Client {
+
+	dialer := &net.Dialer{Timeout: config.CallbackTimeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isForbiddenIP(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenCallbackHost, host)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   config.CallbackTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// 检查回调地址, 主机是IP地址时不能是内网或者保留地址
+// 不解析域名, 避免新增任务时等待DNS; 域名在发送回调时由拨号检查连接的IP地址
+func CheckCallbackURL(rawURL string) error {
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(u.Hostname())
+	if ip != nil && isForbiddenIP(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenCallbackHost, ip)
+	}
+	return nil
+}
+
+// 判断是否是不允许回调的IP地址
+func isForbiddenIP(ip net.IP) bool {
+
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+		return true
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// 领取并发送到期的回调, 返回发送的回调数量
+func (s *CallbackSender) DeliverDue(limit int) int {
+
+	// 领取后在请求超时之前不会被其他程序再次领取
+	tasks, err := s.store.Callbacks().ClaimDue(time.Now(), 2*config.CallbackTimeout, limit)
+	if err != nil {
+		log.Printf("claim due callbacks failed. %s\n", err)
+	}
+
+	for _, task := range tasks {
+		if err := s.deliver(task); err != nil {
+			log.Printf("task %d callback failed. %s\n", task.TaskID, err)
+		}
+	}
+	return len(tasks)
+}
+
+// 发送一次回调并写入回调记录, 返回回调失败的原因
+func (s *CallbackSender) deliver(task *models.Task) error {
+
+	delivery := &models.CallbackDelivery{
+		TaskID:     task.TaskID,
+		Attempt:    task.CallbackAttempts + 1,
+		URL:        task.CallbackURL,
+		TaskStatus: task.Status,
+	}
+
+	start := time.Now()
+	statusCode, err := s.post(task)
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	delivery.StatusCode = statusCode
+	delivery.Success = err == nil
+	if err != nil {
+		delivery.ErrorMsg = err.Error()
+	}
+
+	// 失败时计算下次回调时间, 达到最多回调次数后不再重试
+	var next *time.Time
+	if err != nil && delivery.Attempt < s.maxAttempts {
+		nextAt := time.Now().Add(s.nextBackoff(delivery.Attempt))
+		next = &nextAt
+	}
+
+	if recordErr := s.store.Callbacks().Record(delivery, next); recordErr != nil {
+		return fmt.Errorf("record callback delivery failed. %s", recordErr)
+	}
+	return err
+}
+
+// 第attempt次回调失败后的重试间隔
+func (s *CallbackSender) nextBackoff(attempt int) time.Duration {
+
+	backoff := s.backoff
+	for i := 1; i < attempt && backoff < s.maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, s.maxBackoff)
+}
+
+// 发送回调请求, 返回HTTP状态码, 状态码不是2xx时返回错误
+func (s *CallbackSender) post(task *models.Task) (int, error) {
+
+	payload, err := s.payload(task)
+	if err != nil {
+		return 0, err
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("marshal callback payload failed. %s", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, task.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("create callback request failed. %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "course-scheduler-callback")
+	if task.CallbackSecret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, Sign(task.CallbackSecret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// 生成回调的请求体, 成功时包含排课结果汇总, 失败时包含错误信息
+func (s *CallbackSender) payload(task *models.Task) (*CallbackPayload, error) {
+
+	payload := &CallbackPayload{
+		TaskID:     strconv.FormatUint(task.TaskID, 10),
+		Status:     task.Status,
+		Progress:   task.Progress,
+		Attempts:   task.Attempts,
+		FinishedAt: task.FinishedAt,
+	}
+
+	switch task.Status {
+	case models.TaskStatusSuccess:
+		numResults, err := s.store.Results().CountByTask(task.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		payload.Summary = &CallbackSummary{NumResults: int(numResults)}
+
+		report, err := s.store.Reports().GetByTask(task.TaskID)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+		if report != nil {
+			payload.Summary.Fitness = report.Fitness
+			payload.Summary.NumHardViolations = report.NumHardViolations
+			payload.Summary.NumSoftViolations = report.NumSoftViolations
+			payload.Summary.TerminationReason = report.TerminationReason
+		}
+
+	case models.TaskStatusFailed:
+		errorLogs, err := s.store.ErrorLogs().ListByTask(task.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		if len(errorLogs) > 0 {
+			payload.ErrorMessage = errorLogs[len(errorLogs)-1].ErrorMsg
+		}
+	}
+	return payload, nil
+}
+
+// 使用密钥对 "<时间戳>.<请求体>" 签名, 返回 sha256=<十六进制的HMAC-SHA256>
+// 接收方使用相同的密钥和请求头中的时间戳计算签名, 并使用 hmac.Equal 比较
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,294 @@
+// worker.go
+package worker
+
+import (
+	"context"
+	"course_scheduler/config"
+	"course_scheduler/internal/api/v1/middlewares"
+	"course_scheduler/internal/genetic_algorithm"
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// 任务已经不是执行中(如: 已经取消), 停止执行
+var ErrTaskStopped = errors.New("task is no longer running")
+
+// 执行程序正在停止, 执行中的任务改回等待执行
+var errWorkerStopped = errors.New("worker is stopping")
+
+// 排课任务执行程序
+// 定时从task表中扫描等待执行的任务, 同时最多执行concurrency个任务
+// 1. 领取任务时使用条件更新, 多个程序同时运行时每个任务只会被执行一次
+// 2. 执行中的任务超过staleTimeout没有更新, 视为程序已经崩溃, 改回等待执行重新排课
+// 3. 停止时不再领取新的任务, 执行中的任务在下一代遗传结束时停止并改回等待执行, 之后重新排课
+// 4. 同时发送任务结束的回调, 失败时按照指数退避重试
+type Worker struct {
+	store        storage.Storage
+	concurrency  int           // 同时执行的任务数量
+	interval     time.Duration // 扫描等待执行的任务的间隔
+	staleTimeout time.Duration // 执行中的任务超过这个时间没有更新, 改回等待执行
+	callbacks    *CallbackSender
+
+	mu       sync.Mutex
+	running  int // 当前执行中的任务数量
+	wg       sync.WaitGroup
+	finished chan struct{} // 任务执行完成的通知, 用于立即领取下一个任务
+}
+
+// 创建排课任务执行程序
+func NewWorker(store storage.Storage, concurrency int, interval, staleTimeout time.Duration) *Worker {
+	return &Worker{
+		store:        store,
+		concurrency:  concurrency,
+		interval:     interval,
+		staleTimeout: staleTimeout,
+		callbacks:    NewCallbackSender(store, config.CallbackInitialBackoff, config.CallbackMaxBackoff, config.CallbackMaxAttempts),
+		finished:     make(chan struct{}, 1),
+	}
+}
+
+// 运行排课任务执行程序, ctx 取消后等待执行中的任务停止再返回
+func (w *Worker) Run(ctx context.Context) {
+
+	log.Printf("worker started, concurrency: %d, interval: %v\n", w.concurrency, w.interval)
+
+	// 回调请求可能比较慢, 单独发送, 不影响领取任务
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.runCallbacks(ctx)
+	}()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	// 多个case同时就绪时select随机选择, ctx取消后可能继续领取任务, 所以每次领取前先检查ctx
+	for ctx.Err() == nil {
+		w.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		case <-w.finished:
+		}
+	}
+
+	log.Println("worker stopping, releasing running tasks")
+	w.wg.Wait()
+	log.Println("worker stopped")
+}
+
+// 恢复崩溃的任务, 并领取等待执行的任务
+func (w *Worker) poll(ctx context.Context) {
+
+	recovered, err := w.store.Tasks().RecoverStale(time.Now().Add(-w.staleTimeout))
+	if err != nil {
+		log.Printf("recover stale tasks failed. %s\n", err)
+	} else if recovered > 0 {
+		log.Printf("recovered %d stale tasks\n", recovered)
+	}
+
+	w.mu.Lock()
+	available := w.concurrency - w.running
+	w.mu.Unlock()
+
+	tasks, err := w.store.Tasks().ClaimPending(available)
+	if err != nil {
+		log.Printf("claim pending tasks failed. %s\n", err)
+	}
+
+	for _, task := range tasks {
+		w.mu.Lock()
+		w.running++
+		w.mu.Unlock()
+
+		w.wg.Add(1)
+		go func(task *models.Task) {
+			defer w.wg.Done()
+			defer w.done()
+			w.execute(ctx, task)
+		}(task)
+	}
+}
+
+// 执行领取的排课任务
+// 排课时panic的任务改为失败并记录调用栈, 不影响其他任务和同一个进程中的API服务
+func (w *Worker) execute(ctx context.Context, task *models.Task) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("task %d panic. %v\n%s", task.TaskID, r, debug.Stack())
+			if err := w.store.Tasks().Fail(task.TaskID, fmt.Sprintf("task panic. %v", r)); err != nil {
+				log.Printf("task %d save error log failed. %s\n", task.TaskID, err)
+			}
+		}
+	}()
+
+	log.Printf("task %d started\n", task.TaskID)
+	err := RunTask(ctx, w.store, task)
+	if errors.Is(err, ErrTaskStopped) {
+		log.Printf("task %d stopped, status: %s\n", task.TaskID, task.Status)
+		return
+	}
+	if err != nil {
+		log.Printf("task %d failed. %s\n", task.TaskID, err)
+		return
+	}
+	log.Printf("task %d done\n", task.TaskID)
+}
+
+// 定时发送到期的回调, 直到ctx结束
+func (w *Worker) runCallbacks(ctx context.Context) {
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		// 一次没有发送完时立即继续发送
+		for ctx.Err() == nil {
+			if w.callbacks.DeliverDue(config.CallbackBatchSize) < config.CallbackBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// 任务执行完成
+func (w *Worker) done() {
+
+	w.mu.Lock()
+	w.running--
+	w.mu.Unlock()
+
+	select {
+	case w.finished <- struct{}{}:
+	default:
+	}
+}
+
+// 执行已经领取的排课任务, 更新任务进度并保存每一代的监控数据, 保存排课结果或者错误日志
+// 执行中任务被取消时, 在下一代遗传结束时停止, 返回ErrTaskStopped
+// ctx 取消时(执行程序停止), 在下一代遗传结束时停止并将任务改回等待执行, 返回ErrTaskStopped
+// 与已发布课表相比的变化汇总保存在排课质量报告中
+func RunTask(ctx context.Context, store storage.Storage, task *models.Task) error {
+
+	// 删除之前执行时的遗传代数据, 进度事件从第一代重新开始
+	if err := store.Generations().DeleteByTask(task.TaskID); err != nil {
+		log.Printf("task %d delete generations failed. %s\n", task.TaskID, err)
+	}
+
+	lastProgress := int8(-1)
+	lastCheck := time.Now()
+	onGeneration := func(generation *models.TaskGeneration) error {
+
+		if ctx.Err() != nil {
+			return errWorkerStopped
+		}
+
+		if generation.Progress != lastProgress {
+			lastProgress = generation.Progress
+			if err := store.Tasks().UpdateProgress(task.TaskID, generation.Progress); err != nil {
+				log.Printf("task %d update progress failed. %s\n", task.TaskID, err)
+			}
+		}
+
+		if err := store.Generations().Create(generation); err != nil {
+			log.Printf("task %d save generation %d failed. %s\n", task.TaskID, generation.Generation, err)
+		}
+
+		// 检查任务是否已经取消
+		if time.Since(lastCheck) < config.TaskStatusCheckInterval {
+			return nil
+		}
+		lastCheck = time.Now()
+		return checkRunning(store, task)
+	}
+
+	scheduleResults, report, err := middlewares.ExecuteTask(task.TaskID, task.TaskData, onGeneration)
+	if errors.Is(err, ErrTaskStopped) {
+		return ErrTaskStopped
+	}
+	if errors.Is(err, errWorkerStopped) {
+		if err := store.Tasks().Release(task.TaskID); err != nil {
+			// 停止前任务已经取消
+			checkRunning(store, task)
+			return ErrTaskStopped
+		}
+		task.Status = models.TaskStatusPending
+		return ErrTaskStopped
+	}
+
+	var scheduleReport *models.ScheduleReport
+	if err == nil {
+		scheduleReport, err = newScheduleReport(report)
+	}
+
+	if err == nil {
+		err = store.Tasks().Complete(task.TaskID, scheduleResults, scheduleReport)
+		if errors.Is(err, storage.ErrTaskStatus) {
+			// 排课完成前任务已经取消
+			checkRunning(store, task)
+			return ErrTaskStopped
+		}
+		if err == nil {
+			task.Status = models.TaskStatusSuccess
+			task.Progress = 100
+			return nil
+		}
+	}
+
+	if err := store.Tasks().Fail(task.TaskID, err.Error()); err != nil {
+		log.Printf("task %d save error log failed. %s\n", task.TaskID, err)
+		checkRunning(store, task)
+	} else {
+		task.Status = models.TaskStatusFailed
+	}
+	return err
+}
+
+// 将排课质量报告转换为存储的格式
+func newScheduleReport(report *genetic_algorithm.ScheduleReport) (*models.ScheduleReport, error) {
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("marshal schedule report failed. %s", err)
+	}
+
+	return &models.ScheduleReport{
+		Fitness:           report.Fitness,
+		NumHardViolations: report.NumHardViolations,
+		NumSoftViolations: report.NumSoftViolations,
+		TerminationReason: report.TerminationReason,
+		Report:            string(data),
+	}, nil
+}
+
+// 检查任务是否还在执行中, 并更新任务状态
+// 任务已经取消, 或者被其他程序改回等待执行时返回ErrTaskStopped
+func checkRunning(store storage.Storage, task *models.Task) error {
+
+	current, err := store.Tasks().Get(task.TaskID)
+	if err != nil {
+		log.Printf("task %d get status failed. %s\n", task.TaskID, err)
+		return nil
+	}
+
+	task.Status = current.Status
+	if current.Status != models.TaskStatusRunning {
+		return ErrTaskStopped
+	}
+	return nil
+}
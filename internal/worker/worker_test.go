@@ -0,0 +1,620 @@
+package worker_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/storage"
+	"course_scheduler/internal/worker"
+)
+
+// 测试用的排课数据: 1个班级, 2个科目, 2个教师, 每个科目每周5节课
+const taskData = `{
+	"schedule": {"name": "test", "num_workdays": 5, "num_days_off": 2, "num_forenoon_classes": 4},
+	"subjects": [{"subject_id": 1, "name": "语文", "subject_group_ids": [1], "priority": 1}, {"subject_id": 2, "name": "数学", "subject_group_ids": [1], "priority": 2}],
+	"teachers": [
+		{"teacher_id": 1, "name": "语文1", "teacher_group_ids": [], "class_subjects": [{"grade_id": 1, "class_id": 1, "subject_id": [1]}]},
+		{"teacher_id": 2, "name": "数学1", "teacher_group_ids": [], "class_subjects": [{"grade_id": 1, "class_id": 1, "subject_id": [2]}]}
+	],
+	"teaching_tasks": [
+		{"id": 1, "grade_id": 1, "class_id": 1, "subject_id": 1, "teacher_id": 1, "num_classes_per_week": 5},
+		{"id": 2, "grade_id": 1, "class_id": 1, "subject_id": 2, "teacher_id": 2, "num_classes_per_week": 5}
+	],
+	"grades": [{"school_id": 1, "grade_id": 1, "name": "一年级", "classes": [{"school_id": 1, "class_id": 1, "name": "1班"}]}],
+	"subject_venue_map": {"1_1_1": [101], "2_1_1": [101]}
+}`
+
+// 排课时会在上级目录创建日志文件, 切换到临时目录, 避免在代码目录中写入日志
+func TestMain(m *testing.M) {
+
+	dir, err := os.MkdirTemp("", "worker_test")
+	if err != nil {
+		panic(err)
+	}
+
+	workDir := filepath.Join(dir, "work")
+	if err := os.MkdirAll(workDir, os.ModePerm); err != nil {
+		panic(err)
+	}
+
+	if err := os.Chdir(workDir); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func newTestStorage(t *testing.T) storage.Storage {
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open storage failed. %s", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func createTask(t *testing.T, store storage.Storage, data string) *models.Task {
+	task := &models.Task{TaskData: data, Status: models.TaskStatusPending}
+	if err := store.Tasks().Create(task); err != nil {
+		t.Fatalf("create task failed. %s", err)
+	}
+	return task
+}
+
+// 等待任务执行完成
+func waitTask(t *testing.T, store storage.Storage, taskID uint64) *models.Task {
+
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		task, err := store.Tasks().Get(taskID)
+		if err != nil {
+			t.Fatalf("get task failed. %s", err)
+		}
+		if task.Status == models.TaskStatusSuccess || task.Status == models.TaskStatusFailed {
+			return task
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("task %d not finished", taskID)
+	return nil
+}
+
+func TestWorkerDrainsPendingTasks(t *testing.T) {
+
+	store := newTestStorage(t)
+	var taskIDs []uint64
+	for i := 0; i < 3; i++ {
+		taskIDs = append(taskIDs, createTask(t, store, taskData).TaskID)
+	}
+	invalid := createTask(t, store, `{"schedule": {"name": "test", "num_workdays": 5, "num_days_off": 2, "num_forenoon_classes": 4}}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		worker.NewWorker(store, 2, 10*time.Millisecond, time.Hour).Run(ctx)
+		close(stopped)
+	}()
+
+	for _, taskID := range taskIDs {
+		task := waitTask(t, store, taskID)
+		if task.Status != models.TaskStatusSuccess || task.Progress != 100 {
+			t.Errorf("task %d: status %s, progress %d", taskID, task.Status, task.Progress)
+		}
+
+		results, err := store.Results().ListByTask(taskID)
+		if err != nil {
+			t.Fatalf("list results failed. %s", err)
+		}
+		if len(results) != 10 {
+			t.Errorf("task %d: got %d results, want 10", taskID, len(results))
+		}
+	}
+
+	task := waitTask(t, store, invalid.TaskID)
+	if task.Status != models.TaskStatusFailed {
+		t.Errorf("invalid task: status %s, want %s", task.Status, models.TaskStatusFailed)
+	}
+
+	errorLogs, err := store.ErrorLogs().ListByTask(invalid.TaskID)
+	if err != nil {
+		t.Fatalf("list error logs failed. %s", err)
+	}
+	if len(errorLogs) != 1 {
+		t.Errorf("invalid task: got %d error logs, want 1", len(errorLogs))
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(10 * time.Second):
+		t.Fatal("worker not stopped")
+	}
+}
+
+func TestClaimAndRecoverStale(t *testing.T) {
+
+	store := newTestStorage(t)
+	task := createTask(t, store, taskData)
+
+	if err := store.Tasks().Claim(task.TaskID); err != nil {
+		t.Fatalf("claim failed. %s", err)
+	}
+
+	// 已经领取的任务不能再次领取
+	if err := store.Tasks().Claim(task.TaskID); err != storage.ErrTaskNotPending {
+		t.Fatalf("claim again: got %v, want %v", err, storage.ErrTaskNotPending)
+	}
+	if tasks, err := store.Tasks().ClaimPending(10); err != nil || len(tasks) != 0 {
+		t.Fatalf("claim pending: got %d tasks, err %v", len(tasks), err)
+	}
+
+	// 最近更新的任务不会被恢复
+	if recovered, err := store.Tasks().RecoverStale(time.Now().Add(-time.Hour)); err != nil || recovered != 0 {
+		t.Fatalf("recover recent: got %d, err %v", recovered, err)
+	}
+
+	// 程序崩溃后, 执行中的任务改回等待执行
+	if recovered, err := store.Tasks().RecoverStale(time.Now().Add(time.Second)); err != nil || recovered != 1 {
+		t.Fatalf("recover stale: got %d, err %v", recovered, err)
+	}
+
+	tasks, err := store.Tasks().ClaimPending(10)
+	if err != nil || len(tasks) != 1 || tasks[0].TaskID != task.TaskID {
+		t.Fatalf("claim recovered: got %d tasks, err %v", len(tasks), err)
+	}
+}
+
+// 学校执行中的任务达到限制后, 不能再领取这个学校的任务, 其他学校的任务不受影响
+func TestClaimTenantLimit(t *testing.T) {
+
+	store := newTestStorage(t)
+	if err := store.Tenants().Save(&models.Tenant{SchoolID: 1, MaxRunningTasks: 2}); err != nil {
+		t.Fatalf("save tenant failed. %s", err)
+	}
+
+	var tasks []*models.Task
+	for _, schoolID := range []uint64{1, 1, 1, 2, 2} {
+		task := &models.Task{SchoolID: schoolID, TaskData: taskData, Status: models.TaskStatusPending}
+		if err := store.Tasks().Create(task); err != nil {
+			t.Fatalf("create task failed. %s", err)
+		}
+		tasks = append(tasks, task)
+	}
+
+	// 学校1最多2个, 学校2没有设置, 使用默认的1个
+	claimed, err := store.Tasks().ClaimPending(10)
+	if err != nil {
+		t.Fatalf("claim pending failed. %s", err)
+	}
+	var claimedIDs []uint64
+	for _, task := range claimed {
+		claimedIDs = append(claimedIDs, task.TaskID)
+	}
+	want := []uint64{tasks[0].TaskID, tasks[1].TaskID, tasks[3].TaskID}
+	if len(claimedIDs) != len(want) {
+		t.Fatalf("claimed %v, want %v", claimedIDs, want)
+	}
+	for i := range want {
+		if claimedIDs[i] != want[i] {
+			t.Fatalf("claimed %v, want %v", claimedIDs, want)
+		}
+	}
+
+	if err := store.Tasks().Claim(tasks[2].TaskID); err != storage.ErrTenantBusy {
+		t.Fatalf("claim busy school: %v, want %v", err, storage.ErrTenantBusy)
+	}
+
+	// 执行中的任务结束后可以领取
+	if err := store.Tasks().Complete(tasks[0].TaskID, nil, nil); err != nil {
+		t.Fatalf("complete task failed. %s", err)
+	}
+	if err := store.Tasks().Claim(tasks[2].TaskID); err != nil {
+		t.Fatalf("claim after complete failed. %s", err)
+	}
+}
+
+// 多个程序同时领取同一个学校的任务, 执行中的任务数量不超过限制
+func TestConcurrentClaimTenantLimit(t *testing.T) {
+
+	store := newTestStorage(t)
+	if err := store.Tenants().Save(&models.Tenant{SchoolID: 1, MaxRunningTasks: 2}); err != nil {
+		t.Fatalf("save tenant failed. %s", err)
+	}
+
+	var tasks []*models.Task
+	for i := 0; i < 10; i++ {
+		task := &models.Task{SchoolID: 1, TaskData: taskData, Status: models.TaskStatusPending}
+		if err := store.Tasks().Create(task); err != nil {
+			t.Fatalf("create task failed. %s", err)
+		}
+		tasks = append(tasks, task)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(tasks))
+	for _, task := range tasks {
+		wg.Add(1)
+		go func(taskID uint64) {
+			defer wg.Done()
+			errs <- store.Tasks().Claim(taskID)
+		}(task.TaskID)
+	}
+	wg.Wait()
+	close(errs)
+
+	claimed := 0
+	for err := range errs {
+		switch err {
+		case nil:
+			claimed++
+		case storage.ErrTenantBusy:
+		default:
+			t.Errorf("claim failed. %s", err)
+		}
+	}
+	if claimed != 2 {
+		t.Errorf("claimed %d tasks, want 2", claimed)
+	}
+
+	_, running, err := store.Tasks().List(&storage.TaskFilter{SchoolID: 1, Status: models.TaskStatusRunning})
+	if err != nil {
+		t.Fatalf("list running tasks failed. %s", err)
+	}
+	if running != 2 {
+		t.Errorf("running %d tasks, want 2", running)
+	}
+}
+
+// 停止后不再领取新的任务
+func TestWorkerStoppedClaimsNothing(t *testing.T) {
+
+	store := newTestStorage(t)
+	task := createTask(t, store, taskData)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	worker.NewWorker(store, 1, 10*time.Millisecond, time.Hour).Run(ctx)
+
+	current, err := store.Tasks().Get(task.TaskID)
+	if err != nil {
+		t.Fatalf("get task failed. %s", err)
+	}
+	if current.Status != models.TaskStatusPending || current.Attempts != 0 {
+		t.Errorf("task claimed after stop: status %s, attempts %d", current.Status, current.Attempts)
+	}
+}
+
+// 保存遗传代数据时panic的存储
+type panicStorage struct {
+	storage.Storage
+}
+
+func (s *panicStorage) Generations() storage.TaskGenerationRepository {
+	panic("generations unavailable")
+}
+
+// 排课时panic的任务改为失败, 执行程序继续执行其他任务
+func TestWorkerRecoversPanic(t *testing.T) {
+
+	store := newTestStorage(t)
+	task := createTask(t, store, taskData)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		worker.NewWorker(&panicStorage{store}, 1, 10*time.Millisecond, time.Hour).Run(ctx)
+		close(stopped)
+	}()
+
+	current := waitTask(t, store, task.TaskID)
+	if current.Status != models.TaskStatusFailed {
+		t.Errorf("status %s, want %s", current.Status, models.TaskStatusFailed)
+	}
+
+	errorLogs, err := store.ErrorLogs().ListByTask(task.TaskID)
+	if err != nil || len(errorLogs) != 1 || !strings.Contains(errorLogs[0].ErrorMsg, "generations unavailable") {
+		t.Errorf("error logs: %v, err %v", errorLogs, err)
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(10 * time.Second):
+		t.Fatal("worker not stopped")
+	}
+}
+
+// 执行程序停止时, 执行中的任务改回等待执行
+func TestRunTaskWorkerStopped(t *testing.T) {
+
+	store := newTestStorage(t)
+	task := createTask(t, store, taskData)
+
+	if err := store.Tasks().Claim(task.TaskID); err != nil {
+		t.Fatalf("claim failed. %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := worker.RunTask(ctx, store, task); err != worker.ErrTaskStopped {
+		t.Fatalf("run task: got %v, want %v", err, worker.ErrTaskStopped)
+	}
+
+	current, err := store.Tasks().Get(task.TaskID)
+	if err != nil || current.Status != models.TaskStatusPending || task.Status != models.TaskStatusPending {
+		t.Fatalf("released task: status %s, err %v", current.Status, err)
+	}
+
+	results, err := store.Results().ListByTask(task.TaskID)
+	if err != nil || len(results) != 0 {
+		t.Errorf("released task: got %d results, err %v", len(results), err)
+	}
+
+	// 改回等待执行的任务可以再次领取
+	tasks, err := store.Tasks().ClaimPending(1)
+	if err != nil || len(tasks) != 1 || tasks[0].TaskID != task.TaskID {
+		t.Errorf("claim released: got %v, err %v", tasks, err)
+	}
+}
+
+func TestRunCancelledTask(t *testing.T) {
+
+	store := newTestStorage(t)
+	task := createTask(t, store, taskData)
+
+	if err := store.Tasks().Claim(task.TaskID); err != nil {
+		t.Fatalf("claim failed. %s", err)
+	}
+
+	// 执行中的任务被取消, 不写入排课结果
+	if err := store.Tasks().Cancel(task.TaskID); err != nil {
+		t.Fatalf("cancel failed. %s", err)
+	}
+
+	if err := worker.RunTask(context.Background(), store, task); err != worker.ErrTaskStopped {
+		t.Fatalf("run cancelled task: got %v, want %v", err, worker.ErrTaskStopped)
+	}
+	if task.Status != models.TaskStatusCancelled {
+		t.Errorf("run cancelled task: status %s, want %s", task.Status, models.TaskStatusCancelled)
+	}
+
+	results, err := store.Results().ListByTask(task.TaskID)
+	if err != nil || len(results) != 0 {
+		t.Errorf("run cancelled task: got %d results, err %v", len(results), err)
+	}
+
+	// 取消的任务可以重新执行, 执行次数增加
+	if err := store.Tasks().Retry(task.TaskID); err != nil {
+		t.Fatalf("retry failed. %s", err)
+	}
+	tasks, err := store.Tasks().ClaimPending(1)
+	if err != nil || len(tasks) != 1 || tasks[0].Attempts != 2 || tasks[0].StartedAt == nil {
+		t.Fatalf("claim retried: got %v, err %v", tasks, err)
+	}
+}
+
+func TestCallbackDelivery(t *testing.T) {
+
+	store := newTestStorage(t)
+
+	// 第一次回调返回 500, 之后返回 200
+	var payloads []*worker.CallbackPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, err := strconv.ParseInt(r.Header.Get(worker.TimestampHeader), 10, 64)
+		if err != nil || time.Since(time.Unix(timestamp, 0)) > time.Minute {
+			t.Errorf("callback: invalid timestamp %q", r.Header.Get(worker.TimestampHeader))
+		}
+		if signature := r.Header.Get(worker.SignatureHeader); !hmac.Equal([]byte(signature), []byte(worker.Sign("secret", r.Header.Get(worker.TimestampHeader), body))) {
+			t.Errorf("callback: invalid signature %q", signature)
+		}
+
+		payload := &worker.CallbackPayload{}
+		if err := json.Unmarshal(body, payload); err != nil {
+			t.Errorf("callback: %s", err)
+		}
+		payloads = append(payloads, payload)
+		if len(payloads) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	task := &models.Task{TaskData: taskData, Status: models.TaskStatusPending, CallbackURL: server.URL, CallbackSecret: "secret"}
+	if err := store.Tasks().Create(task); err != nil {
+		t.Fatalf("create task failed. %s", err)
+	}
+
+	if err := store.Tasks().Claim(task.TaskID); err != nil {
+		t.Fatalf("claim failed. %s", err)
+	}
+
+	// 没有设置回调地址的任务和执行中的任务不回调
+	other := createTask(t, store, taskData)
+	if err := store.Tasks().Cancel(other.TaskID); err != nil {
+		t.Fatalf("cancel failed. %s", err)
+	}
+
+	sender := worker.NewCallbackSender(store, 0, 0, 3)
+	sender.AllowPrivateHosts()
+	if sent := sender.DeliverDue(10); sent != 0 {
+		t.Fatalf("running task: sent %d callbacks", sent)
+	}
+
+	if err := store.Tasks().Fail(task.TaskID, "genetic execute failed"); err != nil {
+		t.Fatalf("fail failed. %s", err)
+	}
+
+	// 第一次失败后重试
+	for i := 0; i < 3; i++ {
+		sender.DeliverDue(10)
+	}
+	if len(payloads) != 2 || payloads[1].Status != models.TaskStatusFailed || payloads[1].ErrorMessage != "genetic execute failed" {
+		t.Fatalf("callback: got %d payloads, last %+v", len(payloads), payloads[len(payloads)-1])
+	}
+
+	deliveries, err := store.Callbacks().ListByTask(task.TaskID)
+	if err != nil || len(deliveries) != 2 {
+		t.Fatalf("deliveries: got %d, err %v", len(deliveries), err)
+	}
+	if deliveries[0].Success || deliveries[0].StatusCode != http.StatusInternalServerError || !deliveries[1].Success || deliveries[1].Attempt != 2 {
+		t.Errorf("deliveries: %+v, %+v", deliveries[0], deliveries[1])
+	}
+
+	task, err = store.Tasks().Get(task.TaskID)
+	if err != nil || task.CallbackStatus != models.CallbackStatusDelivered || task.CallbackAttempts != 2 {
+		t.Errorf("task: callback status %s, attempts %d, err %v", task.CallbackStatus, task.CallbackAttempts, err)
+	}
+}
+
+func TestCallbackSummary(t *testing.T) {
+
+	store := newTestStorage(t)
+
+	var payload *worker.CallbackPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload = &worker.CallbackPayload{}
+		if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+			t.Errorf("callback: %s", err)
+		}
+	}))
+	defer server.Close()
+
+	task := &models.Task{TaskData: taskData, Status: models.TaskStatusPending, CallbackURL: server.URL}
+	if err := store.Tasks().Create(task); err != nil {
+		t.Fatalf("create task failed. %s", err)
+	}
+	if err := store.Tasks().Claim(task.TaskID); err != nil {
+		t.Fatalf("claim failed. %s", err)
+	}
+
+	results := []*models.ScheduleResult{{TaskID: task.TaskID, Period: 1}, {TaskID: task.TaskID, Period: 2}}
+	report := &models.ScheduleReport{TaskID: task.TaskID, Fitness: 100, NumSoftViolations: 3, TerminationReason: "stagnation", Report: "{}"}
+	if err := store.Tasks().Complete(task.TaskID, results, report); err != nil {
+		t.Fatalf("complete failed. %s", err)
+	}
+
+	sender := worker.NewCallbackSender(store, 0, 0, 3)
+	sender.AllowPrivateHosts()
+	if sent := sender.DeliverDue(10); sent != 1 {
+		t.Fatalf("callback: sent %d callbacks", sent)
+	}
+
+	// 结果数量由统计查询得到, 其他汇总从质量报告中获取
+	want := worker.CallbackSummary{NumResults: 2, Fitness: 100, NumSoftViolations: 3, TerminationReason: "stagnation"}
+	if payload == nil || payload.Status != models.TaskStatusSuccess || payload.Summary == nil || *payload.Summary != want {
+		t.Fatalf("callback: payload %+v", payload)
+	}
+}
+
+func TestCallbackRetry(t *testing.T) {
+
+	store := newTestStorage(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cancelTask := func() *models.Task {
+		task := &models.Task{TaskData: taskData, Status: models.TaskStatusPending, CallbackURL: server.URL}
+		if err := store.Tasks().Create(task); err != nil {
+			t.Fatalf("create task failed. %s", err)
+		}
+		if err := store.Tasks().Cancel(task.TaskID); err != nil {
+			t.Fatalf("cancel failed. %s", err)
+		}
+		return task
+	}
+
+	// 达到最多回调次数后不再重试
+	task := cancelTask()
+	sender := worker.NewCallbackSender(store, 0, 0, 2)
+	sender.AllowPrivateHosts()
+	for i, want := range []int{1, 1, 0} {
+		if sent := sender.DeliverDue(10); sent != want {
+			t.Fatalf("callback %d: sent %d, want %d", i+1, sent, want)
+		}
+	}
+
+	task, err := store.Tasks().Get(task.TaskID)
+	if err != nil || task.CallbackStatus != models.CallbackStatusFailed || task.CallbackAttempts != 2 {
+		t.Fatalf("give up: callback status %s, attempts %d, err %v", task.CallbackStatus, task.CallbackAttempts, err)
+	}
+
+	// 重试间隔之前不再回调
+	task = cancelTask()
+	sender = worker.NewCallbackSender(store, time.Hour, time.Hour, 2)
+	sender.AllowPrivateHosts()
+	for i, want := range []int{1, 0} {
+		if sent := sender.DeliverDue(10); sent != want {
+			t.Fatalf("backoff callback %d: sent %d, want %d", i+1, sent, want)
+		}
+	}
+
+	task, err = store.Tasks().Get(task.TaskID)
+	if err != nil || task.CallbackStatus != models.CallbackStatusPending || task.CallbackNextAt == nil || time.Until(*task.CallbackNextAt) < 50*time.Minute {
+		t.Fatalf("backoff: callback status %s, next at %v, err %v", task.CallbackStatus, task.CallbackNextAt, err)
+	}
+}
+
+// 回调不跟随重定向, 默认不连接内网地址
+func TestCallbackRestrictedHosts(t *testing.T) {
+
+	store := newTestStorage(t)
+
+	var redirected bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	}))
+	defer target.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusFound)
+	}))
+	defer server.Close()
+
+	cancelTask := func() *models.Task {
+		task := &models.Task{TaskData: taskData, Status: models.TaskStatusPending, CallbackURL: server.URL}
+		if err := store.Tasks().Create(task); err != nil {
+			t.Fatalf("create task failed. %s", err)
+		}
+		if err := store.Tasks().Cancel(task.TaskID); err != nil {
+			t.Fatalf("cancel failed. %s", err)
+		}
+		return task
+	}
+
+	task := cancelTask()
+	sender := worker.NewCallbackSender(store, time.Hour, time.Hour, 2)
+	sender.AllowPrivateHosts()
+	sender.DeliverDue(10)
+
+	deliveries, err := store.Callbacks().ListByTask(task.TaskID)
+	if err != nil || len(deliveries) != 1 || deliveries[0].Success || deliveries[0].StatusCode != http.StatusFound || redirected {
+		t.Fatalf("redirect: deliveries %+v, redirected %v, err %v", deliveries, redirected, err)
+	}
+
+	// 回调服务器在回环地址, 连接前被拒绝
+	task = cancelTask()
+	worker.NewCallbackSender(store, time.Hour, time.Hour, 2).DeliverDue(10)
+
+	deliveries, err = store.Callbacks().ListByTask(task.TaskID)
+	if err != nil || len(deliveries) != 1 || deliveries[0].Success || !strings.Contains(deliveries[0].ErrorMsg, "private or reserved address") {
+		t.Fatalf("loopback: deliveries %+v, err %v", deliveries, err)
+	}
+}
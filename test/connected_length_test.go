@@ -0,0 +1,47 @@
+package test
+
+import (
+	"course_scheduler/internal/models"
+	"course_scheduler/internal/utils"
+	"fmt"
+	"testing"
+)
+
+func TestConnectedLength(t *testing.T) {
+
+	schedule := &models.Schedule{
+		Name:                     "心远中学2023年第一学期",
+		NumWorkdays:              5,
+		NumDaysOff:               2,
+		NumMorningReadingClasses: 1,
+		NumForenoonClasses:       4,
+		NumAfternoonClasses:      4,
+		NumNightClasses:          0,
+		WeekdayClasses: []*models.WeekdayClasses{
+			// 星期三下午只有2节课, 排不下三连堂
+			{Weekday: 3, NumMorningReadingClasses: 1, NumForenoonClasses: 4, NumAfternoonClasses: 2},
+		},
+	}
+
+	if err := schedule.Check(); err != nil {
+		t.Fatalf("schedule check failed. %s", err)
+	}
+
+	// 三连堂, 上午每天2个, 下午除星期三外每天2个
+	timeSlotStrs := utils.GetAllConnectedTimeSlotsWithLength(schedule, 3)
+	fmt.Printf("timeSlotStrs: %v\n", timeSlotStrs)
+
+	if len(timeSlotStrs) != 18 {
+		t.Errorf("connected time slots of length 3 should be 18, got %d", len(timeSlotStrs))
+	}
+
+	// 早读和上午第1节不能连堂
+	if timeSlotStrs[0] != "1_2_3" {
+		t.Errorf("first connected time slots should be 1_2_3, got %s", timeSlotStrs[0])
+	}
+
+	// 两节连堂和原来的结果一致
+	if len(utils.GetAllConnectedTimeSlots(schedule)) != len(utils.GetAllConnectedTimeSlotsWithLength(schedule, 2)) {
+		t.Errorf("connected time slots of length 2 mismatch")
+	}
+}
@@ -0,0 +1,50 @@
+package test
+
+import (
+	"course_scheduler/internal/models"
+	"fmt"
+	"testing"
+)
+
+func TestWeekdayClasses(t *testing.T) {
+
+	schedule := models.Schedule{
+		Name:                     "心远中学2023年第一学期",
+		NumWorkdays:              5,
+		NumDaysOff:               2,
+		NumMorningReadingClasses: 1,
+		NumForenoonClasses:       4,
+		NumAfternoonClasses:      4,
+		NumNightClasses:          0,
+		WeekdayClasses: []*models.WeekdayClasses{
+			// 星期三下午只有2节课
+			{Weekday: 3, NumMorningReadingClasses: 1, NumForenoonClasses: 4, NumAfternoonClasses: 2},
+		},
+	}
+
+	if err := schedule.Check(); err != nil {
+		t.Fatalf("schedule check failed. %s", err)
+	}
+
+	// 星期三 第8节(下午第3节)不存在
+	index := schedule.GetTimeSlotIndex(2, 7)
+	timeSlot := schedule.GetTimeSlot(index)
+	fmt.Printf("timeSlot: %#v, available: %v\n", timeSlot, schedule.IsTimeSlotAvailable(index))
+
+	if timeSlot.Weekday() != 3 || timeSlot.Segment != "afternoon" {
+		t.Errorf("unexpected time slot %#v", timeSlot)
+	}
+
+	if schedule.IsTimeSlotAvailable(index) {
+		t.Errorf("time slot %d should not be available", index)
+	}
+
+	// 星期四 第8节存在
+	if !schedule.IsTimeSlotAvailable(schedule.GetTimeSlotIndex(3, 7)) {
+		t.Errorf("time slot %d should be available", schedule.GetTimeSlotIndex(3, 7))
+	}
+
+	if total := schedule.TotalClassesPerWeek(); total != 43 {
+		t.Errorf("total classes per week should be 43, got %d", total)
+	}
+}